@@ -0,0 +1,338 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/uuid"
+)
+
+// Main UBL ApplicationResponse Namespace
+const (
+	NamespaceUBLApplicationResponse = "urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2"
+)
+
+// Schema location for the application response document
+const (
+	SchemaLocationApplicationResponse = "urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2 http://docs.oasis-open.org/ubl/os-UBL-2.1/xsd/maindoc/UBL-ApplicationResponse-2.1.xsd"
+)
+
+// Invoice response codes defined by the UNCL4343 subset used in Peppol BIS
+// Invoice Response 3.1.
+const (
+	ResponseCodeAcknowledged          = "AB"
+	ResponseCodeInProcess             = "IP"
+	ResponseCodeUnderQuery            = "UQ"
+	ResponseCodeConditionallyAccepted = "CA"
+	ResponseCodeRejected              = "RE"
+	ResponseCodeAccepted              = "AP"
+	ResponseCodePaid                  = "PD"
+)
+
+// List identifiers used by the Peppol Invoice Response codes.
+const (
+	ListIDResponseCode = "UNCL4343OpSubset"
+	ListIDStatusReason = "OPStatusReason"
+	ListIDStatusAction = "OPStatusAction"
+)
+
+// Clarification reason (OPStatusReason) and action (OPStatusAction) codes
+// for invoice responses.
+const (
+	StatusReasonNoIssue         = "NON"
+	StatusReasonReferences      = "REF"
+	StatusReasonLegal           = "LEG"
+	StatusReasonReceiver        = "REC"
+	StatusReasonQuality         = "QUA"
+	StatusReasonDelivery        = "DEL"
+	StatusReasonPrices          = "PRI"
+	StatusReasonQuantity        = "QTY"
+	StatusReasonItems           = "ITM"
+	StatusReasonPaymentTerms    = "PAY"
+	StatusReasonNotRecognized   = "UNR"
+	StatusReasonFinanceTerms    = "FIN"
+	StatusReasonPartiallyPaid   = "PPD"
+	StatusReasonOther           = "OTH"
+	StatusActionNoAction        = "NOA"
+	StatusActionProvideInfo     = "PIN"
+	StatusActionIssueNewInvoice = "NIN"
+	StatusActionCreditFully     = "CNF"
+	StatusActionCreditPartially = "CNP"
+	StatusActionCreditAmount    = "CNA"
+	StatusActionOther           = "OTH"
+)
+
+// responseCodes lists the valid invoice response status codes.
+var responseCodes = []string{
+	ResponseCodeAcknowledged,
+	ResponseCodeInProcess,
+	ResponseCodeUnderQuery,
+	ResponseCodeConditionallyAccepted,
+	ResponseCodeRejected,
+	ResponseCodeAccepted,
+	ResponseCodePaid,
+}
+
+// statusReasonCodes lists the valid clarification reason codes.
+var statusReasonCodes = []string{
+	StatusReasonNoIssue,
+	StatusReasonReferences,
+	StatusReasonLegal,
+	StatusReasonReceiver,
+	StatusReasonQuality,
+	StatusReasonDelivery,
+	StatusReasonPrices,
+	StatusReasonQuantity,
+	StatusReasonItems,
+	StatusReasonPaymentTerms,
+	StatusReasonNotRecognized,
+	StatusReasonFinanceTerms,
+	StatusReasonPartiallyPaid,
+	StatusReasonOther,
+}
+
+// statusActionCodes lists the valid clarification action codes.
+var statusActionCodes = []string{
+	StatusActionNoAction,
+	StatusActionProvideInfo,
+	StatusActionIssueNewInvoice,
+	StatusActionCreditFully,
+	StatusActionCreditPartially,
+	StatusActionCreditAmount,
+	StatusActionOther,
+}
+
+// ApplicationResponse represents the root element of a UBL ApplicationResponse
+// document, used for both Invoice Responses and Message Level Responses.
+type ApplicationResponse struct {
+	// Attributes
+	XMLName        xml.Name
+	CACNamespace   string `xml:"xmlns:cac,attr"`
+	CBCNamespace   string `xml:"xmlns:cbc,attr"`
+	EXTNamespace   string `xml:"xmlns:ext,attr,omitempty"`
+	QDTNamespace   string `xml:"xmlns:qdt,attr"`
+	UDTNamespace   string `xml:"xmlns:udt,attr"`
+	CCTSNamespace  string `xml:"xmlns:ccts,attr"`
+	UBLNamespace   string `xml:"xmlns,attr"`
+	XSINamespace   string `xml:"xmlns:xsi,attr"`
+	SchemaLocation string `xml:"xsi:schemaLocation,attr"`
+
+	UBLExtensions      *Extensions `xml:"ext:UBLExtensions,omitempty"`
+	UBLVersionID       string      `xml:"cbc:UBLVersionID,omitempty"`
+	CustomizationID    string      `xml:"cbc:CustomizationID,omitempty"`
+	ProfileID          string      `xml:"cbc:ProfileID,omitempty"`
+	ProfileExecutionID string      `xml:"cbc:ProfileExecutionID,omitempty"`
+	ID                 string      `xml:"cbc:ID"`
+	UUID               string      `xml:"cbc:UUID,omitempty"`
+	IssueDate          string      `xml:"cbc:IssueDate"`
+	IssueTime          string      `xml:"cbc:IssueTime,omitempty"`
+	ResponseDate       string      `xml:"cbc:ResponseDate,omitempty"`
+	ResponseTime       string      `xml:"cbc:ResponseTime,omitempty"`
+	Note               []string    `xml:"cbc:Note,omitempty"`
+	VersionID          string      `xml:"cbc:VersionID,omitempty"`
+
+	Signature        []Signature        `xml:"cac:Signature,omitempty"`
+	SenderParty      *Party             `xml:"cac:SenderParty"`
+	ReceiverParty    *Party             `xml:"cac:ReceiverParty"`
+	DocumentResponse []DocumentResponse `xml:"cac:DocumentResponse"`
+}
+
+// DocumentResponse represents the response to a specific document
+type DocumentResponse struct {
+	Response          Response       `xml:"cac:Response"`
+	DocumentReference []Reference    `xml:"cac:DocumentReference"`
+	IssuerParty       *Party         `xml:"cac:IssuerParty,omitempty"`
+	RecipientParty    *Party         `xml:"cac:RecipientParty,omitempty"`
+	LineResponse      []LineResponse `xml:"cac:LineResponse,omitempty"`
+}
+
+// Response represents the status of a document or a line within it
+type Response struct {
+	ReferenceID   string   `xml:"cbc:ReferenceID,omitempty"`
+	ResponseCode  *IDType  `xml:"cbc:ResponseCode,omitempty"`
+	Description   []string `xml:"cbc:Description,omitempty"`
+	EffectiveDate string   `xml:"cbc:EffectiveDate,omitempty"`
+	EffectiveTime string   `xml:"cbc:EffectiveTime,omitempty"`
+	Status        []Status `xml:"cac:Status,omitempty"`
+}
+
+// Status represents a clarification of a response
+type Status struct {
+	StatusReasonCode *IDType     `xml:"cbc:StatusReasonCode,omitempty"`
+	StatusReason     []string    `xml:"cbc:StatusReason,omitempty"`
+	Condition        []Condition `xml:"cac:Condition,omitempty"`
+}
+
+// Condition identifies the data that a status refers to
+type Condition struct {
+	AttributeID string   `xml:"cbc:AttributeID"`
+	Description []string `xml:"cbc:Description,omitempty"`
+}
+
+// LineResponse represents the response to a specific part of a document
+type LineResponse struct {
+	LineReference LineReference `xml:"cac:LineReference"`
+	Response      []Response    `xml:"cac:Response"`
+}
+
+// Clarification provides the reason or expected action behind an invoice
+// response status. ListID should be either ListIDStatusReason or
+// ListIDStatusAction, and defaults to the former.
+type Clarification struct {
+	ListID string
+	Code   string
+	Text   string
+}
+
+// InvoiceResponse contains the details of the response to an invoice.
+type InvoiceResponse struct {
+	// ID of the response, a new UUID will be used if empty.
+	ID string
+	// IssueDate of the response, today if empty.
+	IssueDate cal.Date
+	// Code is the response status code.
+	Code string
+	// Clarifications explain the status, and are required for the UQ,
+	// CA and RE codes.
+	Clarifications []Clarification
+}
+
+// NewInvoiceResponse builds a Peppol BIS Invoice Response for the provided
+// invoice. The invoice buyer is used as the sender of the response and the
+// seller as the receiver. The response and clarification codes must belong
+// to the code lists of the Peppol Invoice Response.
+func NewInvoiceResponse(inv *Invoice, r InvoiceResponse) (*ApplicationResponse, error) {
+	if inv == nil {
+		return nil, fmt.Errorf("invoice response: missing invoice")
+	}
+	code := r.Code
+	if !isCode(responseCodes, code) {
+		return nil, fmt.Errorf("invoice response: invalid response code %q", code)
+	}
+	switch code {
+	case ResponseCodeUnderQuery, ResponseCodeConditionallyAccepted, ResponseCodeRejected:
+		if len(r.Clarifications) == 0 {
+			return nil, fmt.Errorf("invoice response: clarification required for response code %s", code)
+		}
+	}
+
+	out := newApplicationResponse(ContextPeppolInvoiceResponse)
+	if r.ID != "" {
+		out.ID = r.ID
+	}
+	if !r.IssueDate.IsZero() {
+		out.IssueDate = formatDate(r.IssueDate)
+	}
+	out.SenderParty = responseParty(inv.AccountingCustomerParty.Party)
+	out.ReceiverParty = responseParty(inv.AccountingSupplierParty.Party)
+
+	ref := Reference{
+		ID:               IDType{Value: inv.ID},
+		IssueDate:        inv.IssueDate,
+		DocumentTypeCode: inv.typeCode(),
+	}
+
+	responseListID := ListIDResponseCode
+	resp := Response{
+		ResponseCode: &IDType{
+			ListID: &responseListID,
+			Value:  code,
+		},
+	}
+	for _, c := range r.Clarifications {
+		listID := c.ListID
+		if listID == "" {
+			listID = ListIDStatusReason
+		}
+		switch listID {
+		case ListIDStatusReason:
+			if !isCode(statusReasonCodes, c.Code) {
+				return nil, fmt.Errorf("invoice response: invalid status reason code %q", c.Code)
+			}
+		case ListIDStatusAction:
+			if !isCode(statusActionCodes, c.Code) {
+				return nil, fmt.Errorf("invoice response: invalid status action code %q", c.Code)
+			}
+		default:
+			return nil, fmt.Errorf("invoice response: invalid clarification list ID %q", listID)
+		}
+		s := Status{
+			StatusReasonCode: &IDType{
+				ListID: &listID,
+				Value:  c.Code,
+			},
+		}
+		if c.Text != "" {
+			s.StatusReason = []string{c.Text}
+		}
+		resp.Status = append(resp.Status, s)
+	}
+
+	dr := DocumentResponse{
+		Response:          resp,
+		DocumentReference: []Reference{ref},
+	}
+	if sp := inv.AccountingSupplierParty.Party; sp != nil {
+		dr.IssuerParty = &Party{
+			PartyIdentification: sp.PartyIdentification,
+		}
+		if sp.PartyLegalEntity != nil && sp.PartyLegalEntity.RegistrationName != nil {
+			dr.IssuerParty.PartyName = &PartyName{Name: *sp.PartyLegalEntity.RegistrationName}
+		} else if sp.PartyName != nil {
+			dr.IssuerParty.PartyName = &PartyName{Name: sp.PartyName.Name}
+		}
+	}
+	out.DocumentResponse = []DocumentResponse{dr}
+
+	return out, nil
+}
+
+// newApplicationResponse prepares an empty application response for the
+// given context with a random identifier and the current date.
+func newApplicationResponse(ctx Context) *ApplicationResponse {
+	return &ApplicationResponse{
+		XMLName:         xml.Name{Local: "ApplicationResponse"},
+		CACNamespace:    NamespaceCAC,
+		CBCNamespace:    NamespaceCBC,
+		EXTNamespace:    NamespaceEXT,
+		QDTNamespace:    NamespaceQDT,
+		UDTNamespace:    NamespaceUDT,
+		UBLNamespace:    NamespaceUBLApplicationResponse,
+		CCTSNamespace:   NamespaceCCTS,
+		XSINamespace:    NamespaceXSI,
+		SchemaLocation:  SchemaLocationApplicationResponse,
+		CustomizationID: ctx.CustomizationID,
+		ProfileID:       ctx.ProfileID,
+		ID:              uuid.V7().String(),
+		IssueDate:       formatDate(cal.Today()),
+	}
+}
+
+// responseParty keeps only the identification details of a party, which
+// is all that is expected in application responses.
+func responseParty(p *Party) *Party {
+	if p == nil {
+		return nil
+	}
+	out := &Party{
+		EndpointID:          p.EndpointID,
+		PartyIdentification: p.PartyIdentification,
+	}
+	if p.PartyLegalEntity != nil && p.PartyLegalEntity.RegistrationName != nil {
+		out.PartyLegalEntity = &PartyLegalEntity{
+			RegistrationName: p.PartyLegalEntity.RegistrationName,
+		}
+	}
+	return out
+}
+
+// isCode returns true when the code is included in the list.
+func isCode(list []string, code string) bool {
+	for _, c := range list {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
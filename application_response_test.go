@@ -0,0 +1,134 @@
+package ubl_test
+
+import (
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/cal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testParsedInvoice(t *testing.T, name string) *ubl.Invoice {
+	t.Helper()
+	data, err := testLoadXML(name)
+	require.NoError(t, err)
+	doc, err := ubl.Parse(data)
+	require.NoError(t, err)
+	inv, ok := doc.(*ubl.Invoice)
+	require.True(t, ok)
+	return inv
+}
+
+func TestNewInvoiceResponse(t *testing.T) {
+	inv := testParsedInvoice(t, "peppol/base-example.xml")
+
+	t.Run("rejected with clarifications", func(t *testing.T) {
+		ar, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{
+			Code: ubl.ResponseCodeRejected,
+			Clarifications: []ubl.Clarification{
+				{Code: ubl.StatusReasonReferences, Text: "Order reference is unknown"},
+				{ListID: ubl.ListIDStatusAction, Code: ubl.StatusActionIssueNewInvoice},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, ubl.NamespaceUBLApplicationResponse, ar.UBLNamespace)
+		assert.Equal(t, ubl.ContextPeppolInvoiceResponse.CustomizationID, ar.CustomizationID)
+		assert.Equal(t, ubl.ContextPeppolInvoiceResponse.ProfileID, ar.ProfileID)
+		assert.NotEmpty(t, ar.ID)
+		assert.NotEmpty(t, ar.IssueDate)
+
+		require.NotNil(t, ar.SenderParty)
+		assert.Equal(t, "FR23342", ar.SenderParty.EndpointID.Value)
+		assert.Equal(t, "Buyer Official Name", *ar.SenderParty.PartyLegalEntity.RegistrationName)
+		assert.Nil(t, ar.SenderParty.PostalAddress)
+		require.NotNil(t, ar.ReceiverParty)
+		assert.Equal(t, "9482348239847239874", ar.ReceiverParty.EndpointID.Value)
+
+		require.Len(t, ar.DocumentResponse, 1)
+		dr := ar.DocumentResponse[0]
+		assert.Equal(t, "RE", dr.Response.ResponseCode.Value)
+		assert.Equal(t, ubl.ListIDResponseCode, *dr.Response.ResponseCode.ListID)
+		require.Len(t, dr.Response.Status, 2)
+		assert.Equal(t, "REF", dr.Response.Status[0].StatusReasonCode.Value)
+		assert.Equal(t, ubl.ListIDStatusReason, *dr.Response.Status[0].StatusReasonCode.ListID)
+		assert.Equal(t, []string{"Order reference is unknown"}, dr.Response.Status[0].StatusReason)
+		assert.Equal(t, "NIN", dr.Response.Status[1].StatusReasonCode.Value)
+		assert.Equal(t, ubl.ListIDStatusAction, *dr.Response.Status[1].StatusReasonCode.ListID)
+
+		require.Len(t, dr.DocumentReference, 1)
+		assert.Equal(t, "Snippet1", dr.DocumentReference[0].ID.Value)
+		assert.Equal(t, "2017-11-13", dr.DocumentReference[0].IssueDate)
+		assert.Equal(t, "380", dr.DocumentReference[0].DocumentTypeCode)
+		require.NotNil(t, dr.IssuerParty)
+		assert.Equal(t, "SupplierOfficialName Ltd", dr.IssuerParty.PartyName.Name)
+	})
+
+	t.Run("accepted without clarifications", func(t *testing.T) {
+		ar, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{Code: ubl.ResponseCodeAccepted})
+		require.NoError(t, err)
+		assert.Empty(t, ar.DocumentResponse[0].Response.Status)
+	})
+
+	t.Run("missing clarification", func(t *testing.T) {
+		for _, code := range []string{ubl.ResponseCodeUnderQuery, ubl.ResponseCodeConditionallyAccepted, ubl.ResponseCodeRejected} {
+			_, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{Code: code})
+			assert.ErrorContains(t, err, "clarification required")
+		}
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		_, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{Code: "XX"})
+		assert.ErrorContains(t, err, "invalid response code")
+	})
+
+	t.Run("id and issue date", func(t *testing.T) {
+		ar, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{
+			ID:        "IR-0001",
+			IssueDate: cal.MakeDate(2017, 11, 20),
+			Code:      ubl.ResponseCodeAccepted,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "IR-0001", ar.ID)
+		assert.Equal(t, "2017-11-20", ar.IssueDate)
+	})
+
+	t.Run("invalid clarifications", func(t *testing.T) {
+		_, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{
+			Code:           ubl.ResponseCodeRejected,
+			Clarifications: []ubl.Clarification{{Code: "XXX"}},
+		})
+		assert.ErrorContains(t, err, `invalid status reason code "XXX"`)
+
+		_, err = ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{
+			Code:           ubl.ResponseCodeRejected,
+			Clarifications: []ubl.Clarification{{ListID: ubl.ListIDStatusAction, Code: ubl.StatusReasonReferences}},
+		})
+		assert.ErrorContains(t, err, `invalid status action code "REF"`)
+
+		_, err = ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{
+			Code:           ubl.ResponseCodeRejected,
+			Clarifications: []ubl.Clarification{{ListID: "UNCL4343", Code: ubl.StatusReasonReferences}},
+		})
+		assert.ErrorContains(t, err, `invalid clarification list ID "UNCL4343"`)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		ar, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{Code: ubl.ResponseCodePaid})
+		require.NoError(t, err)
+
+		data, err := ubl.Bytes(ar)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `xmlns:ext="`+ubl.NamespaceEXT+`"`)
+
+		doc, err := ubl.Parse(data)
+		require.NoError(t, err)
+		parsed, ok := doc.(*ubl.ApplicationResponse)
+		require.True(t, ok)
+		assert.Equal(t, ar.ID, parsed.ID)
+		require.Len(t, parsed.DocumentResponse, 1)
+		assert.Equal(t, "PD", parsed.DocumentResponse[0].Response.ResponseCode.Value)
+		assert.Equal(t, "Snippet1", parsed.DocumentResponse[0].DocumentReference[0].ID.Value)
+	})
+}
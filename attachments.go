@@ -38,6 +38,13 @@ type ExternalReference struct {
 }
 
 func (ui *Invoice) addAttachments(attachments []*org.Attachment) {
+	ui.AdditionalDocumentReference = append(ui.AdditionalDocumentReference, newAttachmentReferences(attachments)...)
+}
+
+// newAttachmentReferences maps GOBL attachments to UBL additional document
+// references with an external reference.
+func newAttachmentReferences(attachments []*org.Attachment) []Reference {
+	var refs []Reference
 	for _, a := range attachments {
 		ref := Reference{
 			ID: IDType{
@@ -70,8 +77,9 @@ func (ui *Invoice) addAttachments(attachments []*org.Attachment) {
 			ExternalReference: extRef,
 		}
 
-		ui.AdditionalDocumentReference = append(ui.AdditionalDocumentReference, ref)
+		refs = append(refs, ref)
 	}
+	return refs
 }
 
 // AddBinaryAttachment adds an embedded binary attachment to the UBL Invoice.
@@ -32,16 +32,22 @@ type BinaryAttachment struct {
 // external reference attachments only.
 // Binary attachments are skipped - use ExtractBinaryAttachments to retrieve them.
 func (ui *Invoice) goblAddAttachments() []*org.Attachment {
+	return goblAttachments(ui.AdditionalDocumentReference)
+}
+
+// goblAttachments converts the external reference attachments in a set of
+// additional document references to GOBL attachments.
+func goblAttachments(refs []Reference) []*org.Attachment {
 	var attachments []*org.Attachment
 
-	for _, ref := range ui.AdditionalDocumentReference {
+	for _, ref := range refs {
 		if ref.Attachment == nil {
 			continue
 		}
 
 		// Only process external reference attachments
 		if ref.Attachment.ExternalReference != nil {
-			att := ui.processExternalAttachment(&ref)
+			att := goblExternalAttachment(&ref)
 			if att != nil {
 				attachments = append(attachments, att)
 			}
@@ -52,8 +58,8 @@ func (ui *Invoice) goblAddAttachments() []*org.Attachment {
 	return attachments
 }
 
-// processExternalAttachment converts a UBL external reference attachment to GOBL format.
-func (ui *Invoice) processExternalAttachment(ref *Reference) *org.Attachment {
+// goblExternalAttachment converts a UBL external reference attachment to GOBL format.
+func goblExternalAttachment(ref *Reference) *org.Attachment {
 	extRef := ref.Attachment.ExternalReference
 	if extRef == nil {
 		return nil
@@ -0,0 +1,110 @@
+package ubl
+
+import (
+	"regexp"
+
+	"github.com/invopop/gobl/addons/eu/en16931"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/l10n"
+	"github.com/invopop/gobl/org"
+)
+
+// ContextPeppolBE defines the Peppol BIS Billing 3.0 context for Belgium
+// (Mercurius), where parties are identified by their KBO/BCE enterprise
+// numbers.
+var ContextPeppolBE = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0",
+	ProfileID:       PeppolBillingProfileIDDefault,
+	Addons:          []cbc.Key{en16931.V2017},
+	VESIDs: VESIDMapping{
+		Invoice:    "eu.peppol.bis3:invoice:2025.5",
+		CreditNote: "eu.peppol.bis3:creditnote:2025.5",
+	},
+	Output: OutputRules{
+		ConcatenateNotes: true,
+	},
+	Hooks: peppolBEHooks{},
+}
+
+var beEnterpriseNumber = regexp.MustCompile(`^[01]\d{9}$`)
+
+// beIdentitySchemes maps the Belgian enterprise number to its ISO 6523
+// ICD scheme.
+var beIdentitySchemes = identitySchemes{
+	IdentityTypeKBO: "0208",
+}
+
+// peppolBEHooks adds the enterprise numbers of Belgian parties.
+type peppolBEHooks struct {
+	BaseHooks
+}
+
+// Detect checks for Peppol BIS Billing documents issued to Belgian
+// customers.
+func (peppolBEHooks) Detect(in *Invoice) bool {
+	if in.CustomizationID != ContextPeppol.CustomizationID {
+		return false
+	}
+	p := in.AccountingCustomerParty.Party
+	return p != nil && p.CountryCode() == l10n.BE.String()
+}
+
+// AfterConvert uses the enterprise number of Belgian parties as their
+// endpoint and legal entity identifiers, unless already defined.
+func (peppolBEHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	applyBEParty(inv.Supplier, out.AccountingSupplierParty.Party)
+	applyBEParty(inv.Customer, out.AccountingCustomerParty.Party)
+	return nil
+}
+
+// AfterParse sets the KBO identity type of the parties' legal entities
+// identified by their enterprise number.
+func (peppolBEHooks) AfterParse(_ *Invoice, inv *bill.Invoice) error {
+	beIdentitySchemes.goblTypes(inv.Supplier)
+	beIdentitySchemes.goblTypes(inv.Customer)
+	return nil
+}
+
+func applyBEParty(party *org.Party, p *Party) {
+	if party == nil || p == nil {
+		return
+	}
+	beIdentitySchemes.apply(party, p)
+	num := beEnterpriseNumberOf(party)
+	if num == "" {
+		return
+	}
+	scheme := beIdentitySchemes[IdentityTypeKBO]
+	if p.EndpointID == nil {
+		p.EndpointID = &EndpointID{SchemeID: scheme, Value: num}
+	}
+	if p.PartyLegalEntity == nil {
+		p.PartyLegalEntity = new(PartyLegalEntity)
+	}
+	if p.PartyLegalEntity.CompanyID == nil {
+		p.PartyLegalEntity.CompanyID = &IDType{SchemeID: &scheme, Value: num}
+	}
+}
+
+// beEnterpriseNumberOf provides the party's enterprise number from its
+// KBO identity or, as both share the same number, its Belgian VAT code.
+func beEnterpriseNumberOf(party *org.Party) string {
+	for _, id := range party.Identities {
+		if id.Type == IdentityTypeKBO {
+			return id.Code.String()
+		}
+	}
+	if party.TaxID == nil || party.TaxID.Country != l10n.BE.Tax() {
+		return ""
+	}
+	code := party.TaxID.Code.String()
+	if len(code) == 9 {
+		// Older numbers were issued without the leading zero
+		code = "0" + code
+	}
+	if !beEnterpriseNumber.MatchString(code) {
+		return ""
+	}
+	return code
+}
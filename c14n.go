@@ -0,0 +1,239 @@
+package ubl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// namespaceXML is the namespace bound to the reserved "xml" prefix.
+const namespaceXML = "http://www.w3.org/XML/1998/namespace"
+
+// xmlNode is a minimal element tree used to canonicalize documents. Names
+// keep the prefixes used in the original document.
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []any // *xmlNode or string
+}
+
+// is checks if the node has the provided prefix and local name.
+func (n *xmlNode) is(prefix, local string) bool {
+	return n.name.Space == prefix && n.name.Local == local
+}
+
+// childText provides the text of the first child with the provided name.
+func (n *xmlNode) childText(prefix, local string) string {
+	for _, c := range n.children {
+		if cn, ok := c.(*xmlNode); ok && cn.is(prefix, local) {
+			return cn.text()
+		}
+	}
+	return ""
+}
+
+// text provides the node's own text, without that of its children.
+func (n *xmlNode) text() string {
+	var sb strings.Builder
+	for _, t := range n.children {
+		if s, ok := t.(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+// find provides the first node with the provided local name amongst the
+// node and its descendants, or nil if there is none. Prefixes are ignored
+// as extension contents may have been re-encoded with default namespaces.
+func (n *xmlNode) find(local string) *xmlNode {
+	if n.name.Local == local {
+		return n
+	}
+	for _, c := range n.children {
+		if cn, ok := c.(*xmlNode); ok {
+			if f := cn.find(local); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// parseXMLTree reads the document's root element.
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	dc := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tk, err := dc.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing XML: %w", err)
+		}
+		switch t := tk.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name, attrs: append([]xml.Attr(nil), t.Attr...)}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("error parsing XML: multiple root elements")
+				}
+				root = n
+			} else {
+				p := stack[len(stack)-1]
+				p.children = append(p.children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("error parsing XML: unexpected end element")
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			// Text outside the root element is not part of the canonical form.
+			if len(stack) > 0 {
+				p := stack[len(stack)-1]
+				p.children = append(p.children, string(t))
+			}
+		}
+	}
+	if root == nil {
+		return nil, ErrUnknownDocumentType
+	}
+	return root, nil
+}
+
+// canonicalize provides the canonical form of the XML document according to
+// the Canonical XML 1.1 recommendation without comments, leaving out the
+// elements for which the exclude function returns true, together with
+// their contents.
+func canonicalize(data []byte, exclude func(n *xmlNode) bool) ([]byte, error) {
+	root, err := parseXMLTree(data)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	writeCanonical(buf, root, map[string]string{}, map[string]string{"xml": namespaceXML}, exclude)
+	return buf.Bytes(), nil
+}
+
+// writeCanonical outputs the node using the namespace declarations already
+// rendered by its ancestors, and those in scope to resolve prefixes.
+func writeCanonical(w *bytes.Buffer, n *xmlNode, rendered, scope map[string]string, exclude func(*xmlNode) bool) {
+	if exclude != nil && exclude(n) {
+		return
+	}
+
+	var decls, attrs []xml.Attr
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			decls = append(decls, a)
+		} else {
+			attrs = append(attrs, a)
+		}
+	}
+	if len(decls) > 0 {
+		rendered = copyNamespaces(rendered)
+		scope = copyNamespaces(scope)
+	}
+
+	var out []xml.Attr
+	for _, d := range decls {
+		prefix := d.Name.Local
+		if d.Name.Space == "" {
+			prefix = ""
+		}
+		scope[prefix] = d.Value
+		if cur, ok := rendered[prefix]; (ok && cur == d.Value) || (!ok && prefix == "" && d.Value == "") {
+			continue
+		}
+		rendered[prefix] = d.Value
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return namespacePrefix(out[i]) < namespacePrefix(out[j])
+	})
+	sort.SliceStable(attrs, func(i, j int) bool {
+		si, sj := attrNamespace(attrs[i], scope), attrNamespace(attrs[j], scope)
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	name := qualifiedName(n.name)
+	w.WriteString("<" + name)
+	for _, a := range append(out, attrs...) {
+		w.WriteString(" " + qualifiedName(a.Name) + "=\"")
+		w.WriteString(escapeCanonicalAttr(a.Value))
+		w.WriteString("\"")
+	}
+	w.WriteString(">")
+	for _, c := range n.children {
+		switch v := c.(type) {
+		case string:
+			w.WriteString(escapeCanonicalText(v))
+		case *xmlNode:
+			writeCanonical(w, v, rendered, scope, exclude)
+		}
+	}
+	w.WriteString("</" + name + ">")
+}
+
+func copyNamespaces(ns map[string]string) map[string]string {
+	out := make(map[string]string, len(ns)+1)
+	for k, v := range ns {
+		out[k] = v
+	}
+	return out
+}
+
+func namespacePrefix(a xml.Attr) string {
+	if a.Name.Space == "" {
+		return ""
+	}
+	return a.Name.Local
+}
+
+func attrNamespace(a xml.Attr, scope map[string]string) string {
+	if a.Name.Space == "" {
+		return ""
+	}
+	return scope[a.Name.Space]
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+var canonicalTextReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\r", "&#xD;",
+)
+
+var canonicalAttrReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	"\"", "&quot;",
+	"\t", "&#x9;",
+	"\n", "&#xA;",
+	"\r", "&#xD;",
+)
+
+func escapeCanonicalText(s string) string {
+	return canonicalTextReplacer.Replace(s)
+}
+
+func escapeCanonicalAttr(s string) string {
+	return canonicalAttrReplacer.Replace(s)
+}
@@ -100,11 +100,7 @@ func makeTaxCategory(taxes tax.Set) []*TaxCategory {
 	for _, t := range taxes {
 		category := TaxCategory{}
 		category.TaxScheme = &TaxScheme{ID: IDType{Value: t.Category.String()}}
-
-		e := t.Ext.Get(untdid.ExtKeyTaxCategory).String()
-		if e != "" {
-			category.ID = &IDType{Value: e}
-		}
+		category.ID = taxCategoryID(t.Key, t.Ext)
 
 		// Set percent: required unless category is "O" (outside scope)
 		if t.Percent != nil {
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"strings"
 
 	"github.com/invopop/gobl"
 	ubl "github.com/invopop/gobl.ubl"
@@ -14,6 +13,7 @@ import (
 type convertOpts struct {
 	*rootOpts
 	contextName string
+	contextFile string
 	profileID   string
 }
 
@@ -29,7 +29,8 @@ func (c *convertOpts) cmd() *cobra.Command {
 	}
 
 	flags := cmd.Flags()
-	flags.StringVar(&c.contextName, "context", "", "Context for UBL conversion (en16931, peppol, xrechnung, nemhandel, ...)")
+	flags.StringVar(&c.contextName, "context", "", "Name of a registered context for UBL conversion (en16931, peppol, xrechnung, nemhandel, ...)")
+	flags.StringVar(&c.contextFile, "context-file", "", "Path to a YAML or JSON context definition to register and use for UBL conversion")
 	flags.StringVar(&c.profileID, "profile-id", "", "Override UBL ProfileID for JSON to XML conversion")
 
 	return cmd
@@ -57,6 +58,13 @@ func (c *convertOpts) runE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("reading input: %w", err)
 	}
 
+	// Prepare options first, so that contexts loaded from a file are
+	// also available when parsing.
+	opts, err := c.buildOptions()
+	if err != nil {
+		return err
+	}
+
 	// Check if input is JSON or XML
 	isJSON := json.Valid(inData)
 
@@ -67,11 +75,7 @@ func (c *convertOpts) runE(cmd *cobra.Command, args []string) error {
 		if err := json.Unmarshal(inData, env); err != nil {
 			return fmt.Errorf("parsing input as GOBL Envelope: %w", err)
 		}
-		opts, err := c.buildOptions()
-		if err != nil {
-			return err
-		}
-		doc, err := ubl.ConvertInvoice(env, opts...)
+		doc, err := ubl.Convert(env, opts...)
 		if err != nil {
 			return fmt.Errorf("building UBL document: %w", err)
 		}
@@ -88,12 +92,23 @@ func (c *convertOpts) runE(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("building GOBL envelope: %w", err)
 		}
 
-		inv, ok := doc.(*ubl.Invoice)
-		if !ok {
+		var env *gobl.Envelope
+		switch d := doc.(type) {
+		case *ubl.Invoice:
+			// Only an explicit context replaces detection from the document,
+			// as the national Peppol variants share their identifiers.
+			if c.contextName != "" || c.contextFile != "" {
+				env, err = d.Convert(opts...)
+			} else {
+				env, err = d.Convert()
+			}
+		case *ubl.Order:
+			env, err = d.Convert()
+		case *ubl.DespatchAdvice:
+			env, err = d.Convert()
+		default:
 			return fmt.Errorf("building GOBL envelope: %w", ubl.ErrUnsupportedDocumentType)
 		}
-
-		env, err := inv.Convert()
 		if err != nil {
 			return fmt.Errorf("building GOBL envelope: %w", err)
 		}
@@ -112,32 +127,24 @@ func (c *convertOpts) runE(cmd *cobra.Command, args []string) error {
 }
 
 func (c *convertOpts) buildOptions() ([]ubl.Option, error) {
-	if c.contextName == "" && c.profileID == "" {
+	if c.contextName == "" && c.contextFile == "" && c.profileID == "" {
 		return nil, nil
 	}
 
 	ctx := ubl.ContextEN16931
+	if c.contextFile != "" {
+		fc, err := ubl.LoadContextFile(c.contextFile)
+		if err != nil {
+			return nil, err
+		}
+		ctx = *fc
+	}
 	if c.contextName != "" {
-		switch strings.ToLower(c.contextName) {
-		case "en16931", "en":
-			ctx = ubl.ContextEN16931
-		case "peppol":
-			ctx = ubl.ContextPeppol
-		case "peppol-self-billed", "peppol-selfbilled", "peppol-self":
-			ctx = ubl.ContextPeppolSelfBilled
-		case "xrechnung":
-			ctx = ubl.ContextXRechnung
-		case "peppol-france-cius", "france-cius", "fr-cius":
-			ctx = ubl.ContextPeppolFranceCIUS
-		case "peppol-france-extended", "france-extended", "fr-extended":
-			ctx = ubl.ContextPeppolFranceExtended
-		case "nemhandel", "oioubl":
-			ctx = ubl.ContextOIOUBL
-		case "nemhandel-2.1", "oioubl-2.1", "oioubl21":
-			ctx = ubl.ContextOIOUBL21
-		default:
+		rc := ubl.ContextByName(c.contextName)
+		if rc == nil {
 			return nil, fmt.Errorf("unknown context %q", c.contextName)
 		}
+		ctx = *rc
 	}
 
 	if c.profileID != "" {
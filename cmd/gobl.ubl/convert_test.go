@@ -102,6 +102,20 @@ func TestConvertBuildOptionsContextAliases(t *testing.T) {
 	}
 }
 
+func TestConvertBuildOptionsRegisteredContext(t *testing.T) {
+	custom := ubl.ContextPeppol
+	custom.CustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:example.com:cli:1.0"
+	ubl.RegisterContext("example-cli", custom)
+
+	opts, err := (&convertOpts{contextName: "example-cli"}).buildOptions()
+	require.NoError(t, err)
+
+	doc, err := ubl.ConvertInvoice(loadTestEnvelope(t), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, custom.CustomizationID, doc.CustomizationID)
+	assert.Equal(t, custom.ProfileID, doc.ProfileID)
+}
+
 func TestConvertRunEErrors(t *testing.T) {
 	t.Run("no args", func(t *testing.T) {
 		cmd := root().cmd()
@@ -230,3 +244,18 @@ func loadTestEnvelope(t *testing.T) *gobl.Envelope {
 
 	return env
 }
+
+func TestConvertBuildOptionsContextFile(t *testing.T) {
+	path := filepath.Join("..", "..", "test", "data", "contexts", "example-cius.yaml")
+
+	opts, err := (&convertOpts{contextFile: path}).buildOptions()
+	require.NoError(t, err)
+
+	doc, err := ubl.ConvertInvoice(loadTestEnvelope(t), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "urn:cen.eu:en16931:2017#compliant#urn:example.com:cius:1.0", doc.CustomizationID)
+	assert.NotNil(t, ubl.ContextByName("example-cius"))
+
+	_, err = (&convertOpts{contextFile: "missing.yaml"}).buildOptions()
+	require.ErrorContains(t, err, "reading context definition")
+}
@@ -1,6 +1,7 @@
 package ubl
 
 import (
+	"encoding/xml"
 	"errors"
 	"strings"
 
@@ -17,18 +18,24 @@ const (
 	NamespaceUDT  = "urn:oasis:names:specification:ubl:schema:xsd:UnqualifiedDataTypes-2"
 	NamespaceCCTS = "urn:un:unece:uncefact:documentation:2"
 	NamespaceXSI  = "http://www.w3.org/2001/XMLSchema-instance"
+	NamespaceEXT  = "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"
 )
 
 // Extensions represents UBL extensions
 type Extensions struct {
-	Extension []Extension `xml:"ext:Extension"`
+	Extension []Extension `xml:"ext:UBLExtension"`
 }
 
 // Extension represents a single UBL extension
 type Extension struct {
-	ID               string  `xml:"cbc:ID"`
-	ExtensionURI     *string `xml:"cbc:ExtensionURI"`
-	ExtensionContent *string `xml:"ext:ExtensionContent"`
+	ID               string            `xml:"cbc:ID,omitempty"`
+	ExtensionURI     *string           `xml:"ext:ExtensionURI"`
+	ExtensionContent *ExtensionContent `xml:"ext:ExtensionContent"`
+}
+
+// ExtensionContent contains the raw XML of an extension
+type ExtensionContent struct {
+	Value string `xml:",innerxml"`
 }
 
 // IDType represents an ID with optional scheme attributes
@@ -43,6 +50,53 @@ type IDType struct {
 	Value         string  `xml:",chardata"`
 }
 
+// Attributes contains the optional scheme and list attributes of an
+// element that is otherwise mapped to a plain value.
+type Attributes struct {
+	SchemeAgencyID *string
+	ListAgencyID   *string
+	ListID         *string
+	ListVersionID  *string
+	SchemeID       *string
+	SchemeName     *string
+	Name           *string
+}
+
+// newAttributes provides the attributes of the ID, or nil if it has none.
+func newAttributes(id *IDType) *Attributes {
+	if id == nil {
+		return nil
+	}
+	a := &Attributes{
+		SchemeAgencyID: id.SchemeAgencyID,
+		ListAgencyID:   id.ListAgencyID,
+		ListID:         id.ListID,
+		ListVersionID:  id.ListVersionID,
+		SchemeID:       id.SchemeID,
+		SchemeName:     id.SchemeName,
+		Name:           id.Name,
+	}
+	if *a == (Attributes{}) {
+		return nil
+	}
+	return a
+}
+
+// idType provides an IDType with the attributes and the value.
+func (a *Attributes) idType(value string) IDType {
+	id := IDType{Value: value}
+	if a != nil {
+		id.SchemeAgencyID = a.SchemeAgencyID
+		id.ListAgencyID = a.ListAgencyID
+		id.ListID = a.ListID
+		id.ListVersionID = a.ListVersionID
+		id.SchemeID = a.SchemeID
+		id.SchemeName = a.SchemeName
+		id.Name = a.Name
+	}
+	return id
+}
+
 // ExchangeRate represents an exchange rate
 type ExchangeRate struct {
 	SourceCurrencyCode *string `xml:"cbc:SourceCurrencyCode"`
@@ -69,6 +123,18 @@ type Signature struct {
 	SignatoryParty             *Party      `xml:"cac:SignatoryParty,omitempty"`
 	DigitalSignatureAttachment *Attachment `xml:"cac:DigitalSignatureAttachment,omitempty"`
 	OriginalDocumentReference  *Reference  `xml:"cac:OriginalDocumentReference,omitempty"`
+
+	// IDAttributes qualify the signature's ID, when required.
+	IDAttributes *Attributes `xml:"-"`
+}
+
+// MarshalXML encodes the signature, adding the attributes of the ID.
+func (s Signature) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type signature Signature
+	return e.EncodeElement(struct {
+		ID IDType `xml:"cbc:ID"`
+		signature
+	}{s.IDAttributes.idType(s.ID), signature(s)}, start)
 }
 
 // Quantity represents a quantity with a unit code
@@ -77,9 +143,16 @@ type Quantity struct {
 	Value    string `xml:",chardata"`
 }
 
+// Measure represents a measure with a unit code
+type Measure struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
 // OrderLineReference represents a reference to an order line
 type OrderLineReference struct {
-	LineID string `xml:"cbc:LineID"`
+	LineID         string          `xml:"cbc:LineID"`
+	OrderReference *OrderReference `xml:"cac:OrderReference,omitempty"`
 }
 
 // Item represents an item in an invoice line
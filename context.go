@@ -1,6 +1,11 @@
 package ubl
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/invopop/gobl/addons/de/xrechnung"
 	"github.com/invopop/gobl/addons/eu/en16931"
 	"github.com/invopop/gobl/addons/fr/facturx"
@@ -16,9 +21,51 @@ const (
 // VESIDMapping maps document types to their corresponding VESID values.
 type VESIDMapping struct {
 	// Invoice is the VESID for invoices
-	Invoice string
+	Invoice string `json:"invoice,omitempty"`
 	// CreditNote is the VESID for credit notes
-	CreditNote string
+	CreditNote string `json:"credit_note,omitempty"`
+}
+
+// OutputRules define simple adjustments to the generated documents
+// required by some contexts.
+type OutputRules struct {
+	// ConcatenateNotes joins all the document notes into a single note, as
+	// only one is allowed by some specifications such as Peppol.
+	ConcatenateNotes bool `json:"concatenate_notes,omitempty"`
+	// VersionID adds the UBLVersionID and the document's UUID.
+	VersionID bool `json:"version_id,omitempty"`
+	// LineTaxTotals adds a TaxTotal with the tax amount of each line.
+	LineTaxTotals bool `json:"line_tax_totals,omitempty"`
+	// SubLines adds the breakdown of each line as nested sub-lines.
+	SubLines bool `json:"sub_lines,omitempty"`
+	// ForcePaymentMeans31 replaces the credit transfer payment means code 30
+	// with 31, adding the IBAN payment channel and financial institution.
+	ForcePaymentMeans31 bool `json:"force_payment_means_31,omitempty"`
+	// UUID adds the document's UUID without the UBLVersionID.
+	UUID bool `json:"uuid,omitempty"`
+	// CopyIndicator always includes the CopyIndicator, also for original
+	// documents.
+	CopyIndicator bool `json:"copy_indicator,omitempty"`
+	// WithholdingTaxTotals adds the retained taxes, which are never part
+	// of the TaxTotal, in a WithholdingTaxTotal, also for lines with tax
+	// totals.
+	WithholdingTaxTotals bool `json:"withholding_tax_totals,omitempty"`
+	// SettlementDiscounts adds the SettlementDiscountPercent and
+	// SettlementPeriod of each early payment discount defined in the
+	// payment terms notes with the XRechnung "#SKONTO#" syntax.
+	SettlementDiscounts bool `json:"settlement_discounts,omitempty"`
+}
+
+// TaxRules define how taxes are identified in contexts that do not use
+// the VAT tax scheme and UNTDID 5305 tax categories, such as the Peppol
+// PINT specializations.
+type TaxRules struct {
+	// Scheme replaces the VAT tax scheme in tax categories and the
+	// parties' tax schemes of generated documents.
+	Scheme string `json:"scheme,omitempty"`
+	// Categories maps UNTDID 5305 tax category codes to the codes used
+	// in the context, and back again when parsing.
+	Categories map[string]string `json:"categories,omitempty"`
 }
 
 // Context is used to ensure that the generated UBL document
@@ -27,21 +74,28 @@ type VESIDMapping struct {
 type Context struct {
 	// CustomizationID identifies specific characteristics in the
 	// document which need to be present for local differences.
-	CustomizationID string
+	CustomizationID string `json:"customization_id"`
 	// ProfileID determines the business process context or scenario
 	// for the exchange of the document
-	ProfileID string
+	ProfileID string `json:"profile_id,omitempty"`
 	// OutputCustomizationID optionally specifies a different CustomizationID
 	// to use in the actual generated UBL XML document. If empty, CustomizationID
 	// is used. This allows the context to be identified by one ID externally while
 	// generating different values in the XML output.
-	OutputCustomizationID string
+	OutputCustomizationID string `json:"output_customization_id,omitempty"`
 	// Addons contains the list of Addons required for this CustomizationID
 	// and ProfileID.
-	Addons []cbc.Key
+	Addons []cbc.Key `json:"addons,omitempty"`
 	// VESIDs contains the VESID (Validation Exchange Specification ID) mappings
 	// for different document types and scenarios within this context.
-	VESIDs VESIDMapping
+	VESIDs VESIDMapping `json:"vesids,omitempty"`
+	// Output contains the rules to apply to generated documents.
+	Output OutputRules `json:"output,omitempty"`
+	// Tax contains the rules to identify taxes in the context.
+	Tax TaxRules `json:"tax,omitempty"`
+	// Hooks optionally adapt invoices to the context's requirements
+	// during conversion and parsing, after the output and tax rules.
+	Hooks Hooks `json:"-"`
 }
 
 // Is checks if two contexts are the same.
@@ -57,16 +111,20 @@ func (c *Context) GetVESID(inv *bill.Invoice) string {
 	return c.VESIDs.Invoice
 }
 
-// FindContext looks up a context by CustomizationID and optionally ProfileID.
-// Returns nil if no matching context is found.
+// FindContext looks up a context by CustomizationID and optionally ProfileID
+// amongst all the registered contexts. Returns nil if no matching context
+// is found.
 //
 // The lookup logic works as follows:
 // 1. First tries to match on the full CustomizationID (for external identification)
 // 2. If not found, tries to match on OutputCustomizationID (for parsing incoming documents)
 // 3. For contexts with a ProfileID, checks if it matches (if provided)
 func FindContext(customizationID string, profileID string) *Context {
+	registry.RLock()
+	defer registry.RUnlock()
+
 	// First pass: try to match on full CustomizationID
-	for _, ctx := range contexts {
+	for _, ctx := range registry.contexts {
 		if ctx.CustomizationID == customizationID {
 			// If context has a ProfileID and one was provided, they must match
 			if ctx.ProfileID != "" && profileID != "" && ctx.ProfileID != profileID {
@@ -77,7 +135,7 @@ func FindContext(customizationID string, profileID string) *Context {
 	}
 
 	// Second pass: try to match on OutputCustomizationID (for parsing where Profile may not be added))
-	for _, ctx := range contexts {
+	for _, ctx := range registry.contexts {
 		if ctx.OutputCustomizationID != "" && ctx.OutputCustomizationID == customizationID {
 			return &ctx
 		}
@@ -86,8 +144,80 @@ func FindContext(customizationID string, profileID string) *Context {
 	return nil
 }
 
+// RegisterContext adds a context to the registry under the provided name
+// and aliases so that it may be found with ContextByName and FindContext.
+// Registering a context under a name that is already in use will replace
+// the context registered with it. Several contexts may share the same
+// CustomizationID and ProfileID, in which case FindContext provides the
+// first one registered. Names are case insensitive.
+func RegisterContext(name string, c Context, aliases ...string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	idx, ok := registry.names[strings.ToLower(name)]
+	if ok {
+		registry.contexts[idx] = c
+	} else {
+		registry.contexts = append(registry.contexts, c)
+		idx = len(registry.contexts) - 1
+	}
+	for _, n := range append([]string{name}, aliases...) {
+		registry.names[strings.ToLower(n)] = idx
+	}
+}
+
+// registerNewContext adds the context to the registry like RegisterContext,
+// unless any of its names or its CustomizationID and ProfileID are already
+// in use by a registered context.
+func registerNewContext(name string, c Context, aliases ...string) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	names := append([]string{name}, aliases...)
+	for _, n := range names {
+		if _, ok := registry.names[strings.ToLower(n)]; ok {
+			return fmt.Errorf("%w: name %q", ErrContextRegistered, n)
+		}
+	}
+	for _, rc := range registry.contexts {
+		if rc.Is(c) {
+			return fmt.Errorf("%w: customization_id %q", ErrContextRegistered, c.CustomizationID)
+		}
+	}
+	registry.contexts = append(registry.contexts, c)
+	for _, n := range names {
+		registry.names[strings.ToLower(n)] = len(registry.contexts) - 1
+	}
+	return nil
+}
+
+// ContextByName returns the context registered with the provided name,
+// or nil if there is none.
+func ContextByName(name string) *Context {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	idx, ok := registry.names[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	ctx := registry.contexts[idx]
+	return &ctx
+}
+
+// Contexts provides the list of registered contexts in the order they
+// were first registered.
+func Contexts() []Context {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return append([]Context(nil), registry.contexts...)
+}
+
 type options struct {
-	context Context
+	context    Context
+	validators []Validator
+	ctx        context.Context
 }
 
 // Option is used to define configuration options to use during
@@ -102,8 +232,8 @@ func WithContext(c Context) Option {
 	}
 }
 
-// When adding new contexts, remember to add them to both the exported
-// variable definitions below AND the contexts slice.
+// Built in contexts, which are registered automatically together with
+// their common names.
 
 // ContextEN16931 is the default context for basic UBL documents.
 var ContextEN16931 = Context{
@@ -124,6 +254,9 @@ var ContextPeppol = Context{
 		Invoice:    "eu.peppol.bis3:invoice:2025.5",
 		CreditNote: "eu.peppol.bis3:creditnote:2025.5",
 	},
+	Output: OutputRules{
+		ConcatenateNotes: true,
+	},
 }
 
 // ContextPeppolSelfBilled defines the Peppol self-billed context.
@@ -148,6 +281,21 @@ var ContextXRechnung = Context{
 	},
 }
 
+// ContextXRechnungExtension defines the context for the XRechnung Extension,
+// which allows nested sub-invoice lines and third party payment details.
+var ContextXRechnungExtension = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0#conformant#urn:xeinkauf.de:kosit:extension:xrechnung_3.0",
+	ProfileID:       PeppolBillingProfileIDDefault,
+	Addons:          []cbc.Key{xrechnung.V3},
+	VESIDs: VESIDMapping{
+		Invoice:    "de.xrechnung:ubl-invoice-ext:3.0.2",
+		CreditNote: "de.xrechnung:ubl-creditnote-ext:3.0.2",
+	},
+	Output: OutputRules{
+		SubLines: true,
+	},
+}
+
 // ContextPeppolFranceCIUS defines the context for France UBL Invoice CIUS.
 var ContextPeppolFranceCIUS = Context{
 	CustomizationID:       "urn:cen.eu:en16931:2017#compliant#urn:peppol:france:billing:cius:1.0",
@@ -177,6 +325,11 @@ var ContextOIOUBL = Context{
 	CustomizationID: "urn:fdc:oioubl.dk:trns:billing:invoice:3.0",
 	ProfileID:       "urn:fdc:oioubl.dk:bis:billing_with_response:3",
 	Addons:          []cbc.Key{en16931.V2017},
+	Output: OutputRules{
+		VersionID:           true,
+		LineTaxTotals:       true,
+		ForcePaymentMeans31: true,
+	},
 }
 
 // ContextOIOUBL21 defines the context for legacy OIOUBL 2.1 documents.
@@ -184,6 +337,40 @@ var ContextOIOUBL21 = Context{
 	CustomizationID: "OIOUBL-2.1",
 	ProfileID:       "urn:www.nesubl.eu:profiles:profile5:ver2.0",
 	Addons:          []cbc.Key{en16931.V2017},
+	Output: OutputRules{
+		VersionID:           true,
+		LineTaxTotals:       true,
+		ForcePaymentMeans31: true,
+	},
+	Hooks: oioubl21Hooks{},
+}
+
+// ContextPeppolOrder defines the context for Peppol BIS Ordering 3.0 Order
+// documents.
+var ContextPeppolOrder = Context{
+	CustomizationID: "urn:fdc:peppol.eu:poacc:trns:order:3",
+	ProfileID:       "urn:fdc:peppol.eu:poacc:bis:ordering:3",
+}
+
+// ContextPeppolDespatchAdvice defines the context for Peppol BIS Despatch
+// Advice 3.0 documents.
+var ContextPeppolDespatchAdvice = Context{
+	CustomizationID: "urn:fdc:peppol.eu:poacc:trns:despatch_advice:3",
+	ProfileID:       "urn:fdc:peppol.eu:poacc:bis:despatch_advice:3",
+}
+
+// ContextPeppolInvoiceResponse defines the context for Peppol BIS Invoice
+// Response 3.1 ApplicationResponse documents.
+var ContextPeppolInvoiceResponse = Context{
+	CustomizationID: "urn:fdc:peppol.eu:poacc:trns:invoice_response:3",
+	ProfileID:       "urn:fdc:peppol.eu:poacc:bis:invoice_response:3",
+}
+
+// ContextPeppolMLR defines the context for Peppol BIS Message Level
+// Response 3.0 ApplicationResponse documents.
+var ContextPeppolMLR = Context{
+	CustomizationID: "urn:fdc:peppol.eu:poacc:trns:mlr:3",
+	ProfileID:       "urn:fdc:peppol.eu:poacc:bis:mlr:3",
 }
 
 // IsOIOUBL reports whether a context is any supported OIOUBL variant.
@@ -191,6 +378,52 @@ func (c *Context) IsOIOUBL() bool {
 	return c.Is(ContextOIOUBL) || c.Is(ContextOIOUBL21)
 }
 
-// contexts is used internally for reverse lookups during parsing.
-// When adding new contexts, remember to add them here AND as exported variables above.
-var contexts = []Context{ContextEN16931, ContextPeppol, ContextPeppolSelfBilled, ContextXRechnung, ContextPeppolFranceCIUS, ContextPeppolFranceExtended, ContextOIOUBL, ContextOIOUBL21}
+// registry holds all the contexts available for lookups by name and
+// during parsing.
+var registry = struct {
+	sync.RWMutex
+	contexts []Context
+	names    map[string]int
+}{
+	names: make(map[string]int),
+}
+
+func init() {
+	builtIn := []struct {
+		context Context
+		names   []string
+	}{
+		{ContextEN16931, []string{"en16931", "en"}},
+		{ContextPeppol, []string{"peppol"}},
+		{ContextPeppolNO, []string{"peppol-no", "ehf"}},
+		{ContextPeppolSE, []string{"peppol-se"}},
+		{ContextPeppolBE, []string{"peppol-be", "mercurius"}},
+		{ContextPeppolSelfBilled, []string{"peppol-self-billed", "peppol-selfbilled", "peppol-self"}},
+		{ContextXRechnung, []string{"xrechnung"}},
+		{ContextXRechnungExtension, []string{"xrechnung-extension", "xrechnung-ext"}},
+		{ContextPeppolFranceCIUS, []string{"peppol-france-cius", "france-cius", "fr-cius"}},
+		{ContextPeppolFranceExtended, []string{"peppol-france-extended", "france-extended", "fr-extended"}},
+		{ContextOIOUBL, []string{"nemhandel", "oioubl"}},
+		{ContextOIOUBL21, []string{"nemhandel-2.1", "oioubl-2.1", "oioubl21"}},
+		{ContextNLCIUS, []string{"nlcius", "si-ubl"}},
+		{ContextNLCIUSExtended, []string{"nlcius-extended", "nlcius-gaccount"}},
+		{ContextROCIUS, []string{"ro-cius", "efactura"}},
+		{ContextHRCIUS, []string{"hr-cius", "fiskalizacija"}},
+		{ContextMyInvois, []string{"myinvois", "my"}},
+		{ContextZATCA, []string{"zatca", "fatoora"}},
+		{ContextUBLTR, []string{"ubl-tr", "e-fatura", "efatura"}},
+		{ContextDIAN, []string{"dian", "co-dian"}},
+		{ContextSUNAT, []string{"sunat", "pe-sunat"}},
+		{ContextPINTAUNZ, []string{"pint-aunz", "pint-anz"}},
+		{ContextPINTSG, []string{"pint-sg", "invoicenow"}},
+		{ContextPINTJP, []string{"pint-jp"}},
+		{ContextPINTAE, []string{"pint-ae"}},
+		{ContextPeppolOrder, []string{"peppol-order", "order"}},
+		{ContextPeppolDespatchAdvice, []string{"peppol-despatch-advice", "despatch-advice"}},
+		{ContextPeppolInvoiceResponse, []string{"peppol-invoice-response", "invoice-response"}},
+		{ContextPeppolMLR, []string{"peppol-mlr", "mlr"}},
+	}
+	for _, b := range builtIn {
+		RegisterContext(b.names[0], b.context, b.names[1:]...)
+	}
+}
@@ -0,0 +1,67 @@
+package ubl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/invopop/gobl/tax"
+	"github.com/invopop/yaml"
+)
+
+// contextDefinition describes the contents of a context definition file,
+// which contains the context's fields together with the names it should
+// be registered under.
+type contextDefinition struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	Context
+}
+
+// LoadContext parses a context definition in YAML or JSON format, and
+// registers it under the name and aliases it provides. Definitions that
+// would replace a registered context, as they use one of its names or the
+// same CustomizationID and ProfileID, are rejected with an error wrapping
+// ErrContextRegistered. For example:
+//
+//	name: acme
+//	customization_id: "urn:cen.eu:en16931:2017#compliant#urn:example.com:acme:1.0"
+//	profile_id: "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+//	addons:
+//	  - eu-en16931-v2017
+//	vesids:
+//	  invoice: "eu.peppol.bis3:invoice:2025.5"
+//	output:
+//	  concatenate_notes: true
+func LoadContext(data []byte) (*Context, error) {
+	def := new(contextDefinition)
+	if err := yaml.Unmarshal(data, def); err != nil {
+		return nil, fmt.Errorf("parsing context definition: %w", err)
+	}
+	if def.Name == "" {
+		return nil, fmt.Errorf("context definition: name is required")
+	}
+	if def.CustomizationID == "" {
+		return nil, fmt.Errorf("context definition: customization_id is required")
+	}
+	for _, k := range def.Addons {
+		if tax.AddonForKey(k) == nil {
+			return nil, fmt.Errorf("context definition: unknown addon %q", k)
+		}
+	}
+
+	if err := registerNewContext(def.Name, def.Context, def.Aliases...); err != nil {
+		return nil, fmt.Errorf("context definition: %w", err)
+	}
+	ctx := def.Context
+	return &ctx, nil
+}
+
+// LoadContextFile reads and registers the context definition contained
+// in the file at the provided path.
+func LoadContextFile(path string) (*Context, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading context definition: %w", err)
+	}
+	return LoadContext(data)
+}
@@ -0,0 +1,90 @@
+package ubl_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/addons/eu/en16931"
+	"github.com/invopop/gobl/cbc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadContext(t *testing.T) {
+	ctx, err := ubl.LoadContextFile(filepath.Join(getDataPath(), "contexts", "example-cius.yaml"))
+	require.NoError(t, err)
+
+	t.Run("yaml definition", func(t *testing.T) {
+		assert.Equal(t, "urn:cen.eu:en16931:2017#compliant#urn:example.com:cius:1.0", ctx.CustomizationID)
+		assert.Equal(t, ubl.PeppolBillingProfileIDDefault, ctx.ProfileID)
+		assert.Equal(t, []cbc.Key{en16931.V2017}, ctx.Addons)
+		assert.Equal(t, "eu.peppol.bis3:creditnote:2025.5", ctx.VESIDs.CreditNote)
+		assert.True(t, ctx.Output.ConcatenateNotes)
+		assert.True(t, ctx.Output.ForcePaymentMeans31)
+
+		for _, name := range []string{"example-cius", "example"} {
+			rc := ubl.ContextByName(name)
+			require.NotNil(t, rc, name)
+			assert.True(t, rc.Is(*ctx))
+		}
+		assert.NotNil(t, ubl.FindContext(ctx.CustomizationID, ctx.ProfileID))
+	})
+
+	t.Run("json definition", func(t *testing.T) {
+		ctx, err := ubl.LoadContextFile(filepath.Join(getDataPath(), "contexts", "example-dk.json"))
+		require.NoError(t, err)
+		assert.True(t, ctx.Output.VersionID)
+		assert.True(t, ctx.Output.LineTaxTotals)
+		assert.False(t, ctx.Output.ForcePaymentMeans31)
+		assert.NotNil(t, ubl.ContextByName("example-dk"))
+	})
+
+	t.Run("output rules", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-complete.json")
+		require.NoError(t, err)
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(*ctx))
+		require.NoError(t, err)
+		require.Len(t, doc.PaymentMeans, 1)
+		assert.Equal(t, "31", doc.PaymentMeans[0].PaymentMeansCode.Value)
+		require.NotNil(t, doc.PaymentMeans[0].PaymentChannelCode)
+		assert.Equal(t, "IBAN", doc.PaymentMeans[0].PaymentChannelCode.Value)
+		assert.Empty(t, doc.UBLVersionID)
+		assert.Nil(t, doc.InvoiceLines[0].TaxTotal)
+	})
+
+	t.Run("registered contexts", func(t *testing.T) {
+		_, err := ubl.LoadContextFile(filepath.Join(getDataPath(), "contexts", "example-cius.yaml"))
+		assert.ErrorIs(t, err, ubl.ErrContextRegistered)
+
+		_, err = ubl.LoadContext([]byte("name: peppol\ncustomization_id: urn:example.com:other"))
+		assert.ErrorIs(t, err, ubl.ErrContextRegistered)
+
+		_, err = ubl.LoadContext([]byte("name: example-other\naliases: [example]\ncustomization_id: urn:example.com:other"))
+		assert.ErrorIs(t, err, ubl.ErrContextRegistered)
+		assert.Nil(t, ubl.ContextByName("example-other"))
+
+		def := "name: example-peppol\ncustomization_id: \"" + ubl.ContextPeppol.CustomizationID + "\"\nprofile_id: \"" + ubl.ContextPeppol.ProfileID + "\""
+		_, err = ubl.LoadContext([]byte(def))
+		assert.ErrorIs(t, err, ubl.ErrContextRegistered)
+		assert.True(t, ubl.ContextByName("peppol").Is(ubl.ContextPeppol))
+	})
+
+	t.Run("invalid definitions", func(t *testing.T) {
+		_, err := ubl.LoadContext([]byte(`customization_id: "urn:example.com"`))
+		assert.EqualError(t, err, "context definition: name is required")
+
+		_, err = ubl.LoadContext([]byte(`name: example-missing`))
+		assert.EqualError(t, err, "context definition: customization_id is required")
+
+		_, err = ubl.LoadContext([]byte("name: example-addon\ncustomization_id: urn:example.com\naddons: [foo]"))
+		assert.EqualError(t, err, `context definition: unknown addon "foo"`)
+
+		_, err = ubl.LoadContext([]byte(`name: [`))
+		assert.ErrorContains(t, err, "parsing context definition")
+
+		_, err = ubl.LoadContextFile("missing.yaml")
+		assert.ErrorContains(t, err, "reading context definition")
+	})
+}
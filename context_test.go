@@ -1,14 +1,22 @@
 package ubl_test
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/invopop/gobl"
 	ubl "github.com/invopop/gobl.ubl"
 	"github.com/invopop/gobl/addons/de/xrechnung"
 	"github.com/invopop/gobl/addons/eu/en16931"
 	"github.com/invopop/gobl/addons/fr/facturx"
 	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/catalogues/untdid"
 	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -114,6 +122,183 @@ func TestContextPeppol(t *testing.T) {
 	})
 }
 
+func TestContextPeppolNordic(t *testing.T) {
+	t.Run("tax registration round trip", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("peppol-se", "invoice-se.json"), ubl.ContextPeppolSE)
+		require.NoError(t, err)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		env, err := in.(*ubl.Invoice).Convert(ubl.WithContext(ubl.ContextPeppolSE))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		assert.Equal(t, cbc.Code("556036079301"), inv.Supplier.TaxID.Code)
+		require.Len(t, inv.Notes, 1)
+		assert.Equal(t, org.NoteKeyTax, inv.Notes[0].Key)
+		assert.Equal(t, "Godkänd för F-skatt", inv.Notes[0].Text)
+		for _, id := range inv.Supplier.Identities {
+			assert.NotEqual(t, cbc.Code(ubl.TaxSchemeTAX), id.Type)
+		}
+
+		out, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolSE))
+		require.NoError(t, err)
+		pts := out.AccountingSupplierParty.Party.PartyTaxScheme
+		require.Len(t, pts, 2)
+		assert.Equal(t, "Godkänd för F-skatt", pts[1].CompanyID.Value)
+		assert.Equal(t, ubl.TaxSchemeTAX, pts[1].TaxScheme.ID.Value)
+	})
+
+	t.Run("detected when parsing", func(t *testing.T) {
+		for name, c := range map[string]ubl.Context{
+			"peppol-no/invoice-no.json": ubl.ContextPeppolNO,
+			"peppol-se/invoice-se.json": ubl.ContextPeppolSE,
+		} {
+			doc, err := testInvoiceFromContext(name, c)
+			require.NoError(t, err, name)
+			data, err := ubl.Bytes(doc)
+			require.NoError(t, err, name)
+
+			in, err := ubl.Parse(data)
+			require.NoError(t, err, name)
+			env, err := in.(*ubl.Invoice).Convert()
+			require.NoError(t, err, name)
+			inv, ok := env.Extract().(*bill.Invoice)
+			require.True(t, ok, name)
+			require.Len(t, inv.Notes, 1, name)
+			assert.Equal(t, org.NoteKeyTax, inv.Notes[0].Key, name)
+			require.NotEmpty(t, inv.Supplier.Identities, name)
+			assert.NotEmpty(t, inv.Supplier.Identities[0].Type, name)
+
+			// The registration text is kept also in the generic context.
+			out, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppol))
+			require.NoError(t, err, name)
+			pts := out.AccountingSupplierParty.Party.PartyTaxScheme
+			require.Len(t, pts, 2, name)
+			assert.Equal(t, ubl.TaxSchemeTAX, pts[1].TaxScheme.ID.Value, name)
+			assert.Equal(t, inv.Notes[0].Text, pts[1].CompanyID.Value, name)
+			assert.Empty(t, out.Note, name)
+		}
+	})
+
+	t.Run("tax registration from meta", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "peppol-no", "invoice-no.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		inv.Supplier.Identities = nil
+		inv.Supplier.Meta = cbc.Meta{"tax-registration": "Foretaksregisteret"}
+
+		out, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolNO))
+		require.NoError(t, err)
+		pts := out.AccountingSupplierParty.Party.PartyTaxScheme
+		require.Len(t, pts, 2)
+		assert.Equal(t, "Foretaksregisteret", pts[1].CompanyID.Value)
+
+		out, err = ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppol))
+		require.NoError(t, err)
+		assert.Len(t, out.AccountingSupplierParty.Party.PartyTaxScheme, 1)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "peppol-no", "invoice-no.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		inv.Supplier.Identities = nil
+
+		out, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolNO))
+		require.NoError(t, err)
+		assert.Len(t, out.AccountingSupplierParty.Party.PartyTaxScheme, 1)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		for name, c := range map[string]ubl.Context{
+			"ehf":       ubl.ContextPeppolNO,
+			"peppol-se": ubl.ContextPeppolSE,
+		} {
+			ctx := ubl.ContextByName(name)
+			require.NotNil(t, ctx, name)
+			assert.True(t, ctx.Is(c), name)
+		}
+	})
+}
+
+func TestContextPeppolBE(t *testing.T) {
+	load := func(t *testing.T) (*gobl.Envelope, *bill.Invoice) {
+		t.Helper()
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "peppol-be", "invoice-be.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		return env, inv
+	}
+
+	t.Run("enterprise number from vat", func(t *testing.T) {
+		env, _ := load(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolBE))
+		require.NoError(t, err)
+		p := doc.AccountingSupplierParty.Party
+		assert.Equal(t, "0208", p.EndpointID.SchemeID)
+		assert.Equal(t, "0412345614", p.EndpointID.Value)
+		assert.Equal(t, "0208", *p.PartyLegalEntity.CompanyID.SchemeID)
+		assert.Equal(t, "0412345614", p.PartyLegalEntity.CompanyID.Value)
+	})
+
+	t.Run("existing inbox", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Supplier.Inboxes = []*org.Inbox{{Scheme: "9925", Code: "BE0412345614"}}
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolBE))
+		require.NoError(t, err)
+		p := doc.AccountingSupplierParty.Party
+		assert.Equal(t, "9925", p.EndpointID.SchemeID)
+		assert.Equal(t, "0412345614", p.PartyLegalEntity.CompanyID.Value)
+	})
+
+	t.Run("foreign party", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Customer.TaxID = &tax.Identity{Country: "NL", Code: "000099995B57"}
+		inv.Customer.Identities = nil
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolBE))
+		require.NoError(t, err)
+		p := doc.AccountingCustomerParty.Party
+		assert.Nil(t, p.EndpointID)
+		assert.Nil(t, p.PartyLegalEntity.CompanyID)
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		env, _ := load(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextPeppolBE))
+		require.NoError(t, err)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		out, err := in.(*ubl.Invoice).Convert(ubl.WithContext(ubl.ContextPeppolBE))
+		require.NoError(t, err)
+		inv, ok := out.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		require.Len(t, inv.Supplier.Identities, 1)
+		assert.Equal(t, ubl.IdentityTypeKBO, inv.Supplier.Identities[0].Type)
+		assert.Equal(t, cbc.Code("0412345614"), inv.Supplier.Identities[0].Code)
+
+		// Detection finds the context from the Belgian customer.
+		out, err = in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		inv = out.Extract().(*bill.Invoice)
+		require.Len(t, inv.Supplier.Identities, 1)
+		assert.Equal(t, ubl.IdentityTypeKBO, inv.Supplier.Identities[0].Type)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("mercurius")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextPeppolBE))
+	})
+}
+
 func TestContextXRechnung(t *testing.T) {
 	t.Run("basic conversion", func(t *testing.T) {
 		env, err := loadTestEnvelope("invoice-minimal.json")
@@ -138,6 +323,479 @@ func TestContextXRechnung(t *testing.T) {
 	})
 }
 
+func TestContextXRechnungExtension(t *testing.T) {
+	t.Run("sub-lines", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "xrechnung-extension", "invoice-xr-sublines.json"))
+		require.NoError(t, err)
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextXRechnungExtension))
+		require.NoError(t, err)
+		assert.Equal(t, "urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0#conformant#urn:xeinkauf.de:kosit:extension:xrechnung_3.0", doc.CustomizationID)
+		require.Len(t, doc.InvoiceLines[0].SubInvoiceLines, 2)
+		assert.Equal(t, "1.2", doc.InvoiceLines[0].SubInvoiceLines[1].ID)
+		assert.Empty(t, doc.InvoiceLines[1].SubInvoiceLines)
+
+		// sub-lines are only included by the extension
+		doc, err = ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextXRechnung))
+		require.NoError(t, err)
+		assert.Empty(t, doc.InvoiceLines[0].SubInvoiceLines)
+	})
+
+	t.Run("find context", func(t *testing.T) {
+		ctx := ubl.FindContext(ubl.ContextXRechnungExtension.CustomizationID, "")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Output.SubLines)
+		assert.Equal(t, ctx, ubl.ContextByName("xrechnung-extension"))
+	})
+}
+
+func TestContextMyInvois(t *testing.T) {
+	t.Run("type codes", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "myinvois", "credit-note-my-foreign.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		inv.Meta = cbc.Meta{"refund": "true"}
+		inv.SetTags(tax.TagSelfBilled)
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextMyInvois))
+		require.NoError(t, err)
+		assert.Equal(t, "Invoice", doc.XMLName.Local)
+		assert.Equal(t, "14", doc.InvoiceTypeCode)
+		assert.Empty(t, doc.CustomizationID)
+		assert.Empty(t, doc.CreditNoteLines)
+		require.Len(t, doc.InvoiceLines, 1)
+		assert.NotNil(t, doc.InvoiceLines[0].InvoicedQuantity)
+
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `<cbc:InvoiceTypeCode listVersionID="1.0">14</cbc:InvoiceTypeCode>`)
+
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		out, err := in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		pinv, ok := out.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		assert.Equal(t, bill.InvoiceTypeCreditNote, pinv.Type)
+		assert.Equal(t, "true", pinv.Meta["refund"])
+		assert.True(t, pinv.HasTags(tax.TagSelfBilled))
+	})
+
+	t.Run("other documents", func(t *testing.T) {
+		// Documents that look like MyInvois ones are only adapted when
+		// converted with the context.
+		doc := &ubl.Invoice{InvoiceTypeCode: ubl.MyInvoisTypeInvoice}
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "<cbc:InvoiceTypeCode>01</cbc:InvoiceTypeCode>")
+	})
+
+	t.Run("parse without regime", func(t *testing.T) {
+		data, err := testLoadXML("myinvois/invoice-my-standard.xml")
+		require.NoError(t, err)
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		env, err := in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		assert.Empty(t, inv.Regime.GetRegime())
+		assert.NoError(t, env.Validate())
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "myinvois", "invoice-my-standard.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		inv.Type = bill.InvoiceTypeProforma
+
+		_, err = ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextMyInvois))
+		assert.EqualError(t, err, `myinvois: unsupported invoice type "proforma"`)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("myinvois")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextMyInvois))
+	})
+}
+
+func TestContextUBLTR(t *testing.T) {
+	load := func(t *testing.T) (*gobl.Envelope, *bill.Invoice) {
+		t.Helper()
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "ubl-tr", "invoice-tr.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		return env, inv
+	}
+
+	t.Run("signature", func(t *testing.T) {
+		env, _ := load(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextUBLTR))
+		require.NoError(t, err)
+		require.Len(t, doc.Signature, 1)
+		sig := doc.Signature[0]
+		assert.Equal(t, "VKN_TCKN", *sig.IDAttributes.SchemeID)
+		assert.Equal(t, "1234567890", sig.ID)
+		assert.Equal(t, "#Signature_ABC-2024000000012", sig.DigitalSignatureAttachment.ExternalReference.URI)
+		assert.Equal(t, doc.AccountingSupplierParty.Party.PostalAddress, sig.SignatoryParty.PostalAddress)
+	})
+
+	t.Run("original copy indicator", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Meta = cbc.Meta{"copy": "true"}
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextUBLTR))
+		require.NoError(t, err)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "<cbc:CopyIndicator>true</cbc:CopyIndicator>")
+		assert.NotContains(t, string(data), "<cbc:CopyIndicator>false</cbc:CopyIndicator>")
+	})
+
+	t.Run("withholding", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Regime = tax.WithRegime("ES")
+		for _, l := range inv.Lines {
+			p := num.MakePercentage(15, 2)
+			l.Taxes = append(l.Taxes, &tax.Combo{Category: "IRPF", Percent: &p})
+		}
+		require.NoError(t, inv.Calculate())
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextUBLTR))
+		require.NoError(t, err)
+		assert.Equal(t, ubl.UBLTRTypeWithholding, doc.InvoiceTypeCode)
+		require.Len(t, doc.TaxTotal, 1)
+		require.Len(t, doc.TaxTotal[0].TaxSubtotal, 1)
+		require.Len(t, doc.WithholdingTaxTotal, 1)
+		wt := doc.WithholdingTaxTotal[0]
+		assert.Equal(t, "2700.00", wt.TaxAmount.Value)
+		require.Len(t, wt.TaxSubtotal, 1)
+		assert.Equal(t, "15", *wt.TaxSubtotal[0].Percent)
+		assert.Equal(t, "IRPF", wt.TaxSubtotal[0].TaxCategory.TaxScheme.ID.Value)
+		lwt := doc.InvoiceLines[0].WithholdingTaxTotal
+		require.Len(t, lwt, 1)
+		assert.Equal(t, "15", *lwt[0].TaxSubtotal[0].Percent)
+		assert.Len(t, doc.InvoiceLines[0].TaxTotal[0].TaxSubtotal, 1)
+	})
+
+	t.Run("return", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Type = bill.InvoiceTypeCreditNote
+		inv.Preceding = []*org.DocumentRef{{Code: "2024000000011", IssueDate: cal.NewDate(2024, 7, 1)}}
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextUBLTR))
+		require.NoError(t, err)
+		assert.Equal(t, "Invoice", doc.XMLName.Local)
+		assert.Equal(t, ubl.UBLTRTypeReturn, doc.InvoiceTypeCode)
+		assert.Len(t, doc.InvoiceLines, 2)
+	})
+
+	t.Run("missing supplier tax ID", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Supplier.TaxID = nil
+		_, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextUBLTR))
+		assert.EqualError(t, err, "ubl-tr: supplier requires a Turkish tax ID")
+	})
+
+	t.Run("individual customer", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Customer.TaxID.Code = "12345678901"
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextUBLTR))
+		require.NoError(t, err)
+		ids := doc.AccountingCustomerParty.Party.PartyIdentification
+		require.Len(t, ids, 1)
+		assert.Equal(t, "TCKN", *ids[0].ID.SchemeID)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("e-fatura")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextUBLTR))
+	})
+}
+
+func TestContextSUNAT(t *testing.T) {
+	load := func(t *testing.T) (*gobl.Envelope, *bill.Invoice) {
+		t.Helper()
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "sunat", "invoice-pe.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		return env, inv
+	}
+
+	t.Run("header", func(t *testing.T) {
+		env, _ := load(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextSUNAT))
+		require.NoError(t, err)
+		assert.Equal(t, "2.1", doc.UBLVersionID)
+		assert.Equal(t, "2.0", doc.CustomizationID)
+		assert.Equal(t, ubl.SUNATOperationSale, doc.ProfileID)
+		assert.Equal(t, "F001-123", doc.ID)
+		assert.Equal(t, ubl.SUNATTypeInvoice, doc.InvoiceTypeCode)
+
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `<cbc:InvoiceTypeCode listID="0101">01</cbc:InvoiceTypeCode>`)
+	})
+
+	t.Run("receipt", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Customer.TaxID = nil
+		inv.Customer.Identities = []*org.Identity{{Type: ubl.IdentityTypeDNI, Code: "46027897"}}
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextSUNAT))
+		require.NoError(t, err)
+		assert.Equal(t, ubl.SUNATTypeReceipt, doc.InvoiceTypeCode)
+		ids := doc.AccountingCustomerParty.Party.PartyIdentification
+		require.Len(t, ids, 1)
+		assert.Equal(t, "1", *ids[0].ID.SchemeID)
+		assert.Equal(t, "46027897", ids[0].ID.Value)
+	})
+
+	t.Run("tax schemes", func(t *testing.T) {
+		env, _ := load(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextSUNAT))
+		require.NoError(t, err)
+		ts := doc.TaxTotal[0].TaxSubtotal[0].TaxCategory.TaxScheme
+		assert.Equal(t, "1000", ts.ID.Value)
+		assert.Equal(t, "IGV", *ts.Name)
+		assert.Equal(t, "VAT", ts.TaxTypeCode)
+		tc := doc.InvoiceLines[0].TaxTotal[0].TaxSubtotal[0].TaxCategory
+		assert.Equal(t, "10", *tc.TaxExemptionReasonCode)
+		assert.Empty(t, doc.InvoiceLines[0].Item.ClassifiedTaxCategory)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Type = bill.InvoiceTypeCreditNote
+		inv.Preceding = []*org.DocumentRef{{Series: "F001", Code: "122", IssueDate: cal.NewDate(2024, 7, 1)}}
+		_, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextSUNAT))
+		assert.EqualError(t, err, `sunat: unsupported invoice type "credit-note"`)
+	})
+
+	t.Run("missing supplier RUC", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Supplier.TaxID = nil
+		_, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextSUNAT))
+		assert.EqualError(t, err, "sunat: supplier requires a Peruvian RUC")
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("pe-sunat")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextSUNAT))
+	})
+}
+
+func TestContextNLCIUS(t *testing.T) {
+	t.Run("legal entity schemes", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("nlcius", "invoice-nl-government.json"), ubl.ContextNLCIUS)
+		require.NoError(t, err)
+		supplier := doc.AccountingSupplierParty.Party.PartyLegalEntity.CompanyID
+		assert.Equal(t, "12345678", supplier.Value)
+		assert.Equal(t, "0106", *supplier.SchemeID)
+		customer := doc.AccountingCustomerParty.Party.PartyLegalEntity.CompanyID
+		assert.Equal(t, "0190", *customer.SchemeID)
+		assert.Empty(t, doc.AccountingSupplierParty.Party.PartyIdentification)
+	})
+
+	t.Run("other contexts keep identities", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("nlcius", "invoice-nl-government.json"), ubl.ContextPeppol)
+		require.NoError(t, err)
+		p := doc.AccountingSupplierParty.Party
+		if p.PartyLegalEntity != nil {
+			assert.Nil(t, p.PartyLegalEntity.CompanyID)
+		}
+		require.NotEmpty(t, p.PartyIdentification)
+		assert.Equal(t, "12345678", p.PartyIdentification[0].ID.Value)
+		assert.Nil(t, p.PartyIdentification[0].ID.SchemeID)
+	})
+
+	t.Run("missing requirements", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "nlcius", "invoice-nl-government.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		inv.Ordering = nil
+		inv.Payment.Instructions = nil
+		inv.Supplier.Identities = nil
+
+		_, err = ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextNLCIUS))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ordering: (code: required.)")
+		assert.Contains(t, err.Error(), "payment: (instructions: required.)")
+		assert.Contains(t, err.Error(), "supplier: (identities: KvK or OIN number required.)")
+		assert.NotContains(t, err.Error(), "customer")
+	})
+
+	t.Run("find context", func(t *testing.T) {
+		for _, c := range []ubl.Context{ubl.ContextNLCIUS, ubl.ContextNLCIUSExtended} {
+			ctx := ubl.FindContext(c.CustomizationID, c.ProfileID)
+			require.NotNil(t, ctx)
+			assert.True(t, ctx.Is(c))
+		}
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		for name, c := range map[string]ubl.Context{
+			"nlcius":          ubl.ContextNLCIUS,
+			"si-ubl":          ubl.ContextNLCIUS,
+			"nlcius-extended": ubl.ContextNLCIUSExtended,
+		} {
+			ctx := ubl.ContextByName(name)
+			require.NotNil(t, ctx, name)
+			assert.True(t, ctx.Is(c), name)
+		}
+	})
+}
+
+func TestContextROCIUS(t *testing.T) {
+	load := func(t *testing.T) (*gobl.Envelope, *bill.Invoice) {
+		t.Helper()
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "ro-cius", "invoice-ro.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		return env, inv
+	}
+
+	t.Run("county subentities", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Supplier.Addresses[0].Region = "Municipiul București"
+		inv.Supplier.Addresses[0].Locality = "București, Sectorul 6"
+		inv.Customer.Addresses[0].Region = "Județul Argeș"
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextROCIUS))
+		require.NoError(t, err)
+		addr := doc.AccountingSupplierParty.Party.PostalAddress
+		assert.Equal(t, "RO-B", *addr.CountrySubentity)
+		assert.Equal(t, "SECTOR6", *addr.CityName)
+		assert.Equal(t, "RO-AG", *doc.AccountingCustomerParty.Party.PostalAddress.CountrySubentity)
+	})
+
+	t.Run("other contexts keep addresses", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Supplier.Addresses[0].Region = "Municipiul București"
+		inv.Supplier.Addresses[0].Locality = "București, Sectorul 6"
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextEN16931))
+		require.NoError(t, err)
+		addr := doc.AccountingSupplierParty.Party.PostalAddress
+		assert.Equal(t, "Municipiul București", *addr.CountrySubentity)
+		assert.Equal(t, "București, Sectorul 6", *addr.CityName)
+	})
+
+	t.Run("constraints", func(t *testing.T) {
+		env, inv := load(t)
+		inv.Supplier.Addresses[0].Locality = "Bucharest"
+		inv.Customer.Addresses[0].Region = "Transylvania"
+		inv.Lines[0].Item.Name = strings.Repeat("x", 101)
+
+		_, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextROCIUS))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ro-cius: seller city in Bucharest must be a sector, SECTOR1 to SECTOR6")
+		assert.Contains(t, err.Error(), `ro-cius: buyer county "Transylvania" is not an ISO 3166-2:RO code`)
+		assert.Contains(t, err.Error(), "ro-cius: line 1 item name (BT-153) must not exceed 100 characters")
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("efactura")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextROCIUS))
+	})
+}
+
+func TestContextHRCIUS(t *testing.T) {
+	t.Run("operator and classifications", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("hr-cius", "invoice-hr.json"), ubl.ContextHRCIUS)
+		require.NoError(t, err)
+		assert.Equal(t, "P1", doc.ProfileID)
+		sc := doc.AccountingSupplierParty.SellerContact
+		require.NotNil(t, sc)
+		assert.Equal(t, "69435151530", *sc.ID)
+		assert.Equal(t, "Ana Horvat", *sc.Name)
+		it := doc.InvoiceLines[0].Item
+		require.NotNil(t, it.CommodityClassification)
+		cc := (*it.CommodityClassification)[0].ItemClassificationCode
+		assert.Equal(t, "CG", *cc.ListID)
+		assert.Equal(t, "95.11.10", cc.Value)
+		assert.Nil(t, it.BuyersItemIdentification)
+	})
+
+	t.Run("business process", func(t *testing.T) {
+		env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "hr-cius", "invoice-hr.json"))
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		inv.Meta = cbc.Meta{"ubl-profile": "P9"}
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextHRCIUS))
+		require.NoError(t, err)
+		assert.Equal(t, "P9", doc.ProfileID)
+
+		ctx := ubl.FindContext(doc.CustomizationID, doc.ProfileID)
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextHRCIUS))
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("fiskalizacija")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextHRCIUS))
+	})
+}
+
+func TestContextPINT(t *testing.T) {
+	t.Run("singapore tax categories", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("pint-sg", "invoice-sg.json"), ubl.ContextPINTSG)
+		require.NoError(t, err)
+		require.Len(t, doc.TaxTotal, 1)
+		require.Len(t, doc.TaxTotal[0].TaxSubtotal, 2)
+		tc := doc.TaxTotal[0].TaxSubtotal[1].TaxCategory
+		assert.Equal(t, "ZR", tc.ID.Value)
+		assert.Equal(t, ubl.TaxSchemeGST, tc.TaxScheme.ID.Value)
+		assert.Equal(t, "ZR", doc.InvoiceLines[1].Item.ClassifiedTaxCategory.ID.Value)
+		assert.Equal(t, "0195ce71-dc9c-72c8-bf2c-9890a4a9f0a2", doc.UUID)
+		assert.Empty(t, doc.UBLVersionID)
+
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		env, err := in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		combo := inv.Lines[1].Taxes[0]
+		assert.Equal(t, tax.CategoryGST, combo.Category)
+		assert.Equal(t, tax.KeyZero, combo.Key)
+		assert.Equal(t, "Z", combo.Ext.Get(untdid.ExtKeyTaxCategory).String())
+	})
+
+	t.Run("gst party tax scheme", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("pint-aunz", "invoice-aunz.json"), ubl.ContextPINTAUNZ)
+		require.NoError(t, err)
+		pts := doc.AccountingSupplierParty.Party.PartyTaxScheme
+		require.Len(t, pts, 1)
+		assert.Equal(t, ubl.TaxSchemeGST, pts[0].TaxScheme.ID.Value)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		for name, c := range map[string]ubl.Context{
+			"pint-aunz":  ubl.ContextPINTAUNZ,
+			"invoicenow": ubl.ContextPINTSG,
+			"pint-jp":    ubl.ContextPINTJP,
+			"pint-ae":    ubl.ContextPINTAE,
+		} {
+			ctx := ubl.ContextByName(name)
+			require.NotNil(t, ctx, name)
+			assert.True(t, ctx.Is(c), name)
+		}
+	})
+}
+
 func TestContextPeppolFranceCIUS(t *testing.T) {
 	t.Run("with ubl-profile meta", func(t *testing.T) {
 		env, err := loadTestEnvelope("invoice-minimal.json")
@@ -479,3 +1137,77 @@ func TestFindContext(t *testing.T) {
 		assert.Nil(t, ctx)
 	})
 }
+
+func TestContextRegistry(t *testing.T) {
+	t.Run("built in contexts by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("peppol")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextPeppol))
+
+		ctx = ubl.ContextByName("OIOUBL21")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextOIOUBL21))
+
+		assert.Nil(t, ubl.ContextByName("unknown"))
+	})
+
+	t.Run("aliases do not duplicate contexts", func(t *testing.T) {
+		count := 0
+		for _, c := range ubl.Contexts() {
+			if c.Is(ubl.ContextEN16931) {
+				count++
+			}
+		}
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("register custom context", func(t *testing.T) {
+		custom := ubl.Context{
+			CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:example.com:acme:1.0",
+			ProfileID:       "urn:example.com:acme:billing",
+			Addons:          []cbc.Key{en16931.V2017},
+		}
+		ubl.RegisterContext("acme", custom)
+
+		ctx := ubl.ContextByName("ACME")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(custom))
+
+		ctx = ubl.FindContext(custom.CustomizationID, "")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(custom))
+		assert.Contains(t, ubl.Contexts(), custom)
+
+		// registering again with the same name replaces the existing definition
+		custom.VESIDs.Invoice = "com.example:acme:1.0"
+		ubl.RegisterContext("acme", custom, "acme-cius")
+		ctx = ubl.ContextByName("acme")
+		require.NotNil(t, ctx)
+		assert.Equal(t, "com.example:acme:1.0", ctx.VESIDs.Invoice)
+		ctx = ubl.ContextByName("acme-cius")
+		require.NotNil(t, ctx)
+		assert.Equal(t, "com.example:acme:1.0", ctx.VESIDs.Invoice)
+	})
+
+	t.Run("contexts sharing identifiers", func(t *testing.T) {
+		for name, c := range map[string]ubl.Context{
+			"peppol":    ubl.ContextPeppol,
+			"peppol-no": ubl.ContextPeppolNO,
+			"ehf":       ubl.ContextPeppolNO,
+			"peppol-se": ubl.ContextPeppolSE,
+			"peppol-be": ubl.ContextPeppolBE,
+			"mercurius": ubl.ContextPeppolBE,
+		} {
+			ctx := ubl.ContextByName(name)
+			require.NotNil(t, ctx, name)
+			assert.Equal(t, c, *ctx, name)
+		}
+
+		// documents with the shared identifiers use the generic context
+		for _, c := range []ubl.Context{ubl.ContextPeppolNO, ubl.ContextPeppolSE, ubl.ContextPeppolBE} {
+			ctx := ubl.FindContext(c.CustomizationID, c.ProfileID)
+			require.NotNil(t, ctx)
+			assert.Equal(t, ubl.ContextPeppol, *ctx)
+		}
+	})
+}
@@ -5,9 +5,12 @@ import "github.com/invopop/gobl/bill"
 // Delivery represents delivery information
 type Delivery struct {
 	ActualDeliveryDate      *string   `xml:"cbc:ActualDeliveryDate"`
+	TrackingID              *string   `xml:"cbc:TrackingID"`
 	DeliveryLocation        *Location `xml:"cac:DeliveryLocation"`
+	RequestedDeliveryPeriod *Period   `xml:"cac:RequestedDeliveryPeriod"`
 	EstimatedDeliveryPeriod *Period   `xml:"cac:EstimatedDeliveryPeriod"`
 	DeliveryParty           *Party    `xml:"cac:DeliveryParty"`
+	Despatch                *Despatch `xml:"cac:Despatch"`
 }
 
 // Location represents a location
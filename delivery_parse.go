@@ -7,20 +7,37 @@ import (
 )
 
 func (ui *Invoice) goblAddDelivery(out *bill.Invoice) error {
+	d, err := goblDeliveryDetails(ui.Delivery, ui.DeliveryTerms)
+	if err != nil {
+		return err
+	}
+
+	if d.Receiver != nil || d.Date != nil || d.Identities != nil {
+		out.Delivery = d
+	}
+	return nil
+}
+
+// goblDeliveryDetails builds GOBL delivery details from a set of UBL deliveries
+// and optional delivery terms. The result is never nil, so callers should check
+// for content before assigning it.
+func goblDeliveryDetails(deliveries []*Delivery, terms *DeliveryTerms) (*bill.DeliveryDetails, error) {
 	d := &bill.DeliveryDetails{}
 
 	// Only one delivery Location and Receiver are supported, so if more than one is passed the former will be overwritten
-	if len(ui.Delivery) > 0 {
-		for _, del := range ui.Delivery {
+	if len(deliveries) > 0 {
+		for _, del := range deliveries {
 			if del.ActualDeliveryDate != nil {
 				deliveryDate, err := parseDate(*del.ActualDeliveryDate)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				d.Date = &deliveryDate
 			}
 			if del.EstimatedDeliveryPeriod != nil {
 				d.Period = goblPeriodDates(del.EstimatedDeliveryPeriod)
+			} else if del.RequestedDeliveryPeriod != nil {
+				d.Period = goblPeriodDates(del.RequestedDeliveryPeriod)
 			}
 			if del.DeliveryLocation != nil && del.DeliveryLocation.ID != nil {
 				id := &org.Identity{
@@ -44,16 +61,13 @@ func (ui *Invoice) goblAddDelivery(out *bill.Invoice) error {
 		}
 	}
 
-	if ui.DeliveryTerms != nil {
+	if terms != nil {
 		d.Identities = []*org.Identity{
 			{
-				Code: cbc.Code(ui.DeliveryTerms.ID),
+				Code: cbc.Code(terms.ID),
 			},
 		}
 	}
 
-	if d.Receiver != nil || d.Date != nil || d.Identities != nil {
-		out.Delivery = d
-	}
-	return nil
+	return d, nil
 }
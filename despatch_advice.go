@@ -0,0 +1,267 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/invopop/gobl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/org"
+)
+
+// Main UBL DespatchAdvice Namespace
+const (
+	NamespaceUBLDespatchAdvice = "urn:oasis:names:specification:ubl:schema:xsd:DespatchAdvice-2"
+)
+
+// Schema location for the despatch advice document
+const (
+	SchemaLocationDespatchAdvice = "urn:oasis:names:specification:ubl:schema:xsd:DespatchAdvice-2 http://docs.oasis-open.org/ubl/os-UBL-2.1/xsd/maindoc/UBL-DespatchAdvice-2.1.xsd"
+)
+
+// notApplicable is used by Peppol for mandatory identifiers that have
+// no meaningful value.
+const notApplicable = "NA"
+
+// DespatchAdvice represents the root element of a UBL DespatchAdvice document.
+type DespatchAdvice struct {
+	// Attributes
+	XMLName        xml.Name
+	CACNamespace   string `xml:"xmlns:cac,attr"`
+	CBCNamespace   string `xml:"xmlns:cbc,attr"`
+	EXTNamespace   string `xml:"xmlns:ext,attr,omitempty"`
+	QDTNamespace   string `xml:"xmlns:qdt,attr"`
+	UDTNamespace   string `xml:"xmlns:udt,attr"`
+	CCTSNamespace  string `xml:"xmlns:ccts,attr"`
+	UBLNamespace   string `xml:"xmlns,attr"`
+	XSINamespace   string `xml:"xmlns:xsi,attr"`
+	SchemaLocation string `xml:"xsi:schemaLocation,attr"`
+
+	UBLExtensions          *Extensions `xml:"ext:UBLExtensions,omitempty"`
+	UBLVersionID           string      `xml:"cbc:UBLVersionID,omitempty"`
+	CustomizationID        string      `xml:"cbc:CustomizationID,omitempty"`
+	ProfileID              string      `xml:"cbc:ProfileID,omitempty"`
+	ProfileExecutionID     string      `xml:"cbc:ProfileExecutionID,omitempty"`
+	ID                     string      `xml:"cbc:ID"`
+	CopyIndicator          bool        `xml:"cbc:CopyIndicator,omitempty"`
+	UUID                   string      `xml:"cbc:UUID,omitempty"`
+	IssueDate              string      `xml:"cbc:IssueDate"`
+	IssueTime              string      `xml:"cbc:IssueTime,omitempty"`
+	DocumentStatusCode     string      `xml:"cbc:DocumentStatusCode,omitempty"`
+	DespatchAdviceTypeCode string      `xml:"cbc:DespatchAdviceTypeCode,omitempty"`
+
+	Note                        []string         `xml:"cbc:Note,omitempty"`
+	LineCountNumeric            int              `xml:"cbc:LineCountNumeric,omitempty"`
+	OrderReference              []OrderReference `xml:"cac:OrderReference,omitempty"`
+	AdditionalDocumentReference []Reference      `xml:"cac:AdditionalDocumentReference,omitempty"`
+	Signature                   []Signature      `xml:"cac:Signature,omitempty"`
+	DespatchSupplierParty       SupplierParty    `xml:"cac:DespatchSupplierParty"`
+	DeliveryCustomerParty       CustomerParty    `xml:"cac:DeliveryCustomerParty"`
+	BuyerCustomerParty          *CustomerParty   `xml:"cac:BuyerCustomerParty,omitempty"`
+	SellerSupplierParty         *SupplierParty   `xml:"cac:SellerSupplierParty,omitempty"`
+	OriginatorCustomerParty     *CustomerParty   `xml:"cac:OriginatorCustomerParty,omitempty"`
+	Shipment                    *Shipment        `xml:"cac:Shipment,omitempty"`
+	DespatchLines               []DespatchLine   `xml:"cac:DespatchLine"`
+}
+
+// Shipment represents the shipment details of a despatch advice
+type Shipment struct {
+	ID          string        `xml:"cbc:ID"`
+	Information []string      `xml:"cbc:Information,omitempty"`
+	Consignment []Consignment `xml:"cac:Consignment,omitempty"`
+	Delivery    *Delivery     `xml:"cac:Delivery,omitempty"`
+}
+
+// Consignment represents a consignment within a shipment
+type Consignment struct {
+	ID           string `xml:"cbc:ID"`
+	CarrierParty *Party `xml:"cac:CarrierParty,omitempty"`
+}
+
+// Despatch represents the despatch details of a delivery
+type Despatch struct {
+	ActualDespatchDate *string        `xml:"cbc:ActualDespatchDate,omitempty"`
+	DespatchAddress    *PostalAddress `xml:"cac:DespatchAddress,omitempty"`
+	DespatchParty      *Party         `xml:"cac:DespatchParty,omitempty"`
+}
+
+// DespatchLine represents a line in a despatch advice
+type DespatchLine struct {
+	ID                  string               `xml:"cbc:ID"`
+	UUID                string               `xml:"cbc:UUID,omitempty"`
+	Note                []string             `xml:"cbc:Note,omitempty"`
+	LineStatusCode      string               `xml:"cbc:LineStatusCode,omitempty"`
+	DeliveredQuantity   *Quantity            `xml:"cbc:DeliveredQuantity,omitempty"`
+	BackorderQuantity   *Quantity            `xml:"cbc:BackorderQuantity,omitempty"`
+	BackorderReason     []string             `xml:"cbc:BackorderReason,omitempty"`
+	OutstandingQuantity *Quantity            `xml:"cbc:OutstandingQuantity,omitempty"`
+	OutstandingReason   []string             `xml:"cbc:OutstandingReason,omitempty"`
+	OversupplyQuantity  *Quantity            `xml:"cbc:OversupplyQuantity,omitempty"`
+	OrderLineReference  []OrderLineReference `xml:"cac:OrderLineReference"`
+	DocumentReference   []Reference          `xml:"cac:DocumentReference,omitempty"`
+	Item                *Item                `xml:"cac:Item"`
+}
+
+func ublDespatchAdvice(dlv *bill.Delivery, o *options) (*DespatchAdvice, error) {
+	customizationID := o.context.CustomizationID
+	if o.context.OutputCustomizationID != "" {
+		customizationID = o.context.OutputCustomizationID
+	}
+
+	out := &DespatchAdvice{
+		XMLName:         xml.Name{Local: "DespatchAdvice"},
+		CACNamespace:    NamespaceCAC,
+		CBCNamespace:    NamespaceCBC,
+		EXTNamespace:    NamespaceEXT,
+		QDTNamespace:    NamespaceQDT,
+		UDTNamespace:    NamespaceUDT,
+		UBLNamespace:    NamespaceUBLDespatchAdvice,
+		CCTSNamespace:   NamespaceCCTS,
+		XSINamespace:    NamespaceXSI,
+		SchemaLocation:  SchemaLocationDespatchAdvice,
+		CustomizationID: customizationID,
+		ProfileID:       o.context.ProfileID,
+		ID:              invoiceNumber(dlv.Series, dlv.Code),
+		IssueDate:       formatDate(dlv.IssueDate),
+	}
+
+	if dlv.IssueTime != nil && !dlv.IssueTime.IsZero() {
+		out.IssueTime = dlv.IssueTime.String()
+	}
+
+	for _, note := range dlv.Notes {
+		out.Note = append(out.Note, note.Text)
+	}
+
+	if dlv.Supplier != nil {
+		out.DespatchSupplierParty = SupplierParty{Party: newParty(dlv.Supplier)}
+	}
+	switch {
+	case dlv.Customer != nil:
+		out.DeliveryCustomerParty = CustomerParty{Party: newParty(dlv.Customer)}
+	case dlv.Receiver != nil:
+		out.DeliveryCustomerParty = CustomerParty{Party: newParty(dlv.Receiver)}
+	}
+
+	if ord := dlv.Ordering; ord != nil {
+		for _, p := range ord.Purchases {
+			ref := OrderReference{
+				ID: p.Series.Join(p.Code).String(),
+			}
+			if p.IssueDate != nil {
+				ref.IssueDate = p.IssueDate.String()
+			}
+			out.OrderReference = append(out.OrderReference, ref)
+		}
+		if ord.Buyer != nil {
+			out.BuyerCustomerParty = &CustomerParty{Party: newParty(ord.Buyer)}
+		}
+		if ord.Seller != nil {
+			out.SellerSupplierParty = &SupplierParty{Party: newParty(ord.Seller)}
+		}
+	}
+
+	out.AdditionalDocumentReference = newAttachmentReferences(dlv.Attachments)
+	out.Shipment = newShipment(dlv)
+
+	for _, l := range dlv.Lines {
+		out.DespatchLines = append(out.DespatchLines, newDespatchLine(l))
+	}
+
+	return out, nil
+}
+
+// newShipment groups the despatch, receiving and courier details of the
+// delivery into a single UBL shipment.
+func newShipment(dlv *bill.Delivery) *Shipment {
+	if dlv.Tracking == nil && dlv.DespatchDate == nil && dlv.ReceiveDate == nil &&
+		dlv.Despatcher == nil && dlv.Receiver == nil && dlv.Courier == nil {
+		return nil
+	}
+
+	d := newDelivery(&bill.DeliveryDetails{Receiver: dlv.Receiver})
+	if dlv.ReceiveDate != nil {
+		d.EstimatedDeliveryPeriod = &Period{
+			StartDate: formatDate(*dlv.ReceiveDate),
+			EndDate:   formatDate(*dlv.ReceiveDate),
+		}
+	}
+	if dlv.Tracking != nil && dlv.Tracking.Code != "" {
+		code := dlv.Tracking.Code.String()
+		d.TrackingID = &code
+	}
+	if dlv.DespatchDate != nil || dlv.Despatcher != nil {
+		d.Despatch = newDespatch(dlv.DespatchDate, dlv.Despatcher)
+	}
+
+	out := &Shipment{
+		ID:       notApplicable,
+		Delivery: d,
+	}
+	if dlv.Courier != nil {
+		out.Consignment = []Consignment{
+			{
+				ID:           notApplicable,
+				CarrierParty: newDeliveryParty(dlv.Courier),
+			},
+		}
+	}
+
+	return out
+}
+
+func newDespatch(date *cal.Date, despatcher *org.Party) *Despatch {
+	out := new(Despatch)
+	if date != nil {
+		d := formatDate(*date)
+		out.ActualDespatchDate = &d
+	}
+	if despatcher != nil {
+		out.DespatchParty = newDeliveryParty(despatcher)
+		out.DespatchAddress = newAddress(despatcher.Addresses)
+	}
+	return out
+}
+
+func newDespatchLine(l *bill.Line) DespatchLine {
+	dl := DespatchLine{
+		ID: strconv.Itoa(l.Index),
+		DeliveredQuantity: &Quantity{
+			Value: l.Quantity.String(),
+		},
+		OrderLineReference: []OrderLineReference{
+			{LineID: notApplicable},
+		},
+	}
+	if l.Item != nil && l.Item.Unit != "" {
+		dl.DeliveredQuantity.UnitCode = string(l.Item.Unit.UNECE())
+	}
+	if l.Order != "" {
+		dl.OrderLineReference[0].LineID = l.Order.String()
+	}
+	for _, note := range l.Notes {
+		dl.Note = append(dl.Note, note.Text)
+	}
+	if l.Item != nil {
+		dl.Item = newItem(l)
+		// Despatch advices do not carry tax information
+		dl.Item.ClassifiedTaxCategory = nil
+	}
+	return dl
+}
+
+// ConvertDespatchAdvice is a convenience function that converts a GOBL
+// envelope containing a delivery into a UBL DespatchAdvice document.
+func ConvertDespatchAdvice(env *gobl.Envelope, opts ...Option) (*DespatchAdvice, error) {
+	doc, err := Convert(env, opts...)
+	if err != nil {
+		return nil, err
+	}
+	da, ok := doc.(*DespatchAdvice)
+	if !ok {
+		return nil, fmt.Errorf("expected despatch advice, got %T", doc)
+	}
+	return da, nil
+}
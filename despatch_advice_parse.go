@@ -0,0 +1,203 @@
+package ubl
+
+import (
+	"github.com/invopop/gobl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/l10n"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/uuid"
+)
+
+// Convert converts the UBL DespatchAdvice to a GOBL envelope containing a
+// delivery advice.
+func (da *DespatchAdvice) Convert() (*gobl.Envelope, error) {
+	dlv, err := da.goblDelivery()
+	if err != nil {
+		return nil, err
+	}
+
+	env := gobl.NewEnvelope()
+	if err := env.Insert(dlv); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+func (da *DespatchAdvice) goblDelivery() (*bill.Delivery, error) {
+	out := &bill.Delivery{
+		Type:     bill.DeliveryTypeAdvice,
+		Code:     cbc.Code(da.ID),
+		Supplier: goblParty(da.DespatchSupplierParty.Party),
+		Customer: goblParty(da.DeliveryCustomerParty.Party),
+	}
+
+	issueDate, err := parseDate(da.IssueDate)
+	if err != nil {
+		return nil, err
+	}
+	out.IssueDate = issueDate
+
+	if da.UUID != "" {
+		id, err := uuid.Parse(da.UUID)
+		if err == nil {
+			out.UUID = id
+		}
+	}
+
+	for _, note := range da.Note {
+		out.Notes = append(out.Notes, &org.Note{
+			Text: cleanString(note),
+		})
+	}
+
+	ordering := new(bill.Ordering)
+	for _, ref := range da.OrderReference {
+		if ref.ID == "" || ref.ID == notApplicable {
+			continue
+		}
+		docRef := &org.DocumentRef{
+			Code: cbc.Code(ref.ID),
+		}
+		if ref.IssueDate != "" {
+			d, err := parseDate(ref.IssueDate)
+			if err != nil {
+				return nil, err
+			}
+			docRef.IssueDate = &d
+		}
+		ordering.Purchases = append(ordering.Purchases, docRef)
+	}
+	if da.BuyerCustomerParty != nil {
+		ordering.Buyer = goblParty(da.BuyerCustomerParty.Party)
+	}
+	if da.SellerSupplierParty != nil {
+		ordering.Seller = goblParty(da.SellerSupplierParty.Party)
+	}
+	if hasOrderingData(ordering) || ordering.Buyer != nil || ordering.Seller != nil {
+		out.Ordering = ordering
+	}
+
+	if err := da.goblAddShipment(out); err != nil {
+		return nil, err
+	}
+
+	for _, dl := range da.DespatchLines {
+		line, err := goblDespatchLine(&dl)
+		if err != nil {
+			return nil, err
+		}
+		out.Lines = append(out.Lines, line)
+	}
+
+	out.Attachments = goblAttachments(da.AdditionalDocumentReference)
+
+	if out.Supplier == nil || out.Supplier.TaxID == nil {
+		// Despatch advices do not include a currency, so the regime is taken
+		// from the first address available for totals to be calculated.
+		if c := partiesCountry(out.Supplier, out.Despatcher, out.Customer, out.Receiver); c != "" {
+			out.SetRegime(l10n.TaxCountryCode(c))
+		}
+	}
+
+	return out, nil
+}
+
+func partiesCountry(parties ...*org.Party) l10n.ISOCountryCode {
+	for _, p := range parties {
+		if p == nil {
+			continue
+		}
+		for _, a := range p.Addresses {
+			if a != nil && a.Country != "" {
+				return a.Country
+			}
+		}
+	}
+	return ""
+}
+
+func (da *DespatchAdvice) goblAddShipment(out *bill.Delivery) error {
+	s := da.Shipment
+	if s == nil {
+		return nil
+	}
+
+	for _, c := range s.Consignment {
+		if c.CarrierParty != nil {
+			out.Courier = goblParty(c.CarrierParty)
+			break
+		}
+	}
+
+	if s.Delivery == nil {
+		return nil
+	}
+
+	d, err := goblDeliveryDetails([]*Delivery{s.Delivery}, nil)
+	if err != nil {
+		return err
+	}
+	out.Receiver = d.Receiver
+	if s.Delivery.DeliveryParty != nil && out.Receiver != nil && out.Receiver.Name == "" {
+		// The delivery location replaces the receiver party, so keep its name
+		out.Receiver.Name = goblParty(s.Delivery.DeliveryParty).Name
+	}
+	switch {
+	case d.Date != nil:
+		out.ReceiveDate = d.Date
+	case d.Period != nil && !d.Period.End.IsZero():
+		out.ReceiveDate = &d.Period.End
+	case d.Period != nil && !d.Period.Start.IsZero():
+		out.ReceiveDate = &d.Period.Start
+	}
+
+	if s.Delivery.TrackingID != nil && *s.Delivery.TrackingID != "" {
+		out.Tracking = &bill.Tracking{
+			Code: cbc.Code(*s.Delivery.TrackingID),
+		}
+	}
+
+	if ds := s.Delivery.Despatch; ds != nil {
+		if ds.ActualDespatchDate != nil {
+			date, err := parseDate(*ds.ActualDespatchDate)
+			if err != nil {
+				return err
+			}
+			out.DespatchDate = &date
+		}
+		if ds.DespatchParty != nil {
+			out.Despatcher = goblParty(ds.DespatchParty)
+		}
+		if ds.DespatchAddress != nil {
+			if out.Despatcher == nil {
+				out.Despatcher = new(org.Party)
+			}
+			out.Despatcher.Addresses = []*org.Address{
+				parseAddress(ds.DespatchAddress),
+			}
+		}
+	}
+
+	return nil
+}
+
+func goblDespatchLine(dl *DespatchLine) (*bill.Line, error) {
+	line, err := goblUnpricedLine(dl.DeliveredQuantity, dl.Item, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range dl.OrderLineReference {
+		if ref.LineID != "" && ref.LineID != notApplicable {
+			line.Order = cbc.Code(ref.LineID)
+			break
+		}
+	}
+	for _, note := range dl.Note {
+		if note != "" {
+			line.Notes = append(line.Notes, &org.Note{Text: cleanString(note)})
+		}
+	}
+	return line, nil
+}
@@ -0,0 +1,69 @@
+package ubl_test
+
+import (
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDespatchAdvice(t *testing.T) {
+	data, err := testLoadXML("despatch-advice/despatch-advice-peppol.xml")
+	require.NoError(t, err)
+
+	doc, err := ubl.Parse(data)
+	require.NoError(t, err)
+
+	da, ok := doc.(*ubl.DespatchAdvice)
+	require.True(t, ok)
+
+	env, err := da.Convert()
+	require.NoError(t, err)
+
+	dlv, ok := env.Extract().(*bill.Delivery)
+	require.True(t, ok)
+
+	assert.Equal(t, bill.DeliveryTypeAdvice, dlv.Type)
+	assert.Equal(t, cbc.Code("1234"), dlv.Code)
+	assert.Equal(t, "2018-09-20", dlv.IssueDate.String())
+	require.Len(t, dlv.Notes, 1)
+	assert.Equal(t, "Delivery on pallets", dlv.Notes[0].Text)
+
+	assert.Equal(t, "Moderna Produkter AB", dlv.Supplier.Name)
+	assert.Equal(t, "Johnssons Byggvaror AB", dlv.Customer.Name)
+
+	require.NotNil(t, dlv.Ordering)
+	require.Len(t, dlv.Ordering.Purchases, 1)
+	assert.Equal(t, cbc.Code("5678"), dlv.Ordering.Purchases[0].Code)
+	require.NotNil(t, dlv.Ordering.Buyer)
+
+	require.NotNil(t, dlv.Courier)
+	assert.Equal(t, "Fast Freight AB", dlv.Courier.Name)
+	require.NotNil(t, dlv.Tracking)
+	assert.Equal(t, cbc.Code("TRACK-5544"), dlv.Tracking.Code)
+	require.NotNil(t, dlv.DespatchDate)
+	assert.Equal(t, "2018-09-20", dlv.DespatchDate.String())
+	require.NotNil(t, dlv.ReceiveDate)
+	assert.Equal(t, "2018-09-23", dlv.ReceiveDate.String())
+	require.NotNil(t, dlv.Despatcher)
+	assert.Equal(t, "Göteborg", dlv.Despatcher.Addresses[0].Locality)
+	require.NotNil(t, dlv.Receiver)
+	assert.Equal(t, "Stockholm", dlv.Receiver.Addresses[0].Locality)
+
+	require.Len(t, dlv.Lines, 2)
+	assert.Equal(t, num.MakeAmount(90, 0), dlv.Lines[0].Quantity)
+	assert.Equal(t, org.Unit("l"), dlv.Lines[0].Item.Unit)
+	assert.Equal(t, "Falu Rödfärg", dlv.Lines[0].Item.Name)
+	assert.Equal(t, cbc.Code("SItemNo001"), dlv.Lines[0].Item.Ref)
+	assert.Equal(t, cbc.Code("1"), dlv.Lines[0].Order)
+	require.Len(t, dlv.Lines[0].Notes, 1)
+	assert.Nil(t, dlv.Lines[0].Item.Price)
+
+	assert.Equal(t, cbc.Code("2"), dlv.Lines[1].Order)
+	assert.Equal(t, num.MakeAmount(15, 0), dlv.Lines[1].Quantity)
+}
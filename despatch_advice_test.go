@@ -0,0 +1,87 @@
+package ubl_test
+
+import (
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertDespatchAdvice(t *testing.T) {
+	t.Run("delivery-advice.json", func(t *testing.T) {
+		env, err := loadTestEnvelope("delivery-advice.json")
+		require.NoError(t, err)
+
+		doc, err := ubl.ConvertDespatchAdvice(env)
+		require.NoError(t, err)
+
+		assert.Equal(t, ubl.NamespaceUBLDespatchAdvice, doc.UBLNamespace)
+		assert.Equal(t, ubl.NamespaceEXT, doc.EXTNamespace)
+		assert.Equal(t, ubl.ContextPeppolDespatchAdvice.CustomizationID, doc.CustomizationID)
+		assert.Equal(t, ubl.ContextPeppolDespatchAdvice.ProfileID, doc.ProfileID)
+		assert.Equal(t, "DA-2001", doc.ID)
+		assert.Equal(t, "2024-05-18", doc.IssueDate)
+		assert.Equal(t, []string{"Deliver to the rear entrance."}, doc.Note)
+
+		require.Len(t, doc.OrderReference, 1)
+		assert.Equal(t, "PO-1001", doc.OrderReference[0].ID)
+		assert.Equal(t, "2024-05-10", doc.OrderReference[0].IssueDate)
+
+		assert.Equal(t, "Provide One GmbH", *doc.DespatchSupplierParty.Party.PartyLegalEntity.RegistrationName)
+		assert.Equal(t, "Sample Consumer GmbH", *doc.DeliveryCustomerParty.Party.PartyLegalEntity.RegistrationName)
+
+		require.NotNil(t, doc.Shipment)
+		assert.Equal(t, "NA", doc.Shipment.ID)
+		require.Len(t, doc.Shipment.Consignment, 1)
+		assert.Equal(t, "Schnell Logistik GmbH", *doc.Shipment.Consignment[0].CarrierParty.PartyLegalEntity.RegistrationName)
+
+		d := doc.Shipment.Delivery
+		require.NotNil(t, d)
+		assert.Equal(t, "TRK-99887766", *d.TrackingID)
+		assert.Equal(t, "Lieferweg 3", *d.DeliveryLocation.Address.StreetName)
+		assert.Equal(t, "2024-05-21", d.EstimatedDeliveryPeriod.EndDate)
+		require.NotNil(t, d.Despatch)
+		assert.Equal(t, "2024-05-18", *d.Despatch.ActualDespatchDate)
+		assert.Equal(t, "Lagerstraße 4", *d.Despatch.DespatchAddress.StreetName)
+
+		require.Len(t, doc.DespatchLines, 2)
+		line := doc.DespatchLines[0]
+		assert.Equal(t, "1", line.ID)
+		assert.Equal(t, "20", line.DeliveredQuantity.Value)
+		assert.Equal(t, "LTR", line.DeliveredQuantity.UnitCode)
+		assert.Equal(t, "1", line.OrderLineReference[0].LineID)
+		assert.Equal(t, "Paint, red", line.Item.Name)
+		assert.Nil(t, line.Item.ClassifiedTaxCategory)
+
+		line = doc.DespatchLines[1]
+		assert.Equal(t, "NA", line.OrderLineReference[0].LineID)
+		assert.Equal(t, []string{"Packed separately"}, line.Note)
+	})
+
+	t.Run("without shipment details", func(t *testing.T) {
+		env, err := loadTestEnvelope("delivery-advice.json")
+		require.NoError(t, err)
+
+		dlv := env.Extract().(*bill.Delivery)
+		dlv.Tracking = nil
+		dlv.DespatchDate = nil
+		dlv.ReceiveDate = nil
+		dlv.Despatcher = nil
+		dlv.Receiver = nil
+		dlv.Courier = nil
+
+		doc, err := ubl.ConvertDespatchAdvice(env)
+		require.NoError(t, err)
+		assert.Nil(t, doc.Shipment)
+	})
+
+	t.Run("not a delivery", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+
+		_, err = ubl.ConvertDespatchAdvice(env)
+		assert.ErrorContains(t, err, "expected despatch advice")
+	})
+}
@@ -0,0 +1,771 @@
+package ubl
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/invopop/gobl/addons/co/dian"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/head"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/regimes/co"
+	"github.com/invopop/gobl/tax"
+)
+
+// DIAN profile IDs and document type codes.
+const (
+	DIANProfileInvoice    = "DIAN 2.1: Factura Electrónica de Venta"
+	DIANProfileCreditNote = "DIAN 2.1: Nota Crédito de Factura Electrónica de Venta"
+
+	DIANTypeInvoice    = "01"
+	DIANTypeCreditNote = "91"
+)
+
+// DIAN operation types, used as the CustomizationID of generated
+// documents.
+const (
+	dianOperationStandard   = "10"
+	dianOperationCreditNote = "20"
+)
+
+const (
+	namespaceSTS = "dian:gov:co:facturaelectronica:Structures-2-1"
+
+	dianUBLVersion = "UBL 2.1"
+	// dianProfilePrefix is shared by the ProfileID of all DIAN documents.
+	dianProfilePrefix = "DIAN 2.1:"
+	// dianAgencyID identifies DIAN as the agency of identifiers.
+	dianAgencyID = "195"
+	// dianNIT is the tax number of DIAN, which authorizes all documents.
+	dianNIT = "8001972684"
+	// dianSchemeNIT is the identity document type of NIT tax numbers.
+	dianSchemeNIT = "31"
+	// dianTimeZone is the offset of Colombian issue times.
+	dianTimeZone = "-05:00"
+
+	dianQRURL     = "https://catalogo-vpfe.dian.gov.co/document/searchqr?documentkey="
+	dianQRURLTest = "https://catalogo-vpfe-hab.dian.gov.co/document/searchqr?documentkey="
+)
+
+// dianMetaAuthorization is the meta key used for the authorization number
+// of the numbering range of parsed documents.
+const dianMetaAuthorization cbc.Key = "dian-authorization"
+
+// ErrDIANSoftwareRequired is returned when trying to convert an invoice
+// with the DIAN context without the details of the invoicing software.
+var ErrDIANSoftwareRequired = errors.New("dian: documents require a CUFE, use a DIANSoftware as the context hooks")
+
+// dianTaxScheme contains the code and name of a DIAN tax.
+type dianTaxScheme struct {
+	id   string
+	name string
+}
+
+// dianTaxSchemes maps the GOBL tax categories of the Colombian regime to
+// the DIAN tax codes.
+var dianTaxSchemes = map[cbc.Code]dianTaxScheme{
+	tax.CategoryVAT:         {id: "01", name: "IVA"},
+	co.TaxCategoryIC:        {id: "02", name: "IC"},
+	co.TaxCategoryICA:       {id: "03", name: "ICA"},
+	co.TaxCategoryINC:       {id: "04", name: "INC"},
+	co.TaxCategoryReteIVA:   {id: "05", name: "ReteIVA"},
+	co.TaxCategoryReteRenta: {id: "06", name: "ReteRenta"},
+	co.TaxCategoryReteICA:   {id: "07", name: "ReteICA"},
+}
+
+// ContextDIAN defines the context for Colombian DIAN 2.1 electronic
+// invoices and credit notes. DIAN documents use the operation type as
+// their CustomizationID, so the one defined here only identifies the
+// context, and incoming documents are detected from their ProfileID
+// instead.
+//
+// Generated documents require the details of the invoicing software
+// registered with DIAN, so the context's hooks need to be replaced with
+// a DIANSoftware:
+//
+//	ctx := ubl.ContextDIAN
+//	ctx.Hooks = ubl.DIANSoftware{SoftwareID: "...", PIN: "...", ...}
+var ContextDIAN = Context{
+	CustomizationID: "DIAN-2.1",
+	Addons:          []cbc.Key{dian.V2},
+	Output: OutputRules{
+		LineTaxTotals:        true,
+		WithholdingTaxTotals: true,
+	},
+	Hooks: dianHooks{},
+}
+
+// DIANSoftware contains the details of the invoicing software registered
+// with DIAN, used to generate the DIAN extensions and the CUFE of
+// Colombian documents.
+type DIANSoftware struct {
+	dianHooks
+
+	// ProviderID is the NIT of the software provider, including the
+	// check digit.
+	ProviderID string
+	// SoftwareID identifies the software registered with DIAN.
+	SoftwareID string
+	// PIN is the software's PIN, used for the security code and the
+	// CUDE of credit notes.
+	PIN string
+	// TechnicalKey is the technical key of the numbering range, used for
+	// the CUFE of invoices.
+	TechnicalKey string
+	// Authorization is the numbering range authorized by DIAN.
+	Authorization DIANAuthorization
+	// Test issues documents for the DIAN test environment.
+	Test bool
+}
+
+// DIANAuthorization describes a numbering range authorized by DIAN.
+type DIANAuthorization struct {
+	// Number is the number of the authorization resolution.
+	Number string
+	// StartDate is the first day the range may be used.
+	StartDate cal.Date
+	// EndDate is the last day the range may be used.
+	EndDate cal.Date
+	// Prefix is the series of the documents in the range.
+	Prefix string
+	// From is the first authorized document number.
+	From int64
+	// To is the last authorized document number.
+	To int64
+}
+
+// dianHooks applies the DIAN mapping rules, which are shared with the
+// DIANSoftware.
+type dianHooks struct {
+	BaseHooks
+}
+
+// Detect checks for documents that use one of the DIAN profiles.
+func (dianHooks) Detect(in *Invoice) bool {
+	return strings.HasPrefix(in.ProfileID, dianProfilePrefix)
+}
+
+// BeforeConvert ensures the invoice has a document type so that the
+// regular conversion can take place.
+func (dianHooks) BeforeConvert(inv *bill.Invoice) error {
+	setDocumentType(inv)
+	return nil
+}
+
+// AfterConvert fails, as documents cannot be issued without the software
+// details.
+func (dianHooks) AfterConvert(_ *bill.Invoice, _ *Invoice) error {
+	return ErrDIANSoftwareRequired
+}
+
+// BeforeParse maps the DIAN tax codes back into tax schemes, and prepares
+// the parties and tax categories expected by the regular parsing process.
+func (dianHooks) BeforeParse(in *Invoice) error {
+	in.eachTaxCategory(func(_ *IDType, ts *TaxScheme) {
+		parseDIANTaxScheme(ts)
+	})
+	in.addItemTaxCategories()
+	for _, p := range []*Party{in.AccountingSupplierParty.Party, in.AccountingCustomerParty.Party} {
+		if p == nil {
+			continue
+		}
+		if a := p.PostalAddress; a != nil && a.StreetName == nil && len(a.AddressLine) > 0 {
+			a.StreetName = &a.AddressLine[0].Line
+		}
+		if p.PartyLegalEntity != nil {
+			// The same identifier is provided in the party tax scheme.
+			p.PartyLegalEntity.CompanyID = nil
+		}
+	}
+	return nil
+}
+
+// AfterParse maps the DIAN extensions and party identifiers back into the
+// Colombian regime.
+func (dianHooks) AfterParse(in *Invoice, inv *bill.Invoice) error {
+	inv.Regime = tax.WithRegime("CO")
+	switch {
+	case in.typeCode() == DIANTypeCreditNote:
+		inv.Type = bill.InvoiceTypeCreditNote
+	default:
+		inv.Type = bill.InvoiceTypeStandard
+	}
+
+	if in.IssueTime != "" {
+		t, err := time.Parse("15:04:05", strings.TrimSuffix(in.IssueTime, dianTimeZone))
+		if err != nil {
+			return fmt.Errorf("dian: parsing issue time: %w", err)
+		}
+		inv.IssueTime = cal.NewTime(t.Hour(), t.Minute(), t.Second())
+	}
+
+	ext, err := parseDIANExtensions(in)
+	if err != nil {
+		return err
+	}
+	if inv.Meta == nil {
+		inv.Meta = make(cbc.Meta)
+	}
+	if in.UUID != "" {
+		inv.Meta[dian.StampCUDE] = in.UUID
+	}
+	if ext != nil {
+		if p := ext.find("Prefix"); p != nil {
+			if prefix := p.text(); prefix != "" && strings.HasPrefix(in.ID, prefix) {
+				inv.Series = cbc.Code(prefix)
+				inv.Code = cbc.Code(strings.TrimPrefix(in.ID, prefix))
+			}
+		}
+		if a := ext.find("InvoiceAuthorization"); a != nil {
+			inv.Meta[dianMetaAuthorization] = a.text()
+		}
+		if qr := ext.find("QRCode"); qr != nil {
+			inv.Meta[dian.StampQR] = qr.text()
+		}
+	}
+
+	if inv.Supplier != nil {
+		parseDIANParty(in.AccountingSupplierParty.Party, inv.Supplier)
+	}
+	if inv.Customer != nil {
+		parseDIANParty(in.AccountingCustomerParty.Party, inv.Customer)
+	}
+
+	for i, dr := range in.DiscrepancyResponse {
+		if i >= len(inv.Preceding) {
+			break
+		}
+		p := inv.Preceding[i]
+		if dr.ResponseCode != nil && dr.ResponseCode.Value != "" {
+			p.Ext = p.Ext.Merge(tax.Extensions{dian.ExtKeyCreditCode: cbc.Code(dr.ResponseCode.Value)})
+		}
+		if len(dr.Description) > 0 {
+			p.Reason = cleanString(dr.Description[0])
+		}
+	}
+	return nil
+}
+
+// AfterConvert applies the DIAN mapping rules and adds the CUFE and the
+// DIAN extensions to the generated document. The document still needs
+// to be signed, for which an empty extension is included.
+func (s DIANSoftware) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	if err := s.apply(inv, out); err != nil {
+		return err
+	}
+
+	env := "1"
+	if s.Test {
+		env = "2"
+	}
+	key, scheme := s.TechnicalKey, "CUFE-SHA384"
+	if inv.Type.In(bill.InvoiceTypeCreditNote) {
+		// Credit notes use the CUDE, calculated with the software PIN.
+		key, scheme = s.PIN, "CUDE-SHA384"
+	}
+	out.ProfileExecutionID = env
+	out.UUID = dianCUFE(inv, out, key, env)
+	out.UUIDAttributes = &Attributes{SchemeID: &env, SchemeName: &scheme}
+
+	content, err := xml.Marshal(s.extensions(inv, out))
+	if err != nil {
+		return err
+	}
+	out.EXTNamespace = NamespaceEXT
+	out.UBLExtensions = &Extensions{
+		Extension: []Extension{
+			{ExtensionContent: &ExtensionContent{Value: string(content)}},
+		},
+	}
+	return nil
+}
+
+// apply adapts the generated document to the DIAN requirements, before
+// the CUFE is calculated.
+func (dianHooks) apply(inv *bill.Invoice, out *Invoice) error {
+	switch inv.Type {
+	case bill.InvoiceTypeStandard:
+		out.CustomizationID = dianOperationStandard
+		out.ProfileID = DIANProfileInvoice
+		out.InvoiceTypeCode = DIANTypeInvoice
+	case bill.InvoiceTypeCreditNote:
+		out.CustomizationID = dianOperationCreditNote
+		out.ProfileID = DIANProfileCreditNote
+		out.CreditNoteTypeCode = DIANTypeCreditNote
+	default:
+		return fmt.Errorf("dian: unsupported invoice type %q", inv.Type)
+	}
+	if inv.Supplier == nil || dianNITCode(inv.Supplier) == "" {
+		return errors.New("dian: supplier requires a Colombian NIT")
+	}
+
+	out.UBLVersionID = dianUBLVersion
+	// Document numbers include the authorized prefix without a separator.
+	out.ID = dianDocumentNumber(inv.Series, inv.Code)
+	out.IssueTime = "00:00:00" + dianTimeZone
+	if inv.IssueTime != nil {
+		out.IssueTime = inv.IssueTime.String() + dianTimeZone
+	}
+	out.LineCountNumeric = len(out.InvoiceLines) + len(out.CreditNoteLines)
+	if r := out.OrderReference; r != nil && r.ID == notApplicable {
+		out.OrderReference = nil
+	}
+
+	for i, p := range inv.Preceding {
+		if i >= len(out.BillingReference) {
+			break
+		}
+		ref := out.BillingReference[i].InvoiceDocumentReference
+		ref.ID.Value = dianDocumentNumber(p.Series, p.Code)
+		if stamp := dianStamp(p.Stamps, dian.StampCUDE); stamp != "" {
+			ref.UUID = stamp
+		}
+		if inv.Type.In(bill.InvoiceTypeCreditNote) {
+			dr := Response{ReferenceID: ref.ID.Value}
+			if code := p.Ext.Get(dian.ExtKeyCreditCode); code != "" {
+				dr.ResponseCode = &IDType{Value: code.String()}
+			}
+			if p.Reason != "" {
+				dr.Description = []string{p.Reason}
+			}
+			out.DiscrepancyResponse = append(out.DiscrepancyResponse, dr)
+		}
+	}
+
+	applyDIANParty(inv.Supplier, out.AccountingSupplierParty.Party)
+	if inv.Customer != nil {
+		applyDIANParty(inv.Customer, out.AccountingCustomerParty.Party)
+	}
+
+	out.eachTaxCategory(func(_ *IDType, ts *TaxScheme) {
+		applyDIANTaxScheme(ts)
+	})
+	// UNTDID 5305 tax categories are not used in Colombia.
+	out.removeTaxCategoryIDs()
+	// Line taxes are only provided in the line's tax total.
+	out.removeItemTaxCategories()
+
+	// Each tax is expected in its own tax total.
+	out.TaxTotal = splitDIANTaxTotals(out.TaxTotal)
+	out.WithholdingTaxTotal = splitDIANTaxTotals(out.WithholdingTaxTotal)
+	for _, lines := range [][]InvoiceLine{out.InvoiceLines, out.CreditNoteLines} {
+		for i := range lines {
+			lines[i].TaxTotal = splitDIANTaxTotals(lines[i].TaxTotal)
+			lines[i].WithholdingTaxTotal = splitDIANTaxTotals(lines[i].WithholdingTaxTotal)
+		}
+	}
+	return nil
+}
+
+// extensions builds the DIAN extensions for the document.
+func (s DIANSoftware) extensions(inv *bill.Invoice, out *Invoice) dianExtensions {
+	provider, providerDV := splitDIANNIT(s.ProviderID)
+	authority, authorityDV := splitDIANNIT(dianNIT)
+	ext := dianExtensions{
+		STSNamespace: namespaceSTS,
+		InvoiceSource: dianInvoiceSource{
+			IdentificationCode: "CO",
+		},
+		SoftwareProvider: dianSoftwareProvider{
+			ProviderID: dianIdentifier(provider, providerDV, dianSchemeNIT),
+			SoftwareID: dianIdentifier(s.SoftwareID, "", ""),
+		},
+		SoftwareSecurityCode: dianIdentifier(dianHash(s.SoftwareID+s.PIN+out.ID), "", ""),
+		AuthorizationProvider: dianAuthorizationProvider{
+			AuthorizationProviderID: dianIdentifier(authority, authorityDV, dianSchemeNIT),
+		},
+		QRCode: s.qrCode(inv, out),
+	}
+	if inv.Type.In(bill.InvoiceTypeStandard) {
+		a := s.Authorization
+		ext.InvoiceControl = &dianInvoiceControl{
+			InvoiceAuthorization: a.Number,
+			AuthorizationPeriod: dianAuthorizationPeriod{
+				StartDate: a.StartDate.String(),
+				EndDate:   a.EndDate.String(),
+			},
+			AuthorizedInvoices: dianAuthorizedInvoices{
+				Prefix: a.Prefix,
+				From:   a.From,
+				To:     a.To,
+			},
+		}
+	}
+	return ext
+}
+
+// qrCode provides the contents of the QR code to include in the printed
+// representation of the document.
+func (s DIANSoftware) qrCode(inv *bill.Invoice, out *Invoice) string {
+	url := dianQRURL
+	if s.Test {
+		url = dianQRURLTest
+	}
+	others := num.MakeAmount(0, 2)
+	for _, cat := range []cbc.Code{co.TaxCategoryINC, co.TaxCategoryICA, co.TaxCategoryIC} {
+		others = others.Add(dianTaxAmount(inv, cat))
+	}
+	supplier, _ := splitDIANNIT(dianNITCode(inv.Supplier))
+	lines := []string{
+		"NumFac=" + out.ID,
+		"FecFac=" + out.IssueDate,
+		"HorFac=" + out.IssueTime,
+		"NitFac=" + supplier,
+		"DocAdq=" + dianCustomerID(inv.Customer),
+		"ValFac=" + dianAmount(inv.Totals.Sum),
+		"ValIva=" + dianAmount(dianTaxAmount(inv, tax.CategoryVAT)),
+		"ValOtroIm=" + dianAmount(others),
+		"ValTolFac=" + dianAmount(inv.Totals.Payable),
+		"CUFE=" + out.UUID,
+		"QRCode=" + url + out.UUID,
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dianCUFE calculates the unique code of the document from its number,
+// issue date and time, amounts, parties, the provided key and the
+// environment.
+func dianCUFE(inv *bill.Invoice, out *Invoice, key, env string) string {
+	supplier, _ := splitDIANNIT(dianNITCode(inv.Supplier))
+	var sb strings.Builder
+	sb.WriteString(out.ID)
+	sb.WriteString(out.IssueDate)
+	sb.WriteString(out.IssueTime)
+	sb.WriteString(dianAmount(inv.Totals.Sum))
+	for _, cat := range []cbc.Code{tax.CategoryVAT, co.TaxCategoryINC, co.TaxCategoryICA} {
+		sb.WriteString(dianTaxSchemes[cat].id)
+		sb.WriteString(dianAmount(dianTaxAmount(inv, cat)))
+	}
+	sb.WriteString(dianAmount(inv.Totals.Payable))
+	sb.WriteString(supplier)
+	sb.WriteString(dianCustomerID(inv.Customer))
+	sb.WriteString(key)
+	sb.WriteString(env)
+	return dianHash(sb.String())
+}
+
+// dianHash provides the hex encoded SHA-384 digest used by DIAN.
+func dianHash(s string) string {
+	sum := sha512.Sum384([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// dianTaxAmount provides the total amount of the tax category, or zero
+// when not present.
+func dianTaxAmount(inv *bill.Invoice, cat cbc.Code) num.Amount {
+	if inv.Totals == nil || inv.Totals.Taxes == nil {
+		return num.AmountZero
+	}
+	if ct := inv.Totals.Taxes.Category(cat); ct != nil {
+		return ct.Amount
+	}
+	return num.AmountZero
+}
+
+// dianAmount formats amounts with the two decimals expected by DIAN.
+func dianAmount(a num.Amount) string {
+	return a.Rescale(2).String()
+}
+
+func dianDocumentNumber(series, code cbc.Code) string {
+	return series.String() + code.String()
+}
+
+func dianStamp(stamps []*head.Stamp, key cbc.Key) string {
+	for _, s := range stamps {
+		if s.Provider == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// dianNITCode provides the party's Colombian NIT including the check
+// digit, or an empty string when not available.
+func dianNITCode(party *org.Party) string {
+	if party == nil || party.TaxID == nil || party.TaxID.Country != "CO" {
+		return ""
+	}
+	return party.TaxID.Code.String()
+}
+
+// splitDIANNIT separates the NIT's number from its check digit.
+func splitDIANNIT(nit string) (string, string) {
+	if len(nit) < 2 {
+		return nit, ""
+	}
+	return nit[:len(nit)-1], nit[len(nit)-1:]
+}
+
+// dianPartyID provides the party's identification number, check digit
+// and DIAN identity document type, from either the Colombian NIT or the
+// identities defined by the DIAN addon.
+func dianPartyID(party *org.Party) (string, string, string) {
+	if nit := dianNITCode(party); nit != "" {
+		code, dv := splitDIANNIT(nit)
+		return code, dv, dianSchemeNIT
+	}
+	if party == nil {
+		return "", "", ""
+	}
+	ad := tax.AddonForKey(dian.V2)
+	for _, id := range party.Identities {
+		if id.Key == "" || ad == nil {
+			continue
+		}
+		if def := cbc.GetKeyDefinition(id.Key, ad.Identities); def != nil {
+			return id.Code.String(), "", def.Map[dian.KeyCompanyID].String()
+		}
+	}
+	return "", "", ""
+}
+
+// dianCustomerID provides the customer's identification number used in
+// the CUFE and QR code.
+func dianCustomerID(party *org.Party) string {
+	code, _, _ := dianPartyID(party)
+	return code
+}
+
+// dianIdentifier provides an identifier issued by DIAN with the optional
+// check digit and identity document type.
+func dianIdentifier(code, dv, scheme string) IDType {
+	agency := dianAgencyID
+	id := IDType{SchemeAgencyID: &agency, Value: code}
+	if dv != "" {
+		id.SchemeID = &dv
+	}
+	if scheme != "" {
+		id.SchemeName = &scheme
+	}
+	return id
+}
+
+// applyDIANParty replaces the party's tax scheme and legal entity
+// identifiers with the DIAN identification, and adds the fiscal
+// responsibility and municipality from the party's extensions.
+func applyDIANParty(party *org.Party, p *Party) {
+	if p == nil {
+		return
+	}
+	p.PartyTaxScheme = nil
+	if code, dv, scheme := dianPartyID(party); code != "" {
+		id := dianIdentifier(code, dv, scheme)
+		// Parties without a NIT are not responsible for VAT.
+		ts := dianTaxScheme{id: "ZZ", name: "No aplica"}
+		if scheme == dianSchemeNIT {
+			ts = dianTaxSchemes[tax.CategoryVAT]
+		}
+		pts := PartyTaxScheme{
+			CompanyID: &id,
+			TaxScheme: &TaxScheme{ID: IDType{Value: ts.id}, Name: &ts.name},
+		}
+		if party.Name != "" {
+			pts.RegistrationName = &party.Name
+		}
+		if fr := party.Ext.Get(dian.ExtKeyFiscalResponsibility).String(); fr != "" {
+			pts.TaxLevelCode = &fr
+		}
+		p.PartyTaxScheme = []PartyTaxScheme{pts}
+		if p.PartyLegalEntity != nil {
+			legal := id
+			p.PartyLegalEntity.CompanyID = &legal
+		}
+	} else if p.PartyLegalEntity != nil {
+		p.PartyLegalEntity.CompanyID = nil
+	}
+
+	if a := p.PostalAddress; a != nil {
+		if m := party.Ext.Get(dian.ExtKeyMunicipality).String(); len(m) >= 2 {
+			// The department is identified by the first two digits.
+			dept := m[:2]
+			a.ID = &m
+			a.CountrySubentityCode = &dept
+		}
+		if a.StreetName != nil && len(a.AddressLine) == 0 {
+			a.AddressLine = []AddressLine{{Line: *a.StreetName}}
+			a.StreetName = nil
+		}
+	}
+}
+
+// parseDIANParty maps the DIAN identification, fiscal responsibility and
+// municipality back into the GOBL party.
+func parseDIANParty(p *Party, party *org.Party) {
+	if p == nil {
+		return
+	}
+	for _, pts := range p.PartyTaxScheme {
+		if pts.CompanyID == nil || pts.CompanyID.Value == "" {
+			continue
+		}
+		id := pts.CompanyID
+		var scheme string
+		if id.SchemeName != nil {
+			scheme = *id.SchemeName
+		}
+		switch {
+		case scheme == dianSchemeNIT:
+			code := id.Value
+			if id.SchemeID != nil {
+				code += *id.SchemeID
+			}
+			party.TaxID = &tax.Identity{Country: "CO", Code: cbc.Code(code)}
+		case scheme != "":
+			party.TaxID = nil
+			if key := dianIdentityKey(scheme); key != "" {
+				party.Identities = append(party.Identities, &org.Identity{Key: key, Code: cbc.Code(id.Value)})
+			}
+		}
+		if pts.TaxLevelCode != nil && *pts.TaxLevelCode != "" {
+			party.Ext = party.Ext.Merge(tax.Extensions{dian.ExtKeyFiscalResponsibility: cbc.Code(*pts.TaxLevelCode)})
+		}
+		break
+	}
+	if a := p.PostalAddress; a != nil && a.ID != nil && *a.ID != "" {
+		party.Ext = party.Ext.Merge(tax.Extensions{dian.ExtKeyMunicipality: cbc.Code(*a.ID)})
+	}
+}
+
+// dianIdentityKey provides the DIAN addon identity key for the identity
+// document type.
+func dianIdentityKey(scheme string) cbc.Key {
+	ad := tax.AddonForKey(dian.V2)
+	if ad == nil {
+		return ""
+	}
+	for _, def := range ad.Identities {
+		if def.Map[dian.KeyCompanyID].String() == scheme {
+			return def.Key
+		}
+	}
+	return ""
+}
+
+// applyDIANTaxScheme replaces the tax scheme's category with the DIAN tax
+// code and name.
+func applyDIANTaxScheme(ts *TaxScheme) {
+	if ts == nil {
+		return
+	}
+	dts, ok := dianTaxSchemes[cbc.Code(ts.ID.Value)]
+	if !ok {
+		return
+	}
+	name := dts.name
+	ts.ID.Value = dts.id
+	ts.Name = &name
+}
+
+// parseDIANTaxScheme sets the tax scheme's category from its DIAN tax
+// code.
+func parseDIANTaxScheme(ts *TaxScheme) {
+	if ts == nil {
+		return
+	}
+	for cat, dts := range dianTaxSchemes {
+		if dts.id == ts.ID.Value {
+			ts.ID.Value = cat.String()
+			ts.Name = nil
+			return
+		}
+	}
+}
+
+// splitDIANTaxTotals provides a tax total for each of the tax schemes
+// included in the subtotals.
+func splitDIANTaxTotals(totals []TaxTotal) []TaxTotal {
+	var out []TaxTotal
+	idx := make(map[string]int)
+	for _, tt := range totals {
+		for _, st := range tt.TaxSubtotal {
+			var scheme string
+			if ts := st.TaxCategory.TaxScheme; ts != nil {
+				scheme = ts.ID.Value
+			}
+			i, ok := idx[scheme]
+			if !ok {
+				idx[scheme] = len(out)
+				out = append(out, TaxTotal{TaxAmount: st.TaxAmount, TaxSubtotal: []TaxSubtotal{st}})
+				continue
+			}
+			out[i].TaxSubtotal = append(out[i].TaxSubtotal, st)
+			sum, err := num.AmountFromString(out[i].TaxAmount.Value)
+			if err != nil {
+				continue
+			}
+			if a, err := num.AmountFromString(st.TaxAmount.Value); err == nil {
+				out[i].TaxAmount.Value = sum.Add(a).String()
+			}
+		}
+	}
+	return out
+}
+
+// parseDIANExtensions provides the DIAN extensions of the document, or nil
+// if there are none.
+func parseDIANExtensions(in *Invoice) (*xmlNode, error) {
+	if in.UBLExtensions == nil {
+		return nil, nil
+	}
+	for _, e := range in.UBLExtensions.Extension {
+		if e.ExtensionContent == nil || !strings.Contains(e.ExtensionContent.Value, "DianExtensions") {
+			continue
+		}
+		n, err := parseXMLTree([]byte(e.ExtensionContent.Value))
+		if err != nil {
+			return nil, fmt.Errorf("dian: parsing extensions: %w", err)
+		}
+		return n.find("DianExtensions"), nil
+	}
+	return nil, nil
+}
+
+type dianExtensions struct {
+	XMLName               xml.Name                  `xml:"sts:DianExtensions"`
+	STSNamespace          string                    `xml:"xmlns:sts,attr"`
+	InvoiceControl        *dianInvoiceControl       `xml:"sts:InvoiceControl,omitempty"`
+	InvoiceSource         dianInvoiceSource         `xml:"sts:InvoiceSource"`
+	SoftwareProvider      dianSoftwareProvider      `xml:"sts:SoftwareProvider"`
+	SoftwareSecurityCode  IDType                    `xml:"sts:SoftwareSecurityCode"`
+	AuthorizationProvider dianAuthorizationProvider `xml:"sts:AuthorizationProvider"`
+	QRCode                string                    `xml:"sts:QRCode"`
+}
+
+type dianInvoiceControl struct {
+	InvoiceAuthorization string                  `xml:"sts:InvoiceAuthorization"`
+	AuthorizationPeriod  dianAuthorizationPeriod `xml:"sts:AuthorizationPeriod"`
+	AuthorizedInvoices   dianAuthorizedInvoices  `xml:"sts:AuthorizedInvoices"`
+}
+
+type dianAuthorizationPeriod struct {
+	StartDate string `xml:"cbc:StartDate"`
+	EndDate   string `xml:"cbc:EndDate"`
+}
+
+type dianAuthorizedInvoices struct {
+	Prefix string `xml:"sts:Prefix,omitempty"`
+	From   int64  `xml:"sts:From"`
+	To     int64  `xml:"sts:To"`
+}
+
+type dianInvoiceSource struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+}
+
+type dianSoftwareProvider struct {
+	ProviderID IDType `xml:"sts:ProviderID"`
+	SoftwareID IDType `xml:"sts:SoftwareID"`
+}
+
+type dianAuthorizationProvider struct {
+	AuthorizationProviderID IDType `xml:"sts:AuthorizationProviderID"`
+}
@@ -0,0 +1,235 @@
+package ubl_test
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/invopop/gobl"
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/addons/co/dian"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDIANContext() ubl.Context {
+	ctx := ubl.ContextDIAN
+	ctx.Hooks = ubl.DIANSoftware{
+		ProviderID:   "9014514812",
+		SoftwareID:   "56f2ae4e-9812-4fad-9255-08fcfcd5ccb0",
+		PIN:          "12345",
+		TechnicalKey: "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c",
+		Authorization: ubl.DIANAuthorization{
+			Number:    "18760000001",
+			StartDate: cal.MakeDate(2024, 1, 1),
+			EndDate:   cal.MakeDate(2030, 12, 31),
+			Prefix:    "SETT",
+			From:      1,
+			To:        5000000,
+		},
+		Test: true,
+	}
+	return ctx
+}
+
+func testDIANEnvelope(t *testing.T) (*gobl.Envelope, *bill.Invoice) {
+	t.Helper()
+	env, err := loadTestEnvelopeFromPath(filepath.Join(getConvertPath(), "dian", "invoice-co.json"))
+	require.NoError(t, err)
+	inv, ok := env.Extract().(*bill.Invoice)
+	require.True(t, ok)
+	return env, inv
+}
+
+func TestContextDIAN(t *testing.T) {
+	examples, err := filepath.Glob(filepath.Join(getConvertPath(), "dian", jsonPattern))
+	require.NoError(t, err)
+	require.NotEmpty(t, examples)
+
+	for _, example := range examples {
+		inName := filepath.Base(example)
+		outName := strings.Replace(inName, ".json", ".xml", 1)
+
+		t.Run(inName, func(t *testing.T) {
+			doc, err := testInvoiceFromContext(filepath.Join("dian", inName), testDIANContext())
+			require.NoError(t, err)
+			data, err := ubl.Bytes(doc)
+			require.NoError(t, err)
+
+			assert.NoError(t, ubl.ValidateSchema(data), "Output should be valid against the UBL schema")
+
+			outPath := filepath.Join(getConvertPath(), "dian", "out", outName)
+			if *updateOut {
+				require.NoError(t, os.WriteFile(outPath, data, 0644))
+			}
+			output, err := os.ReadFile(outPath)
+			assert.NoError(t, err)
+			assert.Equal(t, string(output), string(data), "Output should match the expected XML. Update with --update flag.")
+		})
+	}
+
+	t.Run("cufe", func(t *testing.T) {
+		env, _ := testDIANEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+
+		src := "SETT1234" + "2024-07-24" + "10:15:00-05:00" + "300000.00" +
+			"01" + "57000.00" + "04" + "0.00" + "03" + "0.00" + "357000.00" +
+			"901451481" + "901451480" + "fc8eac422eba16e22ffd8c6f94b3f40a6e38162c" + "2"
+		sum := sha512.Sum384([]byte(src))
+		assert.Equal(t, hex.EncodeToString(sum[:]), doc.UUID)
+		assert.Equal(t, "2", doc.ProfileExecutionID)
+
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `<cbc:UUID schemeID="2" schemeName="CUFE-SHA384">`+doc.UUID+`</cbc:UUID>`)
+		assert.Contains(t, string(data), "<sts:QRCode>NumFac=SETT1234&#xA;")
+		assert.Contains(t, string(data), "CUFE="+doc.UUID+"&#xA;")
+	})
+
+	t.Run("header", func(t *testing.T) {
+		env, _ := testDIANEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+		assert.Equal(t, "UBL 2.1", doc.UBLVersionID)
+		assert.Equal(t, "10", doc.CustomizationID)
+		assert.Equal(t, ubl.DIANProfileInvoice, doc.ProfileID)
+		assert.Equal(t, ubl.DIANTypeInvoice, doc.InvoiceTypeCode)
+		assert.Equal(t, "SETT1234", doc.ID)
+		assert.Equal(t, "10:15:00-05:00", doc.IssueTime)
+		assert.Equal(t, 2, doc.LineCountNumeric)
+	})
+
+	t.Run("parties", func(t *testing.T) {
+		env, _ := testDIANEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+		p := doc.AccountingSupplierParty.Party
+		require.Len(t, p.PartyTaxScheme, 1)
+		pts := p.PartyTaxScheme[0]
+		assert.Equal(t, "901451481", pts.CompanyID.Value)
+		assert.Equal(t, "2", *pts.CompanyID.SchemeID)
+		assert.Equal(t, "31", *pts.CompanyID.SchemeName)
+		assert.Equal(t, "O-23", *pts.TaxLevelCode)
+		assert.Equal(t, "01", pts.TaxScheme.ID.Value)
+		assert.Equal(t, "11001", *p.PostalAddress.ID)
+		assert.Equal(t, "11", *p.PostalAddress.CountrySubentityCode)
+	})
+
+	t.Run("customer identity", func(t *testing.T) {
+		env, inv := testDIANEnvelope(t)
+		inv.Customer.TaxID = nil
+		inv.Customer.Identities = []*org.Identity{{Key: dian.IdentityKeyCitizenID, Code: "1032456789"}}
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+		pts := doc.AccountingCustomerParty.Party.PartyTaxScheme[0]
+		assert.Equal(t, "1032456789", pts.CompanyID.Value)
+		assert.Nil(t, pts.CompanyID.SchemeID)
+		assert.Equal(t, "13", *pts.CompanyID.SchemeName)
+		assert.Equal(t, "ZZ", pts.TaxScheme.ID.Value)
+	})
+
+	t.Run("withholding", func(t *testing.T) {
+		env, inv := testDIANEnvelope(t)
+		for _, l := range inv.Lines {
+			p := num.MakePercentage(15, 3)
+			l.Taxes = append(l.Taxes, &tax.Combo{Category: "RR", Percent: &p})
+		}
+		require.NoError(t, inv.Calculate())
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+		require.Len(t, doc.TaxTotal, 1)
+		require.Len(t, doc.WithholdingTaxTotal, 1)
+		wt := doc.WithholdingTaxTotal[0]
+		assert.Equal(t, "4500.00", wt.TaxAmount.Value)
+		require.Len(t, wt.TaxSubtotal, 1)
+		assert.Equal(t, "06", wt.TaxSubtotal[0].TaxCategory.TaxScheme.ID.Value)
+		assert.Equal(t, "ReteRenta", *wt.TaxSubtotal[0].TaxCategory.TaxScheme.Name)
+	})
+
+	t.Run("credit note", func(t *testing.T) {
+		env, inv := testDIANEnvelope(t)
+		inv.Type = bill.InvoiceTypeCreditNote
+		inv.Preceding = []*org.DocumentRef{{
+			Series:    "SETT",
+			Code:      "1233",
+			IssueDate: cal.NewDate(2024, 7, 1),
+			Reason:    "Devolución parcial",
+			Ext:       tax.Extensions{dian.ExtKeyCreditCode: "1"},
+		}}
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+		assert.Equal(t, "CreditNote", doc.XMLName.Local)
+		assert.Equal(t, "20", doc.CustomizationID)
+		assert.Equal(t, ubl.DIANTypeCreditNote, doc.CreditNoteTypeCode)
+		require.Len(t, doc.DiscrepancyResponse, 1)
+		dr := doc.DiscrepancyResponse[0]
+		assert.Equal(t, "SETT1233", dr.ReferenceID)
+		assert.Equal(t, "1", dr.ResponseCode.Value)
+		assert.Equal(t, []string{"Devolución parcial"}, dr.Description)
+		assert.Equal(t, "SETT1233", doc.BillingReference[0].InvoiceDocumentReference.ID.Value)
+
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `schemeName="CUDE-SHA384"`)
+		assert.NotContains(t, string(data), "<sts:InvoiceControl>")
+	})
+
+	t.Run("without software", func(t *testing.T) {
+		env, _ := testDIANEnvelope(t)
+		_, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextDIAN))
+		assert.ErrorIs(t, err, ubl.ErrDIANSoftwareRequired)
+	})
+
+	t.Run("missing supplier NIT", func(t *testing.T) {
+		env, inv := testDIANEnvelope(t)
+		inv.Supplier.TaxID = nil
+		_, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		assert.ErrorContains(t, err, "dian: supplier requires a Colombian NIT")
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		env, _ := testDIANEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(testDIANContext()))
+		require.NoError(t, err)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		require.NotNil(t, in.(*ubl.Invoice).UUIDAttributes)
+		assert.Equal(t, "CUFE-SHA384", *in.(*ubl.Invoice).UUIDAttributes.SchemeName)
+		again, err := ubl.Bytes(in)
+		require.NoError(t, err)
+		assert.Contains(t, string(again), `<cbc:UUID schemeID="2" schemeName="CUFE-SHA384">`+doc.UUID+`</cbc:UUID>`)
+
+		out, err := in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		inv, ok := out.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		assert.Equal(t, cbc.Code("SETT"), inv.Series)
+		assert.Equal(t, cbc.Code("1234"), inv.Code)
+		assert.Equal(t, doc.UUID, inv.Meta[dian.StampCUDE])
+		assert.Contains(t, inv.Meta[dian.StampQR], "NumFac=SETT1234")
+		assert.Equal(t, "9014514812", inv.Supplier.TaxID.Code.String())
+		assert.Equal(t, cbc.Code("O-23"), inv.Supplier.Ext.Get(dian.ExtKeyFiscalResponsibility))
+		assert.Equal(t, cbc.Code("11001"), inv.Supplier.Ext.Get(dian.ExtKeyMunicipality))
+		assert.Equal(t, tax.CategoryVAT, inv.Lines[0].Taxes[0].Category)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("co-dian")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextDIAN))
+	})
+}
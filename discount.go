@@ -0,0 +1,165 @@
+package ubl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/num"
+)
+
+// unitCodeDay is the UN/ECE Recommendation 20 unit code for days.
+const unitCodeDay = "DAY"
+
+// skontoRegex matches the early payment discounts defined with the XRechnung
+// syntax in payment terms notes, such as "#SKONTO#TAGE=14#PROZENT=2.00#",
+// with an optional base amount.
+var skontoRegex = regexp.MustCompile(`#SKONTO#TAGE=(\d+)#PROZENT=(\d+(?:\.\d+)?)#(?:BASISBETRAG=(-?\d+(?:\.\d+)?)#)?`)
+
+// discountTerm represents an early payment discount, or "Skonto", that
+// applies when the invoice is paid within a number of days.
+type discountTerm struct {
+	days    int
+	percent num.Percentage
+	base    *num.Amount
+}
+
+// String provides the discount term in the XRechnung syntax, which
+// requires percentages and amounts with two decimals.
+func (d *discountTerm) String() string {
+	s := fmt.Sprintf("#SKONTO#TAGE=%d#PROZENT=%s#", d.days, d.percent.Rescale(4).StringWithoutSymbol())
+	if d.base != nil {
+		s += "BASISBETRAG=" + d.base.Rescale(2).String() + "#"
+	}
+	return s
+}
+
+// equals returns true when both discounts apply the same percentage
+// within the same number of days.
+func (d *discountTerm) equals(d2 *discountTerm) bool {
+	return d.days == d2.days && d.percent.Equals(d2.percent)
+}
+
+// parseDiscountTerms extracts the discount terms from payment terms notes,
+// returning them together with the rest of the text.
+func parseDiscountTerms(notes string) ([]*discountTerm, string) {
+	var terms []*discountTerm
+	for _, m := range skontoRegex.FindAllStringSubmatch(notes, -1) {
+		days, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		percent, err := num.PercentageFromString(m[2] + "%")
+		if err != nil {
+			continue
+		}
+		d := &discountTerm{days: days, percent: percent}
+		if m[3] != "" {
+			if base, err := num.AmountFromString(m[3]); err == nil {
+				d.base = &base
+			}
+		}
+		terms = append(terms, d)
+	}
+	return terms, strings.TrimSpace(skontoRegex.ReplaceAllString(notes, ""))
+}
+
+// formatDiscountNotes provides a payment terms note with each of the
+// discount terms on its own line, as XRechnung requires a line break
+// after each one, followed by the rest of the text.
+func formatDiscountNotes(text string, terms []*discountTerm) string {
+	if len(terms) == 0 {
+		return text
+	}
+	var b strings.Builder
+	for _, d := range terms {
+		b.WriteString(d.String())
+		b.WriteString("\n")
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// mergeDiscountNotes adds the discount terms missing from the notes to
+// them, leaving the notes untouched when all are already present.
+func mergeDiscountNotes(notes string, discounts []*discountTerm) string {
+	terms, text := parseDiscountTerms(notes)
+	added := false
+	for _, d := range discounts {
+		found := false
+		for _, t := range terms {
+			if t.equals(d) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			terms = append(terms, d)
+			added = true
+		}
+	}
+	if !added {
+		return notes
+	}
+	return formatDiscountNotes(text, terms)
+}
+
+// addSettlementDiscounts adds payment terms with the settlement discount
+// percent and period of each of the discounts in the payment terms notes.
+func (ui *Invoice) addSettlementDiscounts(inv *bill.Invoice) {
+	if inv.Payment == nil || inv.Payment.Terms == nil {
+		return
+	}
+	terms, _ := parseDiscountTerms(inv.Payment.Terms.Notes)
+	for _, d := range terms {
+		p := d.percent.StringWithoutSymbol()
+		ui.PaymentTerms = append(ui.PaymentTerms, PaymentTerms{
+			SettlementDiscountPercent: &p,
+			SettlementPeriod: &SettlementPeriod{
+				DurationMeasure: &Measure{UnitCode: unitCodeDay, Value: strconv.Itoa(d.days)},
+			},
+		})
+	}
+}
+
+// goblDiscountTerm provides the discount of payment terms with a settlement
+// discount percent. Days are taken from the duration of the settlement
+// period, or from its end date counting from the start or issue date.
+func (pt *PaymentTerms) goblDiscountTerm(issueDate string) (*discountTerm, error) {
+	if pt.SettlementDiscountPercent == nil || pt.SettlementPeriod == nil {
+		return nil, nil
+	}
+	percent, err := num.PercentageFromString(normalizeNumericString(*pt.SettlementDiscountPercent) + "%")
+	if err != nil {
+		return nil, err
+	}
+	d := &discountTerm{percent: percent}
+	sp := pt.SettlementPeriod
+	switch {
+	case sp.DurationMeasure != nil && (sp.DurationMeasure.UnitCode == unitCodeDay || sp.DurationMeasure.UnitCode == ""):
+		days, err := num.AmountFromString(normalizeNumericString(sp.DurationMeasure.Value))
+		if err != nil {
+			return nil, err
+		}
+		d.days = int(days.Rescale(0).Value())
+	case sp.EndDate != nil:
+		start := issueDate
+		if sp.StartDate != nil {
+			start = *sp.StartDate
+		}
+		from, err := parseDate(start)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseDate(*sp.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		d.days = int(to.Time().Sub(from.Time()).Hours() / 24)
+	default:
+		return nil, nil
+	}
+	return d, nil
+}
@@ -0,0 +1,127 @@
+package ubl_test
+
+import (
+	"testing"
+
+	"github.com/invopop/gobl"
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/pay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscountTerms(t *testing.T) {
+	settlement := ubl.ContextEN16931
+	settlement.Output.SettlementDiscounts = true
+
+	t.Run("skonto note", func(t *testing.T) {
+		env := testSkontoEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env)
+		require.NoError(t, err)
+
+		require.Len(t, doc.PaymentTerms, 1)
+		assert.Equal(t, []string{"#SKONTO#TAGE=14#PROZENT=2.00#\nZahlbar innerhalb von 30 Tagen."}, doc.PaymentTerms[0].Note)
+		assert.Nil(t, doc.PaymentTerms[0].SettlementDiscountPercent)
+		assert.Nil(t, doc.PaymentTerms[0].SettlementPeriod)
+	})
+
+	t.Run("skonto note with due dates", func(t *testing.T) {
+		env := testSkontoEnvelope(t)
+		inv := env.Extract().(*bill.Invoice)
+		d1 := cal.MakeDate(2024, 6, 1)
+		d2 := cal.MakeDate(2024, 7, 1)
+		inv.Payment.Terms.DueDates = []*pay.DueDate{
+			{Date: &d1, Amount: num.MakeAmount(100000, 2)},
+			{Date: &d2, Amount: num.MakeAmount(100000, 2)},
+		}
+		doc, err := ubl.ConvertInvoice(env)
+		require.NoError(t, err)
+
+		require.Len(t, doc.PaymentTerms, 3)
+		assert.Equal(t, []string{"#SKONTO#TAGE=14#PROZENT=2.00#\nZahlbar innerhalb von 30 Tagen."}, doc.PaymentTerms[2].Note)
+	})
+
+	t.Run("settlement discounts", func(t *testing.T) {
+		env := testSkontoEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(settlement))
+		require.NoError(t, err)
+
+		require.Len(t, doc.PaymentTerms, 2)
+		pt := doc.PaymentTerms[1]
+		assert.Nil(t, pt.Note)
+		require.NotNil(t, pt.SettlementDiscountPercent)
+		assert.Equal(t, "2", *pt.SettlementDiscountPercent)
+		require.NotNil(t, pt.SettlementPeriod)
+		assert.Equal(t, "DAY", pt.SettlementPeriod.DurationMeasure.UnitCode)
+		assert.Equal(t, "14", pt.SettlementPeriod.DurationMeasure.Value)
+
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "<cbc:SettlementDiscountPercent>2</cbc:SettlementDiscountPercent>")
+		assert.Contains(t, string(data), `<cbc:DurationMeasure unitCode="DAY">14</cbc:DurationMeasure>`)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		env := testSkontoEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(settlement))
+		require.NoError(t, err)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+
+		parsed, err := ubl.Parse(data)
+		require.NoError(t, err)
+		out, err := parsed.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+
+		inv := out.Extract().(*bill.Invoice)
+		require.NotNil(t, inv.Payment.Terms)
+		assert.Equal(t, "#SKONTO#TAGE=14#PROZENT=2.00#\nZahlbar innerhalb von 30 Tagen.", inv.Payment.Terms.Notes)
+	})
+
+	t.Run("parse settlement discount percent", func(t *testing.T) {
+		doc, err := ubl.ConvertInvoice(testSkontoEnvelope(t), ubl.WithContext(settlement))
+		require.NoError(t, err)
+		doc.PaymentTerms[0].Note = []string{"Zahlbar innerhalb von 30 Tagen."}
+
+		out, err := doc.Convert()
+		require.NoError(t, err)
+
+		inv := out.Extract().(*bill.Invoice)
+		assert.Equal(t, "#SKONTO#TAGE=14#PROZENT=2.00#\nZahlbar innerhalb von 30 Tagen.", inv.Payment.Terms.Notes)
+	})
+
+	t.Run("parse settlement period end date", func(t *testing.T) {
+		doc, err := ubl.ConvertInvoice(testSkontoEnvelope(t))
+		require.NoError(t, err)
+		p := "3.00"
+		end := "2024-02-23"
+		doc.IssueDate = "2024-02-13"
+		doc.PaymentTerms = []ubl.PaymentTerms{
+			{
+				SettlementDiscountPercent: &p,
+				SettlementPeriod:          &ubl.SettlementPeriod{EndDate: &end},
+			},
+		}
+
+		out, err := doc.Convert()
+		require.NoError(t, err)
+
+		inv := out.Extract().(*bill.Invoice)
+		assert.Equal(t, "#SKONTO#TAGE=10#PROZENT=3.00#", inv.Payment.Terms.Notes)
+	})
+}
+
+// testSkontoEnvelope provides an invoice with an early payment discount
+// in the XRechnung syntax in its payment terms notes.
+func testSkontoEnvelope(t *testing.T) *gobl.Envelope {
+	t.Helper()
+	env, err := loadTestEnvelope("invoice-minimal.json")
+	require.NoError(t, err)
+	inv, ok := env.Extract().(*bill.Invoice)
+	require.True(t, ok)
+	inv.Payment.Terms.Notes = "Zahlbar innerhalb von 30 Tagen.\n#SKONTO#TAGE=14#PROZENT=2#\n"
+	return env
+}
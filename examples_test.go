@@ -61,12 +61,27 @@ func TestConvertToInvoice(t *testing.T) {
 	}{
 		{"EN16931", ubl.ContextEN16931, "en16931"},
 		{"Peppol", ubl.ContextPeppol, "peppol"},
+		{"PeppolNO", ubl.ContextPeppolNO, "peppol-no"},
+		{"PeppolSE", ubl.ContextPeppolSE, "peppol-se"},
+		{"PeppolBE", ubl.ContextPeppolBE, "peppol-be"},
 		{"PeppolSelfBilled", ubl.ContextPeppolSelfBilled, "peppol-self-billed"},
 		{"XRechnung", ubl.ContextXRechnung, "xrechnung"},
+		{"XRechnungExtension", ubl.ContextXRechnungExtension, "xrechnung-extension"},
 		{"FranceCIUS", ubl.ContextPeppolFranceCIUS, "france-cius"},
 		{"FranceExtended", ubl.ContextPeppolFranceExtended, "france-extended"},
 		{"OIOUBL", ubl.ContextOIOUBL, "oioubl"},
 		{"OIOUBL21", ubl.ContextOIOUBL21, "oioubl21"},
+		{"NLCIUS", ubl.ContextNLCIUS, "nlcius"},
+		{"NLCIUSExtended", ubl.ContextNLCIUSExtended, "nlcius-extended"},
+		{"ROCIUS", ubl.ContextROCIUS, "ro-cius"},
+		{"HRCIUS", ubl.ContextHRCIUS, "hr-cius"},
+		{"MyInvois", ubl.ContextMyInvois, "myinvois"},
+		{"UBLTR", ubl.ContextUBLTR, "ubl-tr"},
+		{"SUNAT", ubl.ContextSUNAT, "sunat"},
+		{"PINTAUNZ", ubl.ContextPINTAUNZ, "pint-aunz"},
+		{"PINTSG", ubl.ContextPINTSG, "pint-sg"},
+		{"PINTJP", ubl.ContextPINTJP, "pint-jp"},
+		{"PINTAE", ubl.ContextPINTAE, "pint-ae"},
 	}
 
 	for _, ctx := range contexts {
@@ -88,6 +103,7 @@ func TestConvertToInvoice(t *testing.T) {
 
 					data, err := ubl.Bytes(doc)
 					require.NoError(t, err)
+					assert.NoError(t, ubl.ValidateSchema(data), "Output should be valid against the UBL schema")
 
 					outPath := filepath.Join(getConvertPath(), ctx.dir, "out", outName)
 					if *updateOut {
@@ -125,18 +141,38 @@ func TestConvertToInvoice(t *testing.T) {
 
 func TestParseInvoice(t *testing.T) {
 	// Define contexts to test
+	// National Peppol variants share the Peppol identifiers, so they
+	// cannot be detected and are parsed with their context explicitly.
 	contexts := []struct {
 		name string
 		dir  string
+		opts []ubl.Option
 	}{
-		{"EN16931", "en16931"},
-		{"Peppol", "peppol"},
-		{"PeppolSelfBilled", "peppol-self-billed"},
-		{"XRechnung", "xrechnung"},
-		{"FranceCIUS", "france-cius"},
-		{"FranceExtended", "france-extended"},
-		{"OIOUBL", "oioubl"},
-		{"OIOUBL21", "oioubl21"},
+		{"EN16931", "en16931", nil},
+		{"Peppol", "peppol", nil},
+		{"PeppolNO", "peppol-no", []ubl.Option{ubl.WithContext(ubl.ContextPeppolNO)}},
+		{"PeppolSE", "peppol-se", []ubl.Option{ubl.WithContext(ubl.ContextPeppolSE)}},
+		{"PeppolBE", "peppol-be", []ubl.Option{ubl.WithContext(ubl.ContextPeppolBE)}},
+		{"PeppolSelfBilled", "peppol-self-billed", nil},
+		{"XRechnung", "xrechnung", nil},
+		{"XRechnungExtension", "xrechnung-extension", nil},
+		{"FranceCIUS", "france-cius", nil},
+		{"FranceExtended", "france-extended", nil},
+		{"OIOUBL", "oioubl", nil},
+		{"OIOUBL21", "oioubl21", nil},
+		{"NLCIUS", "nlcius", nil},
+		{"NLCIUSExtended", "nlcius-extended", nil},
+		{"ROCIUS", "ro-cius", nil},
+		{"HRCIUS", "hr-cius", nil},
+		{"MyInvois", "myinvois", nil},
+		{"ZATCA", "zatca", nil},
+		{"UBLTR", "ubl-tr", nil},
+		{"DIAN", "dian", nil},
+		{"SUNAT", "sunat", nil},
+		{"PINTAUNZ", "pint-aunz", nil},
+		{"PINTSG", "pint-sg", nil},
+		{"PINTJP", "pint-jp", nil},
+		{"PINTAE", "pint-ae", nil},
 	}
 
 	for _, ctx := range contexts {
@@ -162,7 +198,7 @@ func TestParseInvoice(t *testing.T) {
 					require.NoError(t, err)
 					inv, ok := doc.(*ubl.Invoice)
 					require.True(t, ok, "Document should be an invoice")
-					env, err := inv.Convert()
+					env, err := inv.Convert(ctx.opts...)
 					require.NoError(t, err)
 
 					// Unfortunately, the sample UBL documents have lots of errors, including
@@ -0,0 +1,404 @@
+package ubl
+
+import (
+	"strings"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/catalogues/untdid"
+	"github.com/invopop/gobl/cbc"
+)
+
+// Hooks allow a context to adapt invoices to its own requirements during
+// conversion and parsing, so that new jurisdictions can be supported
+// without changing the core mapping process.
+type Hooks interface {
+	// BeforeConvert is called with the GOBL invoice before it is
+	// converted into UBL.
+	BeforeConvert(inv *bill.Invoice) error
+	// AfterConvert is called with the UBL invoice generated from the
+	// GOBL invoice.
+	AfterConvert(inv *bill.Invoice, out *Invoice) error
+	// BeforeParse is called with the UBL invoice before it is parsed
+	// into GOBL.
+	BeforeParse(in *Invoice) error
+	// AfterParse is called with the GOBL invoice parsed from the UBL
+	// invoice.
+	AfterParse(in *Invoice, inv *bill.Invoice) error
+}
+
+// BaseHooks provides empty implementations of all the hook methods, and
+// may be embedded in hooks that only need to override some of them.
+type BaseHooks struct{}
+
+// BeforeConvert does nothing.
+func (BaseHooks) BeforeConvert(_ *bill.Invoice) error { return nil }
+
+// AfterConvert does nothing.
+func (BaseHooks) AfterConvert(_ *bill.Invoice, _ *Invoice) error { return nil }
+
+// BeforeParse does nothing.
+func (BaseHooks) BeforeParse(_ *Invoice) error { return nil }
+
+// AfterParse does nothing.
+func (BaseHooks) AfterParse(_ *Invoice, _ *bill.Invoice) error { return nil }
+
+// Detector may be implemented by hooks to recognise incoming documents
+// that cannot be matched to their context using the CustomizationID and
+// ProfileID.
+type Detector interface {
+	// Detect reports whether the document belongs to the context.
+	Detect(in *Invoice) bool
+}
+
+// detectContext looks for a registered context whose hooks recognise the
+// document. When the document was already matched to a context by its
+// identifiers, only the contexts that share them are considered, so that
+// national variants such as EHF can be told apart from the generic one.
+// The matched context, which may be nil, is returned if none recognises
+// the document.
+func detectContext(in *Invoice, found *Context) *Context {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, ctx := range registry.contexts {
+		if found != nil && (ctx.CustomizationID != found.CustomizationID || ctx.ProfileID != found.ProfileID) {
+			continue
+		}
+		if d, ok := ctx.Hooks.(Detector); ok && d.Detect(in) {
+			return &ctx
+		}
+	}
+	return found
+}
+
+// hooks provides the context's output and tax rules followed by its own
+// hooks.
+func (c *Context) hooks() []Hooks {
+	list := []Hooks{c.Output, c.Tax}
+	if c.Hooks != nil {
+		list = append(list, c.Hooks)
+	}
+	return list
+}
+
+func (c *Context) beforeConvert(inv *bill.Invoice) error {
+	for _, h := range c.hooks() {
+		if err := h.BeforeConvert(inv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Context) afterConvert(inv *bill.Invoice, out *Invoice) error {
+	for _, h := range c.hooks() {
+		if err := h.AfterConvert(inv, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Context) beforeParse(in *Invoice) error {
+	for _, h := range c.hooks() {
+		if err := h.BeforeParse(in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Context) afterParse(in *Invoice, inv *bill.Invoice) error {
+	for _, h := range c.hooks() {
+		if err := h.AfterParse(in, inv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDocumentType sets the invoice's document type from its type if
+// missing, for contexts that do not require the EN16931 addon.
+func setDocumentType(inv *bill.Invoice) {
+	if inv.Tax == nil {
+		inv.Tax = new(bill.Tax)
+	}
+	code := cbc.Code("380")
+	switch inv.Type {
+	case bill.InvoiceTypeCreditNote:
+		code = "381"
+	case bill.InvoiceTypeDebitNote:
+		code = "383"
+	}
+	inv.Tax.Ext = inv.Tax.Ext.SetIfEmpty(untdid.ExtKeyDocumentType, code)
+}
+
+// BeforeConvert does nothing, output rules only apply to the generated
+// document.
+func (OutputRules) BeforeConvert(_ *bill.Invoice) error { return nil }
+
+// AfterConvert applies the enabled rules to the generated UBL invoice.
+func (r OutputRules) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	if r.ConcatenateNotes && len(out.Note) > 1 {
+		out.Note = []string{strings.Join(out.Note, "\n\n")}
+	}
+	if r.VersionID {
+		out.UBLVersionID = Version
+		if !inv.UUID.IsZero() {
+			out.UUID = inv.UUID.String()
+		}
+	}
+	if r.UUID && !inv.UUID.IsZero() {
+		out.UUID = inv.UUID.String()
+	}
+	if r.CopyIndicator {
+		out.includeCopyIndicator = true
+	}
+	if r.WithholdingTaxTotals {
+		out.addWithholdingTaxTotals(inv)
+	}
+	if r.SettlementDiscounts {
+		out.addSettlementDiscounts(inv)
+	}
+	if r.LineTaxTotals || r.SubLines {
+		creditNote := inv.Type.In(bill.InvoiceTypeCreditNote)
+		lines := out.InvoiceLines
+		if creditNote {
+			lines = out.CreditNoteLines
+		}
+		// Retained taxes are only moved out of the line tax totals when
+		// they are provided in withholding tax totals.
+		var retained map[cbc.Code]bool
+		if r.WithholdingTaxTotals {
+			retained = retainedCategories(inv)
+		}
+		for i, l := range inv.Lines {
+			if i >= len(lines) {
+				break
+			}
+			ccy := lineCurrency(inv, l)
+			if r.LineTaxTotals {
+				lines[i].TaxTotal = makeLineTaxTotals(l, ccy, retained)
+				if r.WithholdingTaxTotals {
+					lines[i].WithholdingTaxTotal = makeLineWithholdingTaxTotals(l, ccy, retained)
+				}
+			}
+			if r.SubLines {
+				if creditNote {
+					lines[i].SubCreditNoteLines = newSubLines(l, ccy, true)
+				} else {
+					lines[i].SubInvoiceLines = newSubLines(l, ccy, false)
+				}
+			}
+		}
+	}
+	if r.ForcePaymentMeans31 {
+		for i := range out.PaymentMeans {
+			pm := &out.PaymentMeans[i]
+			if pm.PaymentMeansCode.Value == "30" {
+				// OIOUBL restricts allowed payment means and expects code 31 for IBAN transfers.
+				pm.PaymentMeansCode.Value = "31"
+			}
+			pfa := pm.PayeeFinancialAccount
+			if pm.PaymentMeansCode.Value != "31" || pfa == nil {
+				continue
+			}
+			if b := pfa.FinancialInstitutionBranch; b != nil && b.ID != nil {
+				b.FinancialInstitution = &FinancialInstitution{ID: b.ID}
+			}
+			if pm.PaymentChannelCode == nil {
+				pm.PaymentChannelCode = &IDType{Value: "IBAN"}
+			}
+		}
+	}
+	return nil
+}
+
+// BeforeParse does nothing.
+func (OutputRules) BeforeParse(_ *Invoice) error { return nil }
+
+// AfterParse does nothing.
+func (OutputRules) AfterParse(_ *Invoice, _ *bill.Invoice) error { return nil }
+
+// BeforeConvert does nothing, tax rules only apply to the generated
+// document.
+func (TaxRules) BeforeConvert(_ *bill.Invoice) error { return nil }
+
+// AfterConvert replaces the VAT tax scheme and the UNTDID 5305 tax
+// categories of the generated invoice.
+func (r TaxRules) AfterConvert(_ *bill.Invoice, out *Invoice) error {
+	if r.Scheme == "" && len(r.Categories) == 0 {
+		return nil
+	}
+	out.eachTaxCategory(func(id *IDType, ts *TaxScheme) {
+		if id != nil {
+			if c, ok := r.Categories[id.Value]; ok {
+				id.Value = c
+			}
+		}
+		if ts != nil && r.Scheme != "" && ts.ID.Value == TaxSchemeVAT {
+			ts.ID.Value = r.Scheme
+		}
+	})
+	if r.Scheme != "" {
+		for _, p := range []*Party{out.AccountingSupplierParty.Party, out.AccountingCustomerParty.Party, out.PayeeParty, out.TaxRepresentativeParty} {
+			if p == nil {
+				continue
+			}
+			for _, pts := range p.PartyTaxScheme {
+				if pts.TaxScheme != nil && pts.TaxScheme.ID.Value == TaxSchemeVAT {
+					pts.TaxScheme.ID.Value = r.Scheme
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// BeforeParse maps the context's tax categories back into UNTDID 5305
+// codes. Tax schemes are kept, as they identify the GOBL tax category.
+func (r TaxRules) BeforeParse(in *Invoice) error {
+	if len(r.Categories) == 0 {
+		return nil
+	}
+	codes := make(map[string]string, len(r.Categories))
+	for k, v := range r.Categories {
+		codes[v] = k
+	}
+	in.eachTaxCategory(func(id *IDType, _ *TaxScheme) {
+		if id != nil {
+			if c, ok := codes[id.Value]; ok {
+				id.Value = c
+			}
+		}
+	})
+	return nil
+}
+
+// AfterParse does nothing.
+func (TaxRules) AfterParse(_ *Invoice, _ *bill.Invoice) error { return nil }
+
+// eachTaxCategory calls the function with the ID and tax scheme of every
+// tax category in the invoice.
+func (ui *Invoice) eachTaxCategory(fn func(id *IDType, ts *TaxScheme)) {
+	eachTaxTotal := func(totals []TaxTotal) {
+		for i := range totals {
+			for j := range totals[i].TaxSubtotal {
+				tc := &totals[i].TaxSubtotal[j].TaxCategory
+				fn(tc.ID, tc.TaxScheme)
+			}
+		}
+	}
+	eachCharge := func(ac *AllowanceCharge) {
+		for _, tc := range ac.TaxCategory {
+			if tc != nil {
+				fn(tc.ID, tc.TaxScheme)
+			}
+		}
+	}
+	var eachLine func(lines []InvoiceLine)
+	eachLine = func(lines []InvoiceLine) {
+		for i := range lines {
+			l := &lines[i]
+			if l.Item != nil && l.Item.ClassifiedTaxCategory != nil {
+				fn(l.Item.ClassifiedTaxCategory.ID, l.Item.ClassifiedTaxCategory.TaxScheme)
+			}
+			eachTaxTotal(l.TaxTotal)
+			eachTaxTotal(l.WithholdingTaxTotal)
+			for _, ac := range l.AllowanceCharge {
+				if ac != nil {
+					eachCharge(ac)
+				}
+			}
+			eachLine(l.SubInvoiceLines)
+			eachLine(l.SubCreditNoteLines)
+		}
+	}
+
+	eachTaxTotal(ui.TaxTotal)
+	eachTaxTotal(ui.WithholdingTaxTotal)
+	for i := range ui.AllowanceCharge {
+		eachCharge(&ui.AllowanceCharge[i])
+	}
+	eachLine(ui.InvoiceLines)
+	eachLine(ui.CreditNoteLines)
+}
+
+// eachTaxSubtotal calls the function with every tax subtotal of the
+// document and its lines, including withholding taxes.
+func (ui *Invoice) eachTaxSubtotal(fn func(st *TaxSubtotal)) {
+	each := func(totals []TaxTotal) {
+		for i := range totals {
+			for j := range totals[i].TaxSubtotal {
+				fn(&totals[i].TaxSubtotal[j])
+			}
+		}
+	}
+	each(ui.TaxTotal)
+	each(ui.WithholdingTaxTotal)
+	for _, lines := range [][]InvoiceLine{ui.InvoiceLines, ui.CreditNoteLines} {
+		for i := range lines {
+			each(lines[i].TaxTotal)
+			each(lines[i].WithholdingTaxTotal)
+		}
+	}
+}
+
+// removeTaxCategoryIDs removes the UNTDID 5305 tax category codes of the
+// tax subtotals and charges, for contexts that only identify taxes by
+// their scheme.
+func (ui *Invoice) removeTaxCategoryIDs() {
+	ui.eachTaxSubtotal(func(st *TaxSubtotal) {
+		st.TaxCategory.ID = nil
+	})
+	for i := range ui.AllowanceCharge {
+		for _, tc := range ui.AllowanceCharge[i].TaxCategory {
+			if tc != nil {
+				tc.ID = nil
+			}
+		}
+	}
+}
+
+// removeItemTaxCategories removes the classified tax category of the line
+// items, for contexts that only state line taxes in the line's tax total.
+func (ui *Invoice) removeItemTaxCategories() {
+	for _, lines := range [][]InvoiceLine{ui.InvoiceLines, ui.CreditNoteLines} {
+		for i := range lines {
+			if it := lines[i].Item; it != nil {
+				it.ClassifiedTaxCategory = nil
+			}
+		}
+	}
+}
+
+// addItemTaxCategories sets the classified tax category of the line items
+// without one from the first subtotal of the line's tax total, as
+// expected by the regular parsing process.
+func (ui *Invoice) addItemTaxCategories() {
+	for _, lines := range [][]InvoiceLine{ui.InvoiceLines, ui.CreditNoteLines} {
+		for i := range lines {
+			l := &lines[i]
+			if l.Item == nil || l.Item.ClassifiedTaxCategory != nil || len(l.TaxTotal) == 0 || len(l.TaxTotal[0].TaxSubtotal) == 0 {
+				continue
+			}
+			tc := l.TaxTotal[0].TaxSubtotal[0].TaxCategory
+			l.Item.ClassifiedTaxCategory = &ClassifiedTaxCategory{
+				ID:        tc.ID,
+				Percent:   tc.Percent,
+				TaxScheme: tc.TaxScheme,
+			}
+		}
+	}
+}
+
+// oioubl21Hooks adapts generated documents to the legacy OIOUBL 2.1
+// format.
+type oioubl21Hooks struct {
+	BaseHooks
+}
+
+func (oioubl21Hooks) AfterConvert(_ *bill.Invoice, out *Invoice) error {
+	applyLegacyOIOUBL21Rules(out)
+	return nil
+}
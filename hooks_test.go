@@ -0,0 +1,111 @@
+package ubl_test
+
+import (
+	"errors"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/org"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testHooks struct {
+	ubl.BaseHooks
+	calls []string
+	err   error
+}
+
+func (h *testHooks) BeforeConvert(inv *bill.Invoice) error {
+	h.calls = append(h.calls, "before-convert")
+	inv.Notes = append(inv.Notes, &org.Note{Text: "Added by hook"})
+	return h.err
+}
+
+func (h *testHooks) AfterConvert(_ *bill.Invoice, out *ubl.Invoice) error {
+	h.calls = append(h.calls, "after-convert")
+	out.BuyerReference = "HOOK"
+	return nil
+}
+
+func (h *testHooks) BeforeParse(_ *ubl.Invoice) error {
+	h.calls = append(h.calls, "before-parse")
+	return nil
+}
+
+func (h *testHooks) AfterParse(_ *ubl.Invoice, inv *bill.Invoice) error {
+	h.calls = append(h.calls, "after-parse")
+	inv.Code = cbc.Code("HOOK-" + inv.Code.String())
+	return nil
+}
+
+func TestContextHooks(t *testing.T) {
+	t.Run("convert", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		h := new(testHooks)
+		ctx := ubl.ContextPeppol
+		ctx.Hooks = h
+
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ctx))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"before-convert", "after-convert"}, h.calls)
+		assert.Equal(t, "HOOK", doc.BuyerReference)
+		require.Len(t, doc.Note, 1)
+		assert.Contains(t, doc.Note[0], "Added by hook")
+	})
+
+	t.Run("convert error", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		h := &testHooks{err: errors.New("rejected")}
+		ctx := ubl.ContextEN16931
+		ctx.Hooks = h
+
+		_, err = ubl.Convert(env, ubl.WithContext(ctx))
+		assert.EqualError(t, err, "rejected")
+		assert.Equal(t, []string{"before-convert"}, h.calls)
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		h := new(testHooks)
+		ctx := ubl.Context{
+			CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:example.com:hooks:1.0",
+			Hooks:           h,
+		}
+		ubl.RegisterContext("example-hooks", ctx)
+
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ctx))
+		require.NoError(t, err)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+
+		h.calls = nil
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		out, err := in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"before-parse", "after-parse"}, h.calls)
+		inv, ok := out.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		assert.Equal(t, "HOOK-"+doc.ID, inv.Code.String())
+	})
+
+	t.Run("output rules", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-complete.json")
+		require.NoError(t, err)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextOIOUBL))
+		require.NoError(t, err)
+		assert.Equal(t, ubl.Version, doc.UBLVersionID)
+		assert.NotEmpty(t, doc.InvoiceLines[0].TaxTotal)
+		pm := doc.PaymentMeans[0]
+		assert.Equal(t, "31", pm.PaymentMeansCode.Value)
+		assert.Equal(t, "IBAN", pm.PaymentChannelCode.Value)
+		require.NotNil(t, pm.PayeeFinancialAccount.FinancialInstitutionBranch.FinancialInstitution)
+		assert.Equal(t, "DNBANOKK", *pm.PayeeFinancialAccount.FinancialInstitutionBranch.FinancialInstitution.ID)
+	})
+}
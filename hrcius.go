@@ -0,0 +1,144 @@
+package ubl
+
+import (
+	"github.com/invopop/gobl/addons/eu/en16931"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/org"
+)
+
+// Croatian identity types used for the invoice operator and the product
+// classifications.
+const (
+	// IdentityTypeOIB is the Croatian personal identification number,
+	// used to identify the operator issuing the invoice.
+	IdentityTypeOIB cbc.Code = "OIB"
+	// IdentityTypeKPD is the Croatian classification of products by
+	// activity (KPD 2025).
+	IdentityTypeKPD cbc.Code = "KPD"
+)
+
+// hrKPDListID is the commodity classification list ID used for KPD codes.
+const hrKPDListID = "CG"
+
+// hrProfileID is the default business process for Croatian invoices,
+// covering the supply of goods and services.
+const hrProfileID = "P1"
+
+// ContextHRCIUS defines the context for Croatian HR CIUS documents with
+// the HR extension, used for fiscalisation 2.0 e-invoices. Incoming
+// documents may use any of the HR business processes as ProfileID.
+var ContextHRCIUS = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:mfin.gov.hr:cius-2025:1.0#conformant#urn:mfin.gov.hr:ext-2025:1.0",
+	Addons:          []cbc.Key{en16931.V2017},
+	Hooks:           hrCIUSHooks{},
+}
+
+// hrCIUSHooks maps the invoice operator and KPD classifications.
+type hrCIUSHooks struct {
+	BaseHooks
+}
+
+// AfterConvert adds the HR business process, the operator from the
+// supplier's people with an OIB, and the items' KPD classifications.
+func (hrCIUSHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	if out.ProfileID == "" {
+		out.ProfileID = hrProfileID
+	}
+	if inv.Supplier != nil {
+		out.AccountingSupplierParty.SellerContact = hrOperator(inv.Supplier.People)
+	}
+	lines := out.InvoiceLines
+	if len(out.CreditNoteLines) > 0 {
+		lines = out.CreditNoteLines
+	}
+	for i, l := range inv.Lines {
+		if i >= len(lines) || l.Item == nil || lines[i].Item == nil {
+			continue
+		}
+		applyHRItem(l.Item, lines[i].Item)
+	}
+	return nil
+}
+
+// AfterParse adds the operator to the supplier's people, and sets the
+// type of the KPD classifications.
+func (hrCIUSHooks) AfterParse(in *Invoice, inv *bill.Invoice) error {
+	if sc := in.AccountingSupplierParty.SellerContact; sc != nil && sc.ID != nil && inv.Supplier != nil {
+		parseHROperator(sc, inv.Supplier)
+	}
+	for _, l := range inv.Lines {
+		if l.Item == nil {
+			continue
+		}
+		for _, id := range l.Item.Identities {
+			if id.Label == hrKPDListID {
+				id.Type = IdentityTypeKPD
+				id.Label = ""
+			}
+		}
+	}
+	return nil
+}
+
+// hrOperator provides the seller contact for the first person with an
+// OIB identity.
+func hrOperator(people []*org.Person) *Contact {
+	for _, p := range people {
+		for _, id := range p.Identities {
+			if id.Type != IdentityTypeOIB {
+				continue
+			}
+			oib := id.Code.String()
+			c := &Contact{ID: &oib}
+			if p.Name != nil {
+				if n := contactName(p.Name); n != "" {
+					c.Name = &n
+				}
+			}
+			return c
+		}
+	}
+	return nil
+}
+
+// parseHROperator adds the operator's OIB to the supplier's person with
+// the same name, usually the contact, or as a new person otherwise.
+func parseHROperator(sc *Contact, supplier *org.Party) {
+	oib := &org.Identity{Type: IdentityTypeOIB, Code: cbc.Code(*sc.ID)}
+	var name string
+	if sc.Name != nil {
+		name = cleanString(*sc.Name)
+	}
+	for _, p := range supplier.People {
+		if name != "" && p.Name != nil && contactName(p.Name) == name {
+			p.Identities = append(p.Identities, oib)
+			return
+		}
+	}
+	op := &org.Person{Identities: []*org.Identity{oib}}
+	if name != "" {
+		op.Name = &org.Name{Given: name}
+	}
+	supplier.People = append(supplier.People, op)
+}
+
+// applyHRItem adds the item's KPD codes as commodity classifications.
+func applyHRItem(item *org.Item, it *Item) {
+	var cls []CommodityClassification
+	for _, id := range item.Identities {
+		if id.Type != IdentityTypeKPD {
+			continue
+		}
+		listID := hrKPDListID
+		cls = append(cls, CommodityClassification{
+			ItemClassificationCode: &IDType{ListID: &listID, Value: id.Code.String()},
+		})
+		if b := it.BuyersItemIdentification; b != nil && b.ID != nil && b.ID.Value == id.Code.String() {
+			it.BuyersItemIdentification = nil
+		}
+	}
+	if len(cls) > 0 {
+		it.CommodityClassification = &cls
+	}
+}
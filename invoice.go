@@ -3,7 +3,6 @@ package ubl
 import (
 	"encoding/xml"
 	"fmt"
-	"strings"
 
 	"github.com/invopop/gobl"
 	"github.com/invopop/gobl/bill"
@@ -30,6 +29,7 @@ type Invoice struct {
 	XMLName        xml.Name
 	CACNamespace   string `xml:"xmlns:cac,attr"`
 	CBCNamespace   string `xml:"xmlns:cbc,attr"`
+	EXTNamespace   string `xml:"xmlns:ext,attr,omitempty"`
 	QDTNamespace   string `xml:"xmlns:qdt,attr"`
 	UDTNamespace   string `xml:"xmlns:udt,attr"`
 	CCTSNamespace  string `xml:"xmlns:ccts,attr"`
@@ -52,6 +52,11 @@ type Invoice struct {
 	InvoiceTypeCode    string `xml:"cbc:InvoiceTypeCode,omitempty"`
 	CreditNoteTypeCode string `xml:"cbc:CreditNoteTypeCode,omitempty"`
 
+	// UUIDAttributes and TypeCodeAttributes qualify the UUID and the
+	// invoice or credit note type code, for the contexts that need them.
+	UUIDAttributes     *Attributes `xml:"-"`
+	TypeCodeAttributes *Attributes `xml:"-"`
+
 	Note                           []string            `xml:"cbc:Note,omitempty"`
 	TaxPointDate                   string              `xml:"cbc:TaxPointDate,omitempty"`
 	DocumentCurrencyCode           string              `xml:"cbc:DocumentCurrencyCode,omitempty"`
@@ -63,6 +68,7 @@ type Invoice struct {
 	LineCountNumeric               int                 `xml:"cbc:LineCountNumeric,omitempty"`
 	BuyerReference                 string              `xml:"cbc:BuyerReference,omitempty"`
 	InvoicePeriod                  []Period            `xml:"cac:InvoicePeriod,omitempty"`
+	DiscrepancyResponse            []Response          `xml:"cac:DiscrepancyResponse,omitempty"`
 	OrderReference                 *OrderReference     `xml:"cac:OrderReference,omitempty"`
 	BillingReference               []*BillingReference `xml:"cac:BillingReference,omitempty"`
 	DespatchDocumentReference      []Reference         `xml:"cac:DespatchDocumentReference,omitempty"`
@@ -94,6 +100,9 @@ type Invoice struct {
 	LegalMonetaryTotal             MonetaryTotal       `xml:"cac:LegalMonetaryTotal"`
 	InvoiceLines                   []InvoiceLine       `xml:"cac:InvoiceLine,omitempty"`
 	CreditNoteLines                []InvoiceLine       `xml:"cac:CreditNoteLine,omitempty"`
+
+	// includeCopyIndicator outputs the CopyIndicator also when false.
+	includeCopyIndicator bool
 }
 
 func ublInvoice(inv *bill.Invoice, o *options) (*Invoice, error) {
@@ -143,13 +152,6 @@ func ublInvoice(inv *bill.Invoice, o *options) (*Invoice, error) {
 		out.AccountingCost = inv.Ordering.Cost.String()
 	}
 
-	if o.context.IsOIOUBL() {
-		out.UBLVersionID = Version
-		if !inv.UUID.IsZero() {
-			out.UUID = inv.UUID.String()
-		}
-	}
-
 	if inv.Meta != nil {
 		if v, ok := inv.Meta[cbc.Key("copy")]; ok && v == "true" {
 			out.CopyIndicator = true
@@ -171,37 +173,151 @@ func ublInvoice(inv *bill.Invoice, o *options) (*Invoice, error) {
 			if note.Key == org.NoteKeyLegal {
 				continue
 			}
+			// The supplier's tax registration has its own party tax scheme
+			if isTaxRegistrationNote(note) {
+				out.AccountingSupplierParty.Party.addTaxRegistration(note.Text)
+				continue
+			}
 			noteTexts = append(noteTexts, note.Text)
 		}
 
 		if len(noteTexts) > 0 {
-			if o.context.Is(ContextPeppol) {
-				// Peppol only allows one note, so concatenate all notes
-				out.Note = []string{strings.Join(noteTexts, "\n\n")}
-			} else {
-				out.Note = noteTexts
-			}
+			out.Note = noteTexts
 		}
 	}
 
 	out.addPreceding(inv.Preceding)
 	out.addOrdering(inv.Ordering)
 	out.addCharges(inv)
-	out.addTotals(inv)
-	out.addLines(inv, o)
+	out.addTotals(inv, o.context.Output.WithholdingTaxTotals)
+	out.addTaxCurrency(inv)
+	out.addLines(inv)
 	out.addAttachments(inv.Attachments)
 
-	if err = out.addPayment(inv, o); err != nil {
+	if err = out.addPayment(inv); err != nil {
 		return nil, err
 	}
 	if d := newDelivery(inv.Delivery); d != nil {
 		out.Delivery = []*Delivery{d}
 	}
-	if o.context.Is(ContextOIOUBL21) {
-		applyLegacyOIOUBL21Rules(out)
+	return out, nil
+}
+
+// useInvoiceRoot makes sure the document uses the Invoice root element
+// with the provided type code, moving the lines of credit notes, for
+// contexts that do not support the CreditNote document.
+func (ui *Invoice) useInvoiceRoot(tc string) {
+	ui.XMLName = xml.Name{Local: "Invoice"}
+	ui.UBLNamespace = NamespaceUBLInvoice
+	ui.SchemaLocation = SchemaLocationInvoice
+	ui.InvoiceTypeCode = tc
+	ui.CreditNoteTypeCode = ""
+	if len(ui.CreditNoteLines) == 0 {
+		return
+	}
+	for i := range ui.CreditNoteLines {
+		l := &ui.CreditNoteLines[i]
+		l.InvoicedQuantity = l.CreditedQuantity
+		l.CreditedQuantity = nil
 	}
+	ui.InvoiceLines = ui.CreditNoteLines
+	ui.CreditNoteLines = nil
+}
 
-	return out, nil
+// typeCode provides the value of the invoice or credit note type code.
+func (ui *Invoice) typeCode() string {
+	if ui.InvoiceTypeCode != "" {
+		return ui.InvoiceTypeCode
+	}
+	return ui.CreditNoteTypeCode
+}
+
+// MarshalXML encodes the invoice, adding the attributes of the UUID and
+// type code elements, and the CopyIndicator when required. The header elements are repeated ahead of the rest
+// of the document so that the attributes are output in the right place.
+func (ui Invoice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type document Invoice
+	if ui.XMLName.Local != "" {
+		start.Name = ui.XMLName
+	}
+	var ci *bool
+	if ui.CopyIndicator || ui.includeCopyIndicator {
+		ci = &ui.CopyIndicator
+	}
+	return e.EncodeElement(struct {
+		UBLExtensions      *Extensions `xml:"ext:UBLExtensions,omitempty"`
+		UBLVersionID       string      `xml:"cbc:UBLVersionID,omitempty"`
+		CustomizationID    string      `xml:"cbc:CustomizationID,omitempty"`
+		ProfileID          string      `xml:"cbc:ProfileID,omitempty"`
+		ProfileExecutionID string      `xml:"cbc:ProfileExecutionID,omitempty"`
+		ID                 string      `xml:"cbc:ID"`
+		CopyIndicator      *bool       `xml:"cbc:CopyIndicator,omitempty"`
+		UUID               *IDType     `xml:"cbc:UUID,omitempty"`
+		IssueDate          string      `xml:"cbc:IssueDate"`
+		IssueTime          string      `xml:"cbc:IssueTime,omitempty"`
+		DueDate            string      `xml:"cbc:DueDate,omitempty"`
+		InvoiceTypeCode    *IDType     `xml:"cbc:InvoiceTypeCode,omitempty"`
+		CreditNoteTypeCode *IDType     `xml:"cbc:CreditNoteTypeCode,omitempty"`
+		document
+	}{
+		UBLExtensions:      ui.UBLExtensions,
+		UBLVersionID:       ui.UBLVersionID,
+		CustomizationID:    ui.CustomizationID,
+		ProfileID:          ui.ProfileID,
+		ProfileExecutionID: ui.ProfileExecutionID,
+		ID:                 ui.ID,
+		CopyIndicator:      ci,
+		UUID:               optionalIDType(ui.UUID, ui.UUIDAttributes),
+		IssueDate:          ui.IssueDate,
+		IssueTime:          ui.IssueTime,
+		DueDate:            ui.DueDate,
+		InvoiceTypeCode:    optionalIDType(ui.InvoiceTypeCode, ui.TypeCodeAttributes),
+		CreditNoteTypeCode: optionalIDType(ui.CreditNoteTypeCode, ui.TypeCodeAttributes),
+		document:           document(ui),
+	}, start)
+}
+
+// invoiceAttributes is used to read the attributes of the elements that
+// the invoice maps to plain values.
+type invoiceAttributes struct {
+	CopyIndicator      *bool   `xml:"cbc:CopyIndicator"`
+	UUID               *IDType `xml:"cbc:UUID"`
+	InvoiceTypeCode    *IDType `xml:"cbc:InvoiceTypeCode"`
+	CreditNoteTypeCode *IDType `xml:"cbc:CreditNoteTypeCode"`
+	Signature          []struct {
+		ID *IDType `xml:"cbc:ID"`
+	} `xml:"cac:Signature"`
+}
+
+// parseAttributes sets the attributes of the elements that are mapped to
+// plain values from the raw document.
+func (ui *Invoice) parseAttributes(data []byte, ns string) error {
+	attrs := new(invoiceAttributes)
+	if err := unmarshal(data, ns, attrs); err != nil {
+		return err
+	}
+	ui.includeCopyIndicator = attrs.CopyIndicator != nil
+	ui.UUIDAttributes = newAttributes(attrs.UUID)
+	ui.TypeCodeAttributes = newAttributes(attrs.InvoiceTypeCode)
+	if ui.TypeCodeAttributes == nil {
+		ui.TypeCodeAttributes = newAttributes(attrs.CreditNoteTypeCode)
+	}
+	for i, s := range attrs.Signature {
+		if i < len(ui.Signature) {
+			ui.Signature[i].IDAttributes = newAttributes(s.ID)
+		}
+	}
+	return nil
+}
+
+// optionalIDType provides an IDType with the value and attributes, or nil
+// if there is no value.
+func optionalIDType(value string, a *Attributes) *IDType {
+	if value == "" {
+		return nil
+	}
+	id := a.idType(value)
+	return &id
 }
 
 func invoiceNumber(series cbc.Code, code cbc.Code) string {
@@ -3,8 +3,10 @@ package ubl
 import (
 	"github.com/invopop/gobl"
 	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
 	"github.com/invopop/gobl/cbc"
 	"github.com/invopop/gobl/currency"
+	"github.com/invopop/gobl/num"
 	"github.com/invopop/gobl/org"
 	"github.com/invopop/gobl/tax"
 	"github.com/invopop/gobl/uuid"
@@ -21,6 +23,10 @@ var invoiceTypeMap = map[string]cbc.Key{
 	"261": bill.InvoiceTypeCreditNote,
 }
 
+// exchangeRatePrecision is the number of decimals used for exchange rates
+// derived from the tax totals.
+const exchangeRatePrecision = 6
+
 // InvoiceTagMap maps UBL invoice type codes to GOBL tax tags.
 var InvoiceTagMap = map[string][]cbc.Key{
 	"389": {tax.TagSelfBilled},
@@ -29,22 +35,36 @@ var InvoiceTagMap = map[string][]cbc.Key{
 }
 
 // Convert converts the UBL Invoice to a GOBL envelope.
-// It automatically detects the context based on CustomizationID and ProfileID.
+// It automatically detects the context based on CustomizationID and ProfileID,
+// unless one is provided with the WithContext option. Contexts that share their
+// identifiers with others, such as the national Peppol variants, are told apart
+// by their hooks.
 // Binary attachments are ignored during conversion - use ExtractBinaryAttachments
 // to retrieve them separately.
-func (ui *Invoice) Convert() (*gobl.Envelope, error) {
+func (ui *Invoice) Convert(opts ...Option) (*gobl.Envelope, error) {
 	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
 
 	// Detect context from the invoice
-	ctx := FindContext(ui.CustomizationID, ui.ProfileID)
-	if ctx != nil {
-		o.context = *ctx
+	if o.context.CustomizationID == "" {
+		ctx := detectContext(ui, FindContext(ui.CustomizationID, ui.ProfileID))
+		if ctx != nil {
+			o.context = *ctx
+		}
 	}
 
+	if err := o.context.beforeParse(ui); err != nil {
+		return nil, err
+	}
 	inv, err := ui.goblInvoice(o)
 	if err != nil {
 		return nil, err
 	}
+	if err := o.context.afterParse(ui, inv); err != nil {
+		return nil, err
+	}
 
 	env := gobl.NewEnvelope()
 	if err := env.Insert(inv); err != nil {
@@ -69,10 +89,7 @@ func (ui *Invoice) goblInvoice(o *options) (*bill.Invoice, error) {
 		Supplier: goblParty(ui.AccountingSupplierParty.Party),
 		Customer: goblParty(ui.AccountingCustomerParty.Party),
 	}
-	typeCode := ui.InvoiceTypeCode
-	if typeCode == "" {
-		typeCode = ui.CreditNoteTypeCode
-	}
+	typeCode := ui.typeCode()
 	out.Type = typeCodeParse(typeCode)
 	tags := tagCodeParse(typeCode)
 
@@ -93,6 +110,14 @@ func (ui *Invoice) goblInvoice(o *options) (*bill.Invoice, error) {
 		}
 	}
 
+	rate, err := ui.goblTaxExchangeRate()
+	if err != nil {
+		return nil, err
+	}
+	if rate != nil {
+		out.ExchangeRates = []*currency.ExchangeRate{rate}
+	}
+
 	if ui.CopyIndicator {
 		if out.Meta == nil {
 			out.Meta = make(cbc.Meta)
@@ -122,6 +147,9 @@ func (ui *Invoice) goblInvoice(o *options) (*bill.Invoice, error) {
 			out.Notes = append(out.Notes, n)
 		}
 	}
+	if n := goblTaxRegistrationNote(ui.AccountingSupplierParty.Party); n != nil {
+		out.Notes = append(out.Notes, n)
+	}
 
 	if len(ui.BillingReference) > 0 {
 		out.Preceding = make([]*org.DocumentRef, 0, len(ui.BillingReference))
@@ -170,6 +198,57 @@ func (ui *Invoice) goblInvoice(o *options) (*bill.Invoice, error) {
 	return out, nil
 }
 
+// goblTaxExchangeRate provides the exchange rate from the document's
+// currency into the tax currency, using the TaxExchangeRate when present
+// or otherwise the tax totals provided in each currency.
+func (ui *Invoice) goblTaxExchangeRate() (*currency.ExchangeRate, error) {
+	if ui.TaxCurrencyCode == "" || ui.TaxCurrencyCode == ui.DocumentCurrencyCode {
+		return nil, nil
+	}
+	rate := &currency.ExchangeRate{
+		From: currency.Code(ui.DocumentCurrencyCode),
+		To:   currency.Code(ui.TaxCurrencyCode),
+	}
+
+	if er := ui.TaxExchangeRate; er != nil && er.CalculationRate != nil {
+		a, err := num.AmountFromString(normalizeNumericString(*er.CalculationRate))
+		if err != nil {
+			return nil, err
+		}
+		rate.Amount = a
+		if er.Date != nil {
+			d, err := parseDate(*er.Date)
+			if err != nil {
+				return nil, err
+			}
+			rate.At = cal.NewDateTime(d.Year, d.Month, d.Day, 0, 0, 0)
+		}
+		return rate, nil
+	}
+
+	var amount, taxAmount *num.Amount
+	for _, tt := range ui.TaxTotal {
+		if tt.TaxAmount.CurrencyID == nil {
+			continue
+		}
+		a, err := num.AmountFromString(normalizeNumericString(tt.TaxAmount.Value))
+		if err != nil {
+			return nil, err
+		}
+		switch *tt.TaxAmount.CurrencyID {
+		case ui.DocumentCurrencyCode:
+			amount = &a
+		case ui.TaxCurrencyCode:
+			taxAmount = &a
+		}
+	}
+	if amount == nil || taxAmount == nil || amount.IsZero() {
+		return nil, nil
+	}
+	rate.Amount = taxAmount.RescaleUp(exchangeRatePrecision).Divide(*amount)
+	return rate, nil
+}
+
 // typeCodeParse maps UBL invoice type to GOBL equivalent.
 // Source: https://unece.org/fileadmin/DAM/trade/untdid/d16b/tred/tred1001.htm
 func typeCodeParse(typeCode string) cbc.Key {
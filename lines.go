@@ -1,12 +1,16 @@
 package ubl
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/invopop/gobl/bill"
 	"github.com/invopop/gobl/catalogues/iso"
 	"github.com/invopop/gobl/catalogues/untdid"
+	"github.com/invopop/gobl/cbc"
 	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
 )
 
 // InvoiceLine represents a line item in an invoice and credit note
@@ -21,11 +25,14 @@ type InvoiceLine struct {
 	OrderLineReference  *OrderLineReference `xml:"cac:OrderLineReference"`
 	AllowanceCharge     []*AllowanceCharge  `xml:"cac:AllowanceCharge"`
 	TaxTotal            []TaxTotal          `xml:"cac:TaxTotal,omitempty"`
+	WithholdingTaxTotal []TaxTotal          `xml:"cac:WithholdingTaxTotal,omitempty"`
 	Item                *Item               `xml:"cac:Item"`
 	Price               *Price              `xml:"cac:Price"`
+	SubInvoiceLines     []InvoiceLine       `xml:"cac:SubInvoiceLine,omitempty"`
+	SubCreditNoteLines  []InvoiceLine       `xml:"cac:SubCreditNoteLine,omitempty"`
 }
 
-func (ui *Invoice) addLines(inv *bill.Invoice, o *options) { //nolint:gocyclo
+func (ui *Invoice) addLines(inv *bill.Invoice) { //nolint:gocyclo
 	if len(inv.Lines) == 0 {
 		return
 	}
@@ -33,10 +40,7 @@ func (ui *Invoice) addLines(inv *bill.Invoice, o *options) { //nolint:gocyclo
 	var lines []InvoiceLine
 
 	for _, l := range inv.Lines {
-		ccy := l.Item.Currency.String()
-		if ccy == "" {
-			ccy = inv.Currency.String()
-		}
+		ccy := lineCurrency(inv, l)
 		invLine := InvoiceLine{
 			ID: strconv.Itoa(l.Index),
 
@@ -84,124 +88,200 @@ func (ui *Invoice) addLines(inv *bill.Invoice, o *options) { //nolint:gocyclo
 		}
 
 		if l.Item != nil {
-			it := &Item{}
+			invLine.Item = newItem(l)
+			invLine.Price = newPrice(l.Item, ccy)
+		}
 
-			if l.Item.Description != "" {
-				d := l.Item.Description
-				it.Description = &d
-			}
+		lines = append(lines, invLine)
+	}
+	if inv.Type.In(bill.InvoiceTypeCreditNote) {
+		ui.CreditNoteLines = lines
+	} else {
+		ui.InvoiceLines = lines
+	}
+}
 
-			if l.Item.Name != "" {
-				it.Name = l.Item.Name
-			}
+// lineCurrency provides the currency of the line's item, or the
+// invoice's currency if none is set.
+func lineCurrency(inv *bill.Invoice, l *bill.Line) string {
+	if l.Item != nil && l.Item.Currency != "" {
+		return l.Item.Currency.String()
+	}
+	return inv.Currency.String()
+}
 
-			if l.Item.Origin != "" {
-				it.OriginCountry = &Country{
-					IdentificationCode: l.Item.Origin.String(),
-				}
-			}
+// newSubLines builds the UBL sub-lines from the breakdown of a GOBL line.
+// Sub-line items are classified using the taxes of the parent line, and
+// those without a price are skipped.
+func newSubLines(l *bill.Line, ccy string, creditNote bool) []InvoiceLine {
+	var lines []InvoiceLine
+	for _, sl := range l.Breakdown {
+		if sl.Item == nil || sl.Total == nil {
+			continue
+		}
+		sub := InvoiceLine{
+			ID: fmt.Sprintf("%d.%d", l.Index, sl.Index),
+			LineExtensionAmount: Amount{
+				CurrencyID: &ccy,
+				Value:      sl.Total.String(),
+			},
+		}
 
-			if l.Item.Meta != nil {
-				var properties []AdditionalItemProperty
-				for key, value := range l.Item.Meta {
-					properties = append(properties, AdditionalItemProperty{Name: key.String(), Value: value})
-				}
-				it.AdditionalItemProperty = &properties
+		iq := &Quantity{
+			Value: sl.Quantity.String(),
+		}
+		if sl.Item.Unit != "" {
+			iq.UnitCode = string(sl.Item.Unit.UNECE())
+		}
+		if creditNote {
+			sub.CreditedQuantity = iq
+		} else {
+			sub.InvoicedQuantity = iq
+		}
+
+		for _, note := range sl.Notes {
+			sub.Note = append(sub.Note, note.Text)
+		}
+		if sl.Cost != "" {
+			cost := sl.Cost.String()
+			sub.AccountingCost = &cost
+		}
+		if sl.Order != "" {
+			sub.OrderLineReference = &OrderLineReference{
+				LineID: sl.Order.String(),
 			}
+		}
+		if len(sl.Charges) > 0 || len(sl.Discounts) > 0 {
+			sub.AllowanceCharge = makeLineCharges(sl.Charges, sl.Discounts, ccy, sl.Sum)
+		}
 
-			if len(l.Taxes) > 0 && l.Taxes[0].Category != "" {
-				it.ClassifiedTaxCategory = &ClassifiedTaxCategory{
-					TaxScheme: &TaxScheme{
-						ID: IDType{Value: l.Taxes[0].Category.String()},
-					},
-				}
+		sub.Item = newItem(&bill.Line{Item: sl.Item, Taxes: l.Taxes})
+		sub.Price = newPrice(sl.Item, ccy)
 
-				if l.Taxes[0].Ext != nil && l.Taxes[0].Ext[untdid.ExtKeyTaxCategory].String() != "" {
-					rate := l.Taxes[0].Ext[untdid.ExtKeyTaxCategory].String()
-					it.ClassifiedTaxCategory.ID = &IDType{Value: rate}
-				}
+		lines = append(lines, sub)
+	}
+	return lines
+}
 
-				// Set percent: required unless category is "O" (outside scope)
-				if l.Taxes[0].Percent != nil {
-					p := l.Taxes[0].Percent.StringWithoutSymbol()
-					it.ClassifiedTaxCategory.Percent = &p
-				} else if it.ClassifiedTaxCategory.ID == nil || it.ClassifiedTaxCategory.ID.Value != "O" {
-					// Default to 0% when not outside scope
-					p := "0"
-					it.ClassifiedTaxCategory.Percent = &p
-				}
+// newItem builds the UBL Item for a GOBL line, including its tax category
+// and any identities.
+func newItem(l *bill.Line) *Item {
+	it := &Item{}
 
-				if l.Taxes[0].Ext != nil && l.Taxes[0].Ext[untdid.ExtKeyTaxCategory].String() != "" {
-					rate := l.Taxes[0].Ext[untdid.ExtKeyTaxCategory].String()
-					it.ClassifiedTaxCategory.ID = &IDType{Value: rate}
-				}
-			}
+	if l.Item.Description != "" {
+		d := l.Item.Description
+		it.Description = &d
+	}
 
-			if len(l.Item.Identities) > 0 {
-				for _, id := range l.Item.Identities {
-					if it.BuyersItemIdentification != nil && it.StandardItemIdentification != nil {
-						break
-					}
+	if l.Item.Name != "" {
+		it.Name = l.Item.Name
+	}
 
-					// Map first identity without extension to BuyersItemIdentification
-					if id.Ext == nil || id.Ext[iso.ExtKeySchemeID].String() == "" {
-						if it.BuyersItemIdentification == nil {
-							it.BuyersItemIdentification = &ItemIdentification{
-								ID: &IDType{
-									Value: id.Code.String(),
-								},
-							}
-						}
-						continue
-					}
+	if l.Item.Origin != "" {
+		it.OriginCountry = &Country{
+			IdentificationCode: l.Item.Origin.String(),
+		}
+	}
 
-					// Map first identity with extension to StandardItemIdentification
-					if it.StandardItemIdentification == nil {
-						s := id.Ext[iso.ExtKeySchemeID].String()
-						it.StandardItemIdentification = &ItemIdentification{
-							ID: &IDType{
-								SchemeID: &s,
-								Value:    id.Code.String(),
-							},
-						}
-					}
-				}
-			}
+	if l.Item.Meta != nil {
+		var properties []AdditionalItemProperty
+		for key, value := range l.Item.Meta {
+			properties = append(properties, AdditionalItemProperty{Name: key.String(), Value: value})
+		}
+		it.AdditionalItemProperty = &properties
+	}
+
+	if len(l.Taxes) > 0 && l.Taxes[0].Category != "" {
+		it.ClassifiedTaxCategory = &ClassifiedTaxCategory{
+			TaxScheme: &TaxScheme{
+				ID: IDType{Value: l.Taxes[0].Category.String()},
+			},
+			ID: taxCategoryID(l.Taxes[0].Key, l.Taxes[0].Ext),
+		}
 
-			invLine.Item = it
+		// Set percent: required unless category is "O" (outside scope)
+		if l.Taxes[0].Percent != nil {
+			p := l.Taxes[0].Percent.StringWithoutSymbol()
+			it.ClassifiedTaxCategory.Percent = &p
+		} else if it.ClassifiedTaxCategory.ID == nil || it.ClassifiedTaxCategory.ID.Value != "O" {
+			// Default to 0% when not outside scope
+			p := "0"
+			it.ClassifiedTaxCategory.Percent = &p
+		}
+	}
 
-			if l.Item.Price != nil {
-				invLine.Price = &Price{
-					PriceAmount: Amount{
-						CurrencyID: &ccy,
-						Value:      l.Item.Price.String(),
-					},
+	if len(l.Item.Identities) > 0 {
+		for _, id := range l.Item.Identities {
+			if it.BuyersItemIdentification != nil && it.StandardItemIdentification != nil {
+				break
+			}
+
+			// Map first identity without extension to BuyersItemIdentification
+			if id.Ext == nil || id.Ext[iso.ExtKeySchemeID].String() == "" {
+				if it.BuyersItemIdentification == nil {
+					it.BuyersItemIdentification = &ItemIdentification{
+						ID: &IDType{
+							Value: id.Code.String(),
+						},
+					}
 				}
+				continue
 			}
 
-			if l.Item.Ref != "" {
-				invLine.Item.SellersItemIdentification = &ItemIdentification{
+			// Map first identity with extension to StandardItemIdentification
+			if it.StandardItemIdentification == nil {
+				s := id.Ext[iso.ExtKeySchemeID].String()
+				it.StandardItemIdentification = &ItemIdentification{
 					ID: &IDType{
-						Value: l.Item.Ref.String(),
+						SchemeID: &s,
+						Value:    id.Code.String(),
 					},
 				}
 			}
 		}
+	}
 
-		if o.context.IsOIOUBL() {
-			invLine.TaxTotal = makeLineTaxTotals(l, ccy)
+	if l.Item.Ref != "" {
+		it.SellersItemIdentification = &ItemIdentification{
+			ID: &IDType{
+				Value: l.Item.Ref.String(),
+			},
 		}
+	}
 
-		lines = append(lines, invLine)
+	return it
+}
+
+// newPrice builds the UBL Price for a GOBL item, or nil if the item has no price.
+func newPrice(item *org.Item, ccy string) *Price {
+	if item == nil || item.Price == nil {
+		return nil
 	}
-	if inv.Type.In(bill.InvoiceTypeCreditNote) {
-		ui.CreditNoteLines = lines
-	} else {
-		ui.InvoiceLines = lines
+	return &Price{
+		PriceAmount: Amount{
+			CurrencyID: &ccy,
+			Value:      item.Price.String(),
+		},
 	}
 }
 
-func makeLineTaxTotals(line *bill.Line, ccy string) []TaxTotal {
+// makeLineTaxTotals provides the tax total of the line's taxes, excluding
+// those of the retained categories.
+func makeLineTaxTotals(line *bill.Line, ccy string, retained map[cbc.Code]bool) []TaxTotal {
+	return newLineTaxTotals(line, ccy, func(c *tax.Combo) bool {
+		return !retained[c.Category]
+	})
+}
+
+// makeLineWithholdingTaxTotals provides the withholding tax total of the
+// line's taxes in the retained categories.
+func makeLineWithholdingTaxTotals(line *bill.Line, ccy string, retained map[cbc.Code]bool) []TaxTotal {
+	return newLineTaxTotals(line, ccy, func(c *tax.Combo) bool {
+		return retained[c.Category]
+	})
+}
+
+func newLineTaxTotals(line *bill.Line, ccy string, include func(c *tax.Combo) bool) []TaxTotal {
 	if line == nil || len(line.Taxes) == 0 {
 		return nil
 	}
@@ -221,29 +301,29 @@ func makeLineTaxTotals(line *bill.Line, ccy string) []TaxTotal {
 	}
 	totalAmount := num.MakeAmount(0, taxable.Exp())
 
-	for _, tax := range line.Taxes {
+	for _, combo := range line.Taxes {
+		if !include(combo) {
+			continue
+		}
 		subtotal := TaxSubtotal{
 			TaxableAmount: Amount{Value: taxable.String(), CurrencyID: &ccy},
 		}
-		taxCat := TaxCategory{}
-
-		if tax.Ext != nil && tax.Ext[untdid.ExtKeyTaxCategory].String() != "" {
-			k := tax.Ext[untdid.ExtKeyTaxCategory].String()
-			taxCat.ID = &IDType{Value: k}
+		taxCat := TaxCategory{
+			ID: taxCategoryID(combo.Key, combo.Ext),
 		}
 
-		if tax.Percent != nil {
-			p := tax.Percent.StringWithoutSymbol()
+		if combo.Percent != nil {
+			p := combo.Percent.StringWithoutSymbol()
 			taxCat.Percent = &p
-			amount := tax.Percent.Of(taxable).Rescale(taxable.Exp())
+			amount := combo.Percent.Of(taxable).Rescale(taxable.Exp())
 			subtotal.TaxAmount = Amount{Value: amount.String(), CurrencyID: &ccy}
 			totalAmount = totalAmount.Add(amount)
 		} else {
 			subtotal.TaxAmount = Amount{Value: "0", CurrencyID: &ccy}
 		}
 
-		if tax.Category != "" {
-			taxCat.TaxScheme = &TaxScheme{ID: IDType{Value: tax.Category.String()}}
+		if combo.Category != "" {
+			taxCat.TaxScheme = &TaxScheme{ID: IDType{Value: combo.Category.String()}}
 		}
 		subtotal.TaxCategory = taxCat
 		taxTotal.TaxSubtotal = append(taxTotal.TaxSubtotal, subtotal)
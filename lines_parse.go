@@ -32,6 +32,7 @@ func (ui *Invoice) goblAddLines(out *bill.Invoice) error {
 			return err
 		}
 		if line != nil {
+			goblAddLineRetainedTaxes(line, &docLine, ui.WithholdingTaxTotal)
 			out.Lines = append(out.Lines, line)
 		}
 	}
@@ -120,9 +121,42 @@ func goblConvertLine(docLine *InvoiceLine, taxCategoryMap map[string]*taxCategor
 	if len(notes) > 0 {
 		line.Notes = notes
 	}
+
+	subLines := docLine.SubInvoiceLines
+	if len(docLine.SubCreditNoteLines) > 0 {
+		subLines = docLine.SubCreditNoteLines
+	}
+	for _, sub := range subLines {
+		sl, err := goblConvertSubLine(&sub, taxCategoryMap)
+		if err != nil {
+			return nil, err
+		}
+		if sl != nil {
+			line.Breakdown = append(line.Breakdown, sl)
+		}
+	}
 	return line, nil
 }
 
+// goblConvertSubLine converts a UBL sub-line into a GOBL sub-line for the
+// parent line's breakdown. GOBL only supports a single level of sub-lines,
+// so any further nesting is lost.
+func goblConvertSubLine(docLine *InvoiceLine, taxCategoryMap map[string]*taxCategoryInfo) (*bill.SubLine, error) {
+	line, err := goblConvertLine(docLine, taxCategoryMap)
+	if err != nil || line == nil {
+		return nil, err
+	}
+	return &bill.SubLine{
+		Quantity:  line.Quantity,
+		Order:     line.Order,
+		Cost:      line.Cost,
+		Item:      line.Item,
+		Discounts: line.Discounts,
+		Charges:   line.Charges,
+		Notes:     line.Notes,
+	}, nil
+}
+
 // calculateRequiredPrecision determines the decimal precision needed when
 // dividing a price by a base quantity to avoid rounding errors.
 // Formula: price_decimals + ceil(log10(base_quantity))
@@ -200,11 +234,7 @@ func goblConvertLineItemTaxes(di *Item, line *bill.Line, taxCategoryMap map[stri
 		}
 	}
 	if ctc.Percent != nil {
-		percentStr := normalizeNumericString(*ctc.Percent)
-		if !strings.HasSuffix(percentStr, "%") {
-			percentStr += "%"
-		}
-		percent, _ := num.PercentageFromString(percentStr)
+		percent, _ := goblTaxPercent(*ctc.Percent)
 
 		// Skip setting percent if it's 0% and tax category is not "Z" (zero-rated)
 		// This prevents GOBL from normalizing to "zero" tax rate for exempt/reverse-charge cases
@@ -220,6 +250,45 @@ func goblConvertLineItemTaxes(di *Item, line *bill.Line, taxCategoryMap map[stri
 	}
 }
 
+// goblAddLineRetainedTaxes adds the retained taxes of the line's
+// withholding tax total to the line. Lines without one use those of the
+// document instead, as retained taxes usually apply to the whole invoice.
+func goblAddLineRetainedTaxes(line *bill.Line, docLine *InvoiceLine, withholding []TaxTotal) {
+	totals := docLine.WithholdingTaxTotal
+	if len(totals) == 0 {
+		totals = withholding
+	}
+	for _, tt := range totals {
+		for _, st := range tt.TaxSubtotal {
+			ts := st.TaxCategory.TaxScheme
+			if ts == nil || ts.ID.Value == "" || line.Taxes.Get(cbc.Code(ts.ID.Value)) != nil {
+				continue
+			}
+			combo := &tax.Combo{Category: cbc.Code(ts.ID.Value)}
+			p := st.TaxCategory.Percent
+			if p == nil {
+				p = st.Percent
+			}
+			if p != nil {
+				if percent, err := goblTaxPercent(*p); err == nil {
+					combo.Percent = &percent
+				}
+			}
+			line.Taxes = append(line.Taxes, combo)
+		}
+	}
+}
+
+// goblTaxPercent parses a UBL tax percent, which does not include the
+// percent sign.
+func goblTaxPercent(value string) (num.Percentage, error) {
+	percentStr := normalizeNumericString(value)
+	if !strings.HasSuffix(percentStr, "%") {
+		percentStr += "%"
+	}
+	return num.PercentageFromString(percentStr)
+}
+
 func goblItemIdentities(di *Item) []*org.Identity {
 	ids := make([]*org.Identity, 0)
 
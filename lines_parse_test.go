@@ -158,4 +158,41 @@ func TestParseLines(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid major number")
 	})
+
+	t.Run("withholding tax totals", func(t *testing.T) {
+		// Retained taxes of the document apply to lines without their own.
+		lineCtx := ubl.ContextEN16931
+		lineCtx.Output.LineTaxTotals = true
+		lineCtx.Output.WithholdingTaxTotals = true
+		docCtx := ubl.ContextEN16931
+		docCtx.Output.WithholdingTaxTotals = true
+
+		for name, ctx := range map[string]ubl.Context{
+			"line":     lineCtx,
+			"document": docCtx,
+		} {
+			t.Run(name, func(t *testing.T) {
+				doc, err := ubl.ConvertInvoice(testRetainedEnvelope(t), ubl.WithContext(ctx))
+				require.NoError(t, err)
+				data, err := ubl.Bytes(doc)
+				require.NoError(t, err)
+
+				in, err := ubl.Parse(data)
+				require.NoError(t, err)
+				env, err := in.(*ubl.Invoice).Convert()
+				require.NoError(t, err)
+				inv, ok := env.Extract().(*bill.Invoice)
+				require.True(t, ok)
+
+				require.Len(t, inv.Lines, 1)
+				require.Len(t, inv.Lines[0].Taxes, 2)
+				irpf := inv.Lines[0].Taxes[1]
+				assert.Equal(t, cbc.Code("IRPF"), irpf.Category)
+				assert.Equal(t, "15%", irpf.Percent.String())
+				require.NotNil(t, inv.Totals.RetainedTax)
+				assert.Equal(t, "243.00", inv.Totals.RetainedTax.String())
+				assert.Equal(t, "1717.20", inv.Totals.Payable.String())
+			})
+		}
+	})
 }
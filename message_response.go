@@ -0,0 +1,122 @@
+package ubl
+
+import "strings"
+
+// Status reason codes used in Message Level Response line responses.
+const (
+	MLRStatusBusinessRuleFatal   = "BV"
+	MLRStatusBusinessRuleWarning = "BW"
+	MLRStatusSyntaxViolation     = "SV"
+)
+
+// ListIDMLRStatusReason is the list identifier for MLR status reason codes.
+const ListIDMLRStatusReason = "PEPPOLSTATUS"
+
+// NewMessageLevelResponse builds a Peppol Message Level Response reporting
+// the findings of validating the provided raw UBL document. If the document
+// cannot be parsed, the parsing error is included as a syntax finding.
+//
+// The response code is AB when there are no findings, AP when there are
+// only warnings, and RE when at least one finding is fatal.
+func NewMessageLevelResponse(data []byte, findings []Finding) *ApplicationResponse {
+	out := newApplicationResponse(ContextPeppolMLR)
+
+	ref := Reference{ID: IDType{Value: notApplicable}}
+	doc, err := Parse(data)
+	if err != nil {
+		findings = append([]Finding{
+			{
+				Flag:     FlagFatal,
+				Location: "/",
+				Message:  err.Error(),
+				Syntax:   true,
+			},
+		}, findings...)
+	}
+	switch d := doc.(type) {
+	case *Invoice:
+		ref.ID.Value = d.ID
+		ref.IssueDate = d.IssueDate
+		out.SenderParty = responseParty(d.AccountingCustomerParty.Party)
+		out.ReceiverParty = responseParty(d.AccountingSupplierParty.Party)
+	case *Order:
+		ref.ID.Value = d.ID
+		ref.IssueDate = d.IssueDate
+		out.SenderParty = responseParty(d.SellerSupplierParty.Party)
+		out.ReceiverParty = responseParty(d.BuyerCustomerParty.Party)
+	case *DespatchAdvice:
+		ref.ID.Value = d.ID
+		ref.IssueDate = d.IssueDate
+		out.SenderParty = responseParty(d.DeliveryCustomerParty.Party)
+		out.ReceiverParty = responseParty(d.DespatchSupplierParty.Party)
+	}
+
+	code := ResponseCodeAcknowledged
+	if len(findings) > 0 {
+		code = ResponseCodeAccepted
+	}
+	for _, f := range findings {
+		if f.IsFatal() {
+			code = ResponseCodeRejected
+			break
+		}
+	}
+
+	responseListID := ListIDResponseCode
+	dr := DocumentResponse{
+		Response: Response{
+			ResponseCode: &IDType{
+				ListID: &responseListID,
+				Value:  code,
+			},
+		},
+		DocumentReference: []Reference{ref},
+	}
+	for _, f := range findings {
+		dr.LineResponse = append(dr.LineResponse, newLineResponse(f))
+	}
+	out.DocumentResponse = []DocumentResponse{dr}
+
+	return out
+}
+
+func newLineResponse(f Finding) LineResponse {
+	location := f.Location
+	if location == "" {
+		location = notApplicable
+	}
+
+	code := ResponseCodeRejected
+	status := MLRStatusBusinessRuleFatal
+	switch {
+	case f.Syntax:
+		status = MLRStatusSyntaxViolation
+	case !f.IsFatal():
+		code = ResponseCodeAcknowledged
+		status = MLRStatusBusinessRuleWarning
+	}
+	listID := ListIDMLRStatusReason
+
+	resp := Response{
+		ReferenceID: f.RuleID,
+		ResponseCode: &IDType{
+			Value: code,
+		},
+		Status: []Status{
+			{
+				StatusReasonCode: &IDType{
+					ListID: &listID,
+					Value:  status,
+				},
+			},
+		},
+	}
+	if msg := strings.TrimSpace(f.Message); msg != "" {
+		resp.Description = []string{msg}
+	}
+
+	return LineResponse{
+		LineReference: LineReference{LineID: location},
+		Response:      []Response{resp},
+	}
+}
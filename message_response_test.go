@@ -0,0 +1,100 @@
+package ubl_test
+
+import (
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessageLevelResponse(t *testing.T) {
+	data, err := testLoadXML("peppol/base-example.xml")
+	require.NoError(t, err)
+
+	t.Run("rejected with findings", func(t *testing.T) {
+		findings := []ubl.Finding{
+			{
+				RuleID:   "BR-CO-10",
+				Flag:     ubl.FlagFatal,
+				Location: "/*:Invoice[1]/cac:LegalMonetaryTotal[1]",
+				Message:  "Sum of Invoice line net amount = Σ Invoice line net amount.",
+			},
+			{
+				RuleID:   "PEPPOL-EN16931-R004",
+				Flag:     ubl.FlagWarning,
+				Location: "/*:Invoice[1]/cbc:ProfileID[1]",
+				Message:  "Specification identifier SHALL have the value 'urn:fdc:peppol.eu:2017:poacc:billing:01:1.0'.",
+			},
+			{
+				Flag:     ubl.FlagFatal,
+				Location: "/*:Invoice[1]/cbc:IssueDate[1]",
+				Message:  "Element 'IssueDate' is not a valid value of the atomic type 'xs:date'.",
+				Syntax:   true,
+			},
+		}
+		ar := ubl.NewMessageLevelResponse(data, findings)
+
+		assert.Equal(t, ubl.ContextPeppolMLR.CustomizationID, ar.CustomizationID)
+		assert.Equal(t, ubl.ContextPeppolMLR.ProfileID, ar.ProfileID)
+		require.NotNil(t, ar.SenderParty)
+		assert.Equal(t, "FR23342", ar.SenderParty.EndpointID.Value)
+		require.NotNil(t, ar.ReceiverParty)
+		assert.Equal(t, "9482348239847239874", ar.ReceiverParty.EndpointID.Value)
+
+		require.Len(t, ar.DocumentResponse, 1)
+		dr := ar.DocumentResponse[0]
+		assert.Equal(t, ubl.ResponseCodeRejected, dr.Response.ResponseCode.Value)
+		assert.Equal(t, "Snippet1", dr.DocumentReference[0].ID.Value)
+
+		require.Len(t, dr.LineResponse, 3)
+		lr := dr.LineResponse[0]
+		assert.Equal(t, "/*:Invoice[1]/cac:LegalMonetaryTotal[1]", lr.LineReference.LineID)
+		assert.Equal(t, "BR-CO-10", lr.Response[0].ReferenceID)
+		assert.Equal(t, ubl.ResponseCodeRejected, lr.Response[0].ResponseCode.Value)
+		assert.Equal(t, ubl.MLRStatusBusinessRuleFatal, lr.Response[0].Status[0].StatusReasonCode.Value)
+
+		lr = dr.LineResponse[1]
+		assert.Equal(t, ubl.ResponseCodeAcknowledged, lr.Response[0].ResponseCode.Value)
+		assert.Equal(t, ubl.MLRStatusBusinessRuleWarning, lr.Response[0].Status[0].StatusReasonCode.Value)
+
+		lr = dr.LineResponse[2]
+		assert.Equal(t, ubl.MLRStatusSyntaxViolation, lr.Response[0].Status[0].StatusReasonCode.Value)
+		assert.Empty(t, lr.Response[0].ReferenceID)
+	})
+
+	t.Run("warnings only", func(t *testing.T) {
+		ar := ubl.NewMessageLevelResponse(data, []ubl.Finding{
+			{RuleID: "BR-W-01", Flag: ubl.FlagWarning, Location: "/"},
+		})
+		assert.Equal(t, ubl.ResponseCodeAccepted, ar.DocumentResponse[0].Response.ResponseCode.Value)
+	})
+
+	t.Run("no findings", func(t *testing.T) {
+		ar := ubl.NewMessageLevelResponse(data, nil)
+		dr := ar.DocumentResponse[0]
+		assert.Equal(t, ubl.ResponseCodeAcknowledged, dr.Response.ResponseCode.Value)
+		assert.Empty(t, dr.LineResponse)
+	})
+
+	t.Run("unparsable document", func(t *testing.T) {
+		ar := ubl.NewMessageLevelResponse([]byte("<Invoice><cbc:ID>1</Invoice>"), nil)
+		dr := ar.DocumentResponse[0]
+		assert.Equal(t, ubl.ResponseCodeRejected, dr.Response.ResponseCode.Value)
+		assert.Equal(t, "NA", dr.DocumentReference[0].ID.Value)
+		require.Len(t, dr.LineResponse, 1)
+		assert.Equal(t, ubl.MLRStatusSyntaxViolation, dr.LineResponse[0].Response[0].Status[0].StatusReasonCode.Value)
+		assert.NotEmpty(t, dr.LineResponse[0].Response[0].Description)
+	})
+
+	t.Run("serializes", func(t *testing.T) {
+		ar := ubl.NewMessageLevelResponse(data, []ubl.Finding{
+			{RuleID: "BR-01", Flag: ubl.FlagFatal, Location: "/*:Invoice[1]", Message: "An Invoice shall have a Specification identifier."},
+		})
+		out, err := ubl.Bytes(ar)
+		require.NoError(t, err)
+		assert.Contains(t, string(out), "<cac:LineResponse>")
+		assert.Contains(t, string(out), "<cbc:LineID>/*:Invoice[1]</cbc:LineID>")
+		assert.Contains(t, string(out), `<cbc:StatusReasonCode listID="PEPPOLSTATUS">BV</cbc:StatusReasonCode>`)
+	})
+}
@@ -0,0 +1,426 @@
+package ubl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/catalogues/iso"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/l10n"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
+)
+
+// MyInvois identity types used in party identifications and item
+// classifications.
+const (
+	MyInvoisSchemeTIN      cbc.Code = "TIN"
+	MyInvoisSchemeBRN      cbc.Code = "BRN"
+	MyInvoisSchemeNRIC     cbc.Code = "NRIC"
+	MyInvoisSchemePassport cbc.Code = "PASSPORT"
+	MyInvoisSchemeArmy     cbc.Code = "ARMY"
+	MyInvoisSchemeSST      cbc.Code = "SST"
+	MyInvoisSchemeTTX      cbc.Code = "TTX"
+	MyInvoisSchemeMSIC     cbc.Code = "MSIC"
+	MyInvoisSchemeCLASS    cbc.Code = "CLASS"
+	MyInvoisSchemePTC      cbc.Code = "PTC"
+)
+
+// MyInvois general TINs used for parties without a Malaysian tax ID.
+const (
+	MyInvoisTINGeneralPublic   = "EI00000000010"
+	MyInvoisTINForeignBuyer    = "EI00000000020"
+	MyInvoisTINForeignSupplier = "EI00000000030"
+)
+
+// MyInvois invoice type codes. Self-billed documents add 10 to the
+// regular code.
+const (
+	MyInvoisTypeInvoice    = "01"
+	MyInvoisTypeCreditNote = "02"
+	MyInvoisTypeDebitNote  = "03"
+	MyInvoisTypeRefundNote = "04"
+)
+
+// myInvoisNotApplicable is used for mandatory identifications that the
+// party does not have.
+const myInvoisNotApplicable = "NA"
+
+// myInvoisVersion is the document version expected in the list version
+// of the invoice type code. Version 1.0 does not require a signature.
+const myInvoisVersion = "1.0"
+
+// myInvoisLocation is the Malaysian time zone, which is used for GOBL
+// issue dates while MyInvois expects them in UTC.
+var myInvoisLocation = time.FixedZone("MYT", 8*60*60)
+
+var myInvoisTypeCodes = []string{"01", "02", "03", "04", "11", "12", "13", "14"}
+
+var myInvoisPartySchemes = []cbc.Code{
+	MyInvoisSchemeBRN,
+	MyInvoisSchemeNRIC,
+	MyInvoisSchemePassport,
+	MyInvoisSchemeArmy,
+}
+
+// ContextMyInvois defines the context for Malaysian MyInvois UBL 2.1
+// documents. MyInvois documents do not include a CustomizationID or
+// ProfileID, so the CustomizationID is only used to identify the context,
+// and incoming documents are detected from their type code instead.
+var ContextMyInvois = Context{
+	CustomizationID: "MyInvois-1.0",
+	Output: OutputRules{
+		LineTaxTotals: true,
+	},
+	Hooks: myInvoisHooks{},
+}
+
+// myInvoisHooks adapts documents to the MyInvois requirements, which
+// differ considerably from EN16931.
+type myInvoisHooks struct {
+	BaseHooks
+}
+
+// Detect checks for documents without a CustomizationID that use one of
+// the MyInvois type codes.
+func (myInvoisHooks) Detect(in *Invoice) bool {
+	if in.CustomizationID != "" || in.InvoiceTypeCode == "" {
+		return false
+	}
+	for _, c := range myInvoisTypeCodes {
+		if in.InvoiceTypeCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+// BeforeConvert ensures the invoice has a document type so that the
+// regular conversion can take place, as MyInvois invoices will not usually
+// use the EN16931 addon.
+func (myInvoisHooks) BeforeConvert(inv *bill.Invoice) error {
+	setDocumentType(inv)
+	return nil
+}
+
+// AfterConvert applies the MyInvois mapping rules to the generated
+// document.
+func (myInvoisHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	tc, err := myInvoisTypeCode(inv)
+	if err != nil {
+		return err
+	}
+	out.CustomizationID = ""
+	out.ProfileID = ""
+
+	// Credit notes are also issued as Invoice documents.
+	out.useInvoiceRoot(tc)
+	v := myInvoisVersion
+	out.TypeCodeAttributes = &Attributes{ListVersionID: &v}
+
+	out.IssueDate, out.IssueTime = myInvoisIssueDateTime(inv.IssueDate, inv.IssueTime)
+
+	if inv.Supplier != nil && out.AccountingSupplierParty.Party != nil {
+		p := out.AccountingSupplierParty.Party
+		applyMyInvoisParty(inv.Supplier, p, MyInvoisTINForeignSupplier)
+		if id := myInvoisIdentity(inv.Supplier.Identities, MyInvoisSchemeMSIC); id != nil {
+			p.IndustryClassificationCode = &IDType{Value: id.Code.String()}
+			if id.Description != "" {
+				p.IndustryClassificationCode.Name = &id.Description
+			}
+		}
+	}
+	if inv.Customer != nil && out.AccountingCustomerParty.Party != nil {
+		applyMyInvoisParty(inv.Customer, out.AccountingCustomerParty.Party, MyInvoisTINForeignBuyer)
+	}
+
+	for i, l := range inv.Lines {
+		if i >= len(out.InvoiceLines) || l.Item == nil || out.InvoiceLines[i].Item == nil {
+			continue
+		}
+		applyMyInvoisItem(l.Item, out.InvoiceLines[i].Item)
+	}
+	return nil
+}
+
+// BeforeParse does nothing.
+func (myInvoisHooks) BeforeParse(_ *Invoice) error { return nil }
+
+// AfterParse maps the MyInvois specific fields back into the invoice.
+// The regime is left undefined, as GOBL does not include a Malaysian one.
+func (myInvoisHooks) AfterParse(in *Invoice, inv *bill.Invoice) error {
+	code := in.typeCode()
+	if n, err := strconv.Atoi(code); err == nil && n > 10 {
+		inv.SetTags(tax.TagSelfBilled)
+		code = fmt.Sprintf("%02d", n-10)
+	}
+	switch code {
+	case MyInvoisTypeCreditNote:
+		inv.Type = bill.InvoiceTypeCreditNote
+	case MyInvoisTypeDebitNote:
+		inv.Type = bill.InvoiceTypeDebitNote
+	case MyInvoisTypeRefundNote:
+		inv.Type = bill.InvoiceTypeCreditNote
+		if inv.Meta == nil {
+			inv.Meta = make(cbc.Meta)
+		}
+		inv.Meta[cbc.Key("refund")] = "true"
+	default:
+		inv.Type = bill.InvoiceTypeStandard
+	}
+
+	if err := parseMyInvoisIssueDateTime(in, inv); err != nil {
+		return err
+	}
+
+	if inv.Supplier != nil {
+		p := in.AccountingSupplierParty.Party
+		parseMyInvoisParty(p, inv.Supplier)
+		if p != nil && p.IndustryClassificationCode != nil {
+			id := &org.Identity{
+				Type: MyInvoisSchemeMSIC,
+				Code: cbc.Code(p.IndustryClassificationCode.Value),
+			}
+			if p.IndustryClassificationCode.Name != nil {
+				id.Description = *p.IndustryClassificationCode.Name
+			}
+			inv.Supplier.Identities = append(inv.Supplier.Identities, id)
+		}
+	}
+	if inv.Customer != nil {
+		parseMyInvoisParty(in.AccountingCustomerParty.Party, inv.Customer)
+	}
+
+	for _, l := range inv.Lines {
+		if l.Item == nil {
+			continue
+		}
+		for _, id := range l.Item.Identities {
+			if c := cbc.Code(id.Label); c.In(MyInvoisSchemeCLASS, MyInvoisSchemePTC) {
+				id.Type = c
+				id.Label = ""
+			}
+		}
+	}
+	return nil
+}
+
+// myInvoisTypeCode determines the MyInvois type code for the invoice.
+func myInvoisTypeCode(inv *bill.Invoice) (string, error) {
+	var code int
+	switch inv.Type {
+	case bill.InvoiceTypeStandard:
+		code = 1
+	case bill.InvoiceTypeCreditNote:
+		code = 2
+		if inv.Meta != nil && inv.Meta[cbc.Key("refund")] == "true" {
+			code = 4
+		}
+	case bill.InvoiceTypeDebitNote:
+		code = 3
+	default:
+		return "", fmt.Errorf("myinvois: unsupported invoice type %q", inv.Type)
+	}
+	if inv.HasTags(tax.TagSelfBilled) {
+		code += 10
+	}
+	return fmt.Sprintf("%02d", code), nil
+}
+
+// myInvoisIssueDateTime converts the issue date and time from Malaysian
+// time into UTC, as required by MyInvois.
+func myInvoisIssueDateTime(date cal.Date, tm *cal.Time) (string, string) {
+	if date.IsZero() {
+		return "", ""
+	}
+	if tm == nil {
+		return formatDate(date), "00:00:00Z"
+	}
+	t := time.Date(date.Year, date.Month, date.Day, tm.Hour, tm.Minute, tm.Second, 0, myInvoisLocation).UTC()
+	return t.Format("2006-01-02"), t.Format("15:04:05") + "Z"
+}
+
+// parseMyInvoisIssueDateTime converts the UTC issue date and time back
+// into Malaysian time.
+func parseMyInvoisIssueDateTime(in *Invoice, inv *bill.Invoice) error {
+	if in.IssueTime == "" {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02 15:04:05Z07:00", in.IssueDate+" "+in.IssueTime)
+	if err != nil {
+		return fmt.Errorf("myinvois: parsing issue time: %w", err)
+	}
+	t = t.In(myInvoisLocation)
+	inv.IssueDate = cal.MakeDate(t.Year(), t.Month(), t.Day())
+	inv.IssueTime = cal.NewTime(t.Hour(), t.Minute(), t.Second())
+	return nil
+}
+
+// applyMyInvoisParty replaces the party's identification details with the
+// schemes required by MyInvois. The foreign TIN is used for parties from
+// other countries without a Malaysian tax ID.
+func applyMyInvoisParty(party *org.Party, p *Party, foreignTIN string) {
+	country := myInvoisPartyCountry(party)
+	tin := MyInvoisTINGeneralPublic
+	if country != "" && country != "MY" {
+		tin = foreignTIN
+	}
+	if party.TaxID != nil && party.TaxID.Country == "MY" && party.TaxID.Code != "" {
+		tin = party.TaxID.Code.String()
+	}
+	ids := []Identification{myInvoisIdentification(MyInvoisSchemeTIN, tin)}
+
+	found := false
+	for _, s := range myInvoisPartySchemes {
+		if id := myInvoisIdentity(party.Identities, s); id != nil {
+			ids = append(ids, myInvoisIdentification(s, id.Code.String()))
+			found = true
+		}
+	}
+	if !found {
+		ids = append(ids, myInvoisIdentification(MyInvoisSchemeBRN, myInvoisNotApplicable))
+	}
+
+	sst := myInvoisNotApplicable
+	if id := myInvoisIdentity(party.Identities, MyInvoisSchemeSST); id != nil {
+		sst = id.Code.String()
+	}
+	ids = append(ids, myInvoisIdentification(MyInvoisSchemeSST, sst))
+	if id := myInvoisIdentity(party.Identities, MyInvoisSchemeTTX); id != nil {
+		ids = append(ids, myInvoisIdentification(MyInvoisSchemeTTX, id.Code.String()))
+	}
+
+	p.PartyIdentification = ids
+	p.PartyTaxScheme = nil
+	if p.PartyLegalEntity != nil {
+		p.PartyLegalEntity.CompanyID = nil
+	}
+
+	if a := p.PostalAddress; a != nil {
+		// MyInvois only supports address lines for the street.
+		var lines []AddressLine
+		for _, s := range []*string{a.StreetName, a.AdditionalStreetName} {
+			if s != nil {
+				lines = append(lines, AddressLine{Line: *s})
+			}
+		}
+		a.AddressLine = append(lines, a.AddressLine...)
+		a.StreetName = nil
+		a.AdditionalStreetName = nil
+		if len(party.Addresses) > 0 && party.Addresses[0].State != "" {
+			s := party.Addresses[0].State.String()
+			a.CountrySubentityCode = &s
+		}
+		if a.Country != nil {
+			if c := l10n.ISOCountryCode(a.Country.IdentificationCode).Alpha3(); c != "" {
+				a.Country.IdentificationCode = c
+			}
+		}
+	}
+}
+
+// parseMyInvoisParty maps the MyInvois party identifications back into
+// the GOBL party's tax ID and identities.
+func parseMyInvoisParty(p *Party, party *org.Party) {
+	if p == nil {
+		return
+	}
+	var ids []*org.Identity
+	for _, id := range party.Identities {
+		s := id.Ext.Get(iso.ExtKeySchemeID)
+		switch {
+		case s == cbc.CodeEmpty:
+			ids = append(ids, id)
+		case id.Code == myInvoisNotApplicable:
+			continue
+		case s == MyInvoisSchemeTIN:
+			if !strings.HasPrefix(id.Code.String(), "EI") {
+				party.TaxID = &tax.Identity{Country: "MY", Code: id.Code}
+			}
+		default:
+			id.Type = s
+			id.Ext = nil
+			ids = append(ids, id)
+		}
+	}
+	party.Identities = ids
+
+	if a := p.PostalAddress; a != nil && len(party.Addresses) > 0 {
+		addr := party.Addresses[0]
+		if addr.Street == "" && len(a.AddressLine) > 0 {
+			addr.Street = cleanString(a.AddressLine[0].Line)
+			if len(a.AddressLine) > 1 {
+				addr.StreetExtra = cleanString(a.AddressLine[1].Line)
+			}
+		}
+		if a.CountrySubentityCode != nil {
+			addr.State = cbc.Code(cleanString(*a.CountrySubentityCode))
+		}
+		if c := myInvoisCountryAlpha2(addr.Country.String()); c != "" {
+			addr.Country = c
+		}
+	}
+}
+
+// applyMyInvoisItem adds the item's classification and product tariff
+// codes as commodity classifications.
+func applyMyInvoisItem(item *org.Item, it *Item) {
+	var cls []CommodityClassification
+	for _, id := range item.Identities {
+		if !id.Type.In(MyInvoisSchemeCLASS, MyInvoisSchemePTC) {
+			continue
+		}
+		listID := id.Type.String()
+		cls = append(cls, CommodityClassification{
+			ItemClassificationCode: &IDType{ListID: &listID, Value: id.Code.String()},
+		})
+		if b := it.BuyersItemIdentification; b != nil && b.ID != nil && b.ID.Value == id.Code.String() {
+			it.BuyersItemIdentification = nil
+		}
+	}
+	if len(cls) > 0 {
+		it.CommodityClassification = &cls
+	}
+}
+
+func myInvoisPartyCountry(party *org.Party) l10n.ISOCountryCode {
+	if party.TaxID != nil && party.TaxID.Country != "" {
+		return l10n.ISOCountryCode(party.TaxID.Country)
+	}
+	if len(party.Addresses) > 0 {
+		return party.Addresses[0].Country
+	}
+	return ""
+}
+
+func myInvoisIdentity(ids []*org.Identity, typ cbc.Code) *org.Identity {
+	for _, id := range ids {
+		if id.Type == typ {
+			return id
+		}
+	}
+	return nil
+}
+
+func myInvoisIdentification(scheme cbc.Code, code string) Identification {
+	s := scheme.String()
+	return Identification{ID: &IDType{SchemeID: &s, Value: code}}
+}
+
+// myInvoisCountryAlpha2 looks up the ISO alpha-2 code for an alpha-3
+// country code, or returns an empty code if there is no match.
+func myInvoisCountryAlpha2(code string) l10n.ISOCountryCode {
+	if len(code) != 3 {
+		return ""
+	}
+	for _, c := range l10n.Countries().ISO() {
+		if c.Alpha3 == code {
+			return l10n.ISOCountryCode(c.Code)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package ubl
+
+import (
+	"errors"
+
+	"github.com/invopop/gobl/addons/eu/en16931"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/l10n"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/validation"
+)
+
+// ContextNLCIUS defines the context for the Dutch NLCIUS (SI-UBL 2.0)
+// documents required by Dutch government buyers.
+var ContextNLCIUS = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:fdc:nen.nl:nlcius:v1.0",
+	ProfileID:       PeppolBillingProfileIDDefault,
+	Addons:          []cbc.Key{en16931.V2017},
+	Hooks:           nlciusHooks{},
+}
+
+// ContextNLCIUSExtended defines the context for the NLCIUS extension with
+// G-account payments, used for Dutch payroll and turnover tax deposits.
+var ContextNLCIUSExtended = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#conformant#urn:fdc:nen.nl:gaccount:v1.0",
+	ProfileID:       PeppolBillingProfileIDDefault,
+	Addons:          []cbc.Key{en16931.V2017},
+	Hooks:           nlciusHooks{},
+}
+
+// nlIdentitySchemes maps the Dutch KvK and OIN numbers to their ISO 6523
+// ICD schemes.
+var nlIdentitySchemes = identitySchemes{
+	IdentityTypeKVK: "0106",
+	IdentityTypeOIN: "0190",
+}
+
+// nlciusHooks checks the additional NLCIUS requirements before the
+// invoice is converted, and identifies the parties' legal entities by
+// their KvK or OIN numbers.
+type nlciusHooks struct {
+	BaseHooks
+}
+
+// BeforeConvert ensures the invoice has a buyer reference and payment
+// means, and that Dutch parties are identified by their KvK or OIN
+// numbers.
+func (nlciusHooks) BeforeConvert(inv *bill.Invoice) error {
+	errs := validation.Errors{}
+	if inv.Ordering == nil || inv.Ordering.Code == cbc.CodeEmpty {
+		errs["ordering"] = validation.Errors{
+			"code": errors.New("required"),
+		}
+	}
+	if inv.Payment == nil || inv.Payment.Instructions == nil {
+		errs["payment"] = validation.Errors{
+			"instructions": errors.New("required"),
+		}
+	}
+	if !nlciusLegalEntity(inv.Supplier) {
+		errs["supplier"] = validation.Errors{
+			"identities": errors.New("KvK or OIN number required"),
+		}
+	}
+	if !nlciusLegalEntity(inv.Customer) {
+		errs["customer"] = validation.Errors{
+			"identities": errors.New("KvK or OIN number required"),
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// AfterConvert uses the KvK or OIN numbers of the parties as their legal
+// entities.
+func (nlciusHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	nlIdentitySchemes.apply(inv.Supplier, out.AccountingSupplierParty.Party)
+	nlIdentitySchemes.apply(inv.Customer, out.AccountingCustomerParty.Party)
+	return nil
+}
+
+// AfterParse sets the KvK and OIN identity types of the parties' legal
+// entities.
+func (nlciusHooks) AfterParse(_ *Invoice, inv *bill.Invoice) error {
+	nlIdentitySchemes.goblTypes(inv.Supplier)
+	nlIdentitySchemes.goblTypes(inv.Customer)
+	return nil
+}
+
+// nlciusLegalEntity checks if a party, when Dutch, includes a legal
+// entity identity with the KvK or OIN scheme.
+func nlciusLegalEntity(p *org.Party) bool {
+	if p == nil || p.TaxID == nil || p.TaxID.Country != l10n.NL.Tax() {
+		return true
+	}
+	id := nlIdentitySchemes.legalIdentity(p)
+	if id == nil {
+		return false
+	}
+	return nlIdentitySchemes.hasScheme(nlIdentitySchemes.schemeID(id))
+}
@@ -0,0 +1,109 @@
+package ubl
+
+import (
+	"github.com/invopop/gobl/addons/eu/en16931"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/l10n"
+)
+
+// ContextPeppolNO defines the Peppol BIS Billing 3.0 context with the
+// Norwegian (EHF) national rules, which require suppliers registered in
+// the Register of Business Enterprises to state "Foretaksregisteret".
+var ContextPeppolNO = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0",
+	ProfileID:       PeppolBillingProfileIDDefault,
+	Addons:          []cbc.Key{en16931.V2017},
+	VESIDs: VESIDMapping{
+		Invoice:    "eu.peppol.bis3:invoice:2025.5",
+		CreditNote: "eu.peppol.bis3:creditnote:2025.5",
+	},
+	Output: OutputRules{
+		ConcatenateNotes: true,
+	},
+	Hooks: taxRegistrationHooks{
+		country: l10n.NO,
+		schemes: identitySchemes{IdentityTypeNOOrgNr: "0192"},
+		text:    "Foretaksregisteret",
+	},
+}
+
+// ContextPeppolSE defines the Peppol BIS Billing 3.0 context with the
+// Swedish national rules, which require suppliers to state their F-tax
+// approval.
+var ContextPeppolSE = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0",
+	ProfileID:       PeppolBillingProfileIDDefault,
+	Addons:          []cbc.Key{en16931.V2017},
+	VESIDs: VESIDMapping{
+		Invoice:    "eu.peppol.bis3:invoice:2025.5",
+		CreditNote: "eu.peppol.bis3:creditnote:2025.5",
+	},
+	Output: OutputRules{
+		ConcatenateNotes: true,
+	},
+	Hooks: taxRegistrationHooks{
+		country: l10n.SE,
+		schemes: identitySchemes{IdentityTypeSEOrgNr: "0007"},
+		text:    "Godkänd för F-skatt",
+	},
+}
+
+// nordicMetaTaxRegistration is the supplier meta key used for the
+// seller's tax registration text.
+const nordicMetaTaxRegistration cbc.Key = "tax-registration"
+
+// taxRegistrationHooks identifies the parties' legal entities by their
+// organisation numbers, and adds the seller's tax registration text to
+// suppliers identified with them.
+type taxRegistrationHooks struct {
+	BaseHooks
+	country l10n.Code
+	schemes identitySchemes
+	text    string
+}
+
+// Detect checks for Peppol BIS Billing documents issued by suppliers from
+// the context's country.
+func (h taxRegistrationHooks) Detect(in *Invoice) bool {
+	if in.CustomizationID != ContextPeppol.CustomizationID {
+		return false
+	}
+	p := in.AccountingSupplierParty.Party
+	return p != nil && p.CountryCode() == h.country.String()
+}
+
+// AfterConvert uses the organisation numbers as the parties' legal
+// entities and adds the tax registration party tax scheme, with the text
+// from the supplier's meta or, when identified by their organisation
+// number, the context's, unless the supplier already includes one.
+func (h taxRegistrationHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	h.schemes.apply(inv.Supplier, out.AccountingSupplierParty.Party)
+	h.schemes.apply(inv.Customer, out.AccountingCustomerParty.Party)
+
+	p := out.AccountingSupplierParty.Party
+	if p == nil || inv.Supplier == nil {
+		return nil
+	}
+	text := inv.Supplier.Meta[nordicMetaTaxRegistration]
+	if text == "" {
+		if p.PartyLegalEntity == nil || p.PartyLegalEntity.CompanyID == nil {
+			return nil
+		}
+		if s := p.PartyLegalEntity.CompanyID.SchemeID; s == nil || !h.schemes.hasScheme(*s) {
+			return nil
+		}
+		text = h.text
+	}
+	p.addTaxRegistration(text)
+	return nil
+}
+
+// AfterParse sets the organisation number identity type of the parties'
+// legal entities. The seller's tax registration text is kept in a supplier
+// tax note, as in any other context.
+func (h taxRegistrationHooks) AfterParse(_ *Invoice, inv *bill.Invoice) error {
+	h.schemes.goblTypes(inv.Supplier)
+	h.schemes.goblTypes(inv.Customer)
+	return nil
+}
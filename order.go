@@ -0,0 +1,318 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/invopop/gobl"
+	"github.com/invopop/gobl/bill"
+)
+
+// Main UBL Order Namespace
+const (
+	NamespaceUBLOrder = "urn:oasis:names:specification:ubl:schema:xsd:Order-2"
+)
+
+// Schema location for the order document
+const (
+	SchemaLocationOrder = "urn:oasis:names:specification:ubl:schema:xsd:Order-2 http://docs.oasis-open.org/ubl/os-UBL-2.1/xsd/maindoc/UBL-Order-2.1.xsd"
+)
+
+// OrderTypeCodeDefault is the UNTDID 1001 code used for regular purchase
+// orders.
+const OrderTypeCodeDefault = "220"
+
+// Order represents the root element of a UBL Order document.
+type Order struct {
+	// Attributes
+	XMLName        xml.Name
+	CACNamespace   string `xml:"xmlns:cac,attr"`
+	CBCNamespace   string `xml:"xmlns:cbc,attr"`
+	EXTNamespace   string `xml:"xmlns:ext,attr,omitempty"`
+	QDTNamespace   string `xml:"xmlns:qdt,attr"`
+	UDTNamespace   string `xml:"xmlns:udt,attr"`
+	CCTSNamespace  string `xml:"xmlns:ccts,attr"`
+	UBLNamespace   string `xml:"xmlns,attr"`
+	XSINamespace   string `xml:"xmlns:xsi,attr"`
+	SchemaLocation string `xml:"xsi:schemaLocation,attr"`
+
+	UBLExtensions      *Extensions `xml:"ext:UBLExtensions,omitempty"`
+	UBLVersionID       string      `xml:"cbc:UBLVersionID,omitempty"`
+	CustomizationID    string      `xml:"cbc:CustomizationID,omitempty"`
+	ProfileID          string      `xml:"cbc:ProfileID,omitempty"`
+	ProfileExecutionID string      `xml:"cbc:ProfileExecutionID,omitempty"`
+	ID                 string      `xml:"cbc:ID"`
+	SalesOrderID       string      `xml:"cbc:SalesOrderID,omitempty"`
+	CopyIndicator      bool        `xml:"cbc:CopyIndicator,omitempty"`
+	UUID               string      `xml:"cbc:UUID,omitempty"`
+	IssueDate          string      `xml:"cbc:IssueDate"`
+	IssueTime          string      `xml:"cbc:IssueTime,omitempty"`
+	OrderTypeCode      string      `xml:"cbc:OrderTypeCode,omitempty"`
+
+	Note                         []string           `xml:"cbc:Note,omitempty"`
+	RequestedInvoiceCurrencyCode string             `xml:"cbc:RequestedInvoiceCurrencyCode,omitempty"`
+	DocumentCurrencyCode         string             `xml:"cbc:DocumentCurrencyCode,omitempty"`
+	PricingCurrencyCode          string             `xml:"cbc:PricingCurrencyCode,omitempty"`
+	TaxCurrencyCode              string             `xml:"cbc:TaxCurrencyCode,omitempty"`
+	CustomerReference            string             `xml:"cbc:CustomerReference,omitempty"`
+	AccountingCostCode           string             `xml:"cbc:AccountingCostCode,omitempty"`
+	AccountingCost               string             `xml:"cbc:AccountingCost,omitempty"`
+	LineCountNumeric             int                `xml:"cbc:LineCountNumeric,omitempty"`
+	ValidityPeriod               []Period           `xml:"cac:ValidityPeriod,omitempty"`
+	QuotationDocumentReference   *Reference         `xml:"cac:QuotationDocumentReference,omitempty"`
+	OrderDocumentReference       []Reference        `xml:"cac:OrderDocumentReference,omitempty"`
+	OriginatorDocumentReference  *Reference         `xml:"cac:OriginatorDocumentReference,omitempty"`
+	CatalogueReference           *Reference         `xml:"cac:CatalogueReference,omitempty"`
+	AdditionalDocumentReference  []Reference        `xml:"cac:AdditionalDocumentReference,omitempty"`
+	Contract                     []Contract         `xml:"cac:Contract,omitempty"`
+	ProjectReference             []ProjectReference `xml:"cac:ProjectReference,omitempty"`
+	Signature                    []Signature        `xml:"cac:Signature,omitempty"`
+	BuyerCustomerParty           CustomerParty      `xml:"cac:BuyerCustomerParty"`
+	SellerSupplierParty          SupplierParty      `xml:"cac:SellerSupplierParty"`
+	OriginatorCustomerParty      *CustomerParty     `xml:"cac:OriginatorCustomerParty,omitempty"`
+	FreightForwarderParty        *Party             `xml:"cac:FreightForwarderParty,omitempty"`
+	AccountingCustomerParty      *CustomerParty     `xml:"cac:AccountingCustomerParty,omitempty"`
+	Delivery                     []*Delivery        `xml:"cac:Delivery,omitempty"`
+	DeliveryTerms                []DeliveryTerms    `xml:"cac:DeliveryTerms,omitempty"`
+	PaymentMeans                 []PaymentMeans     `xml:"cac:PaymentMeans,omitempty"`
+	PaymentTerms                 []PaymentTerms     `xml:"cac:PaymentTerms,omitempty"`
+	TransactionConditions        *Conditions        `xml:"cac:TransactionConditions,omitempty"`
+	AllowanceCharge              []AllowanceCharge  `xml:"cac:AllowanceCharge,omitempty"`
+	TaxExchangeRate              *ExchangeRate      `xml:"cac:TaxExchangeRate,omitempty"`
+	PricingExchangeRate          *ExchangeRate      `xml:"cac:PricingExchangeRate,omitempty"`
+	PaymentExchangeRate          *ExchangeRate      `xml:"cac:PaymentExchangeRate,omitempty"`
+	DestinationCountry           *Country           `xml:"cac:DestinationCountry,omitempty"`
+	TaxTotal                     []TaxTotal         `xml:"cac:TaxTotal,omitempty"`
+	AnticipatedMonetaryTotal     *MonetaryTotal     `xml:"cac:AnticipatedMonetaryTotal,omitempty"`
+	OrderLines                   []OrderLine        `xml:"cac:OrderLine"`
+}
+
+// Contract represents a reference to a contract
+type Contract struct {
+	ID               IDType  `xml:"cbc:ID"`
+	IssueDate        string  `xml:"cbc:IssueDate,omitempty"`
+	ContractTypeCode string  `xml:"cbc:ContractTypeCode,omitempty"`
+	ContractType     string  `xml:"cbc:ContractType,omitempty"`
+	ValidityPeriod   *Period `xml:"cac:ValidityPeriod,omitempty"`
+}
+
+// Conditions represents the transaction conditions of an order
+type Conditions struct {
+	ID          string   `xml:"cbc:ID,omitempty"`
+	ActionCode  string   `xml:"cbc:ActionCode,omitempty"`
+	Description []string `xml:"cbc:Description,omitempty"`
+}
+
+// OrderLine represents a line in an order
+type OrderLine struct {
+	Note                             []string             `xml:"cbc:Note,omitempty"`
+	LineItem                         LineItem             `xml:"cac:LineItem"`
+	SellerProposedSubstituteLineItem []LineItem           `xml:"cac:SellerProposedSubstituteLineItem,omitempty"`
+	SellerSubstitutedLineItem        []LineItem           `xml:"cac:SellerSubstitutedLineItem,omitempty"`
+	BuyerProposedSubstituteLineItem  []LineItem           `xml:"cac:BuyerProposedSubstituteLineItem,omitempty"`
+	CatalogueLineReference           *LineReference       `xml:"cac:CatalogueLineReference,omitempty"`
+	QuotationLineReference           *LineReference       `xml:"cac:QuotationLineReference,omitempty"`
+	OrderLineReference               []OrderLineReference `xml:"cac:OrderLineReference,omitempty"`
+	DocumentReference                []Reference          `xml:"cac:DocumentReference,omitempty"`
+}
+
+// LineItem represents the ordered item details of an order line
+type LineItem struct {
+	ID                        string             `xml:"cbc:ID"`
+	SalesOrderID              string             `xml:"cbc:SalesOrderID,omitempty"`
+	UUID                      string             `xml:"cbc:UUID,omitempty"`
+	Note                      []string           `xml:"cbc:Note,omitempty"`
+	LineStatusCode            string             `xml:"cbc:LineStatusCode,omitempty"`
+	Quantity                  *Quantity          `xml:"cbc:Quantity,omitempty"`
+	LineExtensionAmount       *Amount            `xml:"cbc:LineExtensionAmount,omitempty"`
+	TotalTaxAmount            *Amount            `xml:"cbc:TotalTaxAmount,omitempty"`
+	MinimumQuantity           *Quantity          `xml:"cbc:MinimumQuantity,omitempty"`
+	MaximumQuantity           *Quantity          `xml:"cbc:MaximumQuantity,omitempty"`
+	MinimumBackorderQuantity  *Quantity          `xml:"cbc:MinimumBackorderQuantity,omitempty"`
+	MaximumBackorderQuantity  *Quantity          `xml:"cbc:MaximumBackorderQuantity,omitempty"`
+	InspectionMethodCode      string             `xml:"cbc:InspectionMethodCode,omitempty"`
+	PartialDeliveryIndicator  *bool              `xml:"cbc:PartialDeliveryIndicator,omitempty"`
+	BackOrderAllowedIndicator *bool              `xml:"cbc:BackOrderAllowedIndicator,omitempty"`
+	AccountingCostCode        string             `xml:"cbc:AccountingCostCode,omitempty"`
+	AccountingCost            string             `xml:"cbc:AccountingCost,omitempty"`
+	WarrantyInformation       []string           `xml:"cbc:WarrantyInformation,omitempty"`
+	Delivery                  []*Delivery        `xml:"cac:Delivery,omitempty"`
+	DeliveryTerms             *DeliveryTerms     `xml:"cac:DeliveryTerms,omitempty"`
+	OriginatorParty           *Party             `xml:"cac:OriginatorParty,omitempty"`
+	AllowanceCharge           []*AllowanceCharge `xml:"cac:AllowanceCharge,omitempty"`
+	Price                     *Price             `xml:"cac:Price,omitempty"`
+	Item                      *Item              `xml:"cac:Item"`
+	SubLineItem               []LineItem         `xml:"cac:SubLineItem,omitempty"`
+	WarrantyValidityPeriod    *Period            `xml:"cac:WarrantyValidityPeriod,omitempty"`
+	WarrantyParty             *Party             `xml:"cac:WarrantyParty,omitempty"`
+	TaxTotal                  []TaxTotal         `xml:"cac:TaxTotal,omitempty"`
+	LineReference             []LineReference    `xml:"cac:LineReference,omitempty"`
+}
+
+// LineReference represents a reference to a line in another document
+type LineReference struct {
+	LineID            string     `xml:"cbc:LineID"`
+	UUID              string     `xml:"cbc:UUID,omitempty"`
+	LineStatusCode    string     `xml:"cbc:LineStatusCode,omitempty"`
+	DocumentReference *Reference `xml:"cac:DocumentReference,omitempty"`
+}
+
+func ublOrder(ord *bill.Order, o *options) (*Order, error) {
+	customizationID := o.context.CustomizationID
+	if o.context.OutputCustomizationID != "" {
+		customizationID = o.context.OutputCustomizationID
+	}
+
+	out := &Order{
+		XMLName:              xml.Name{Local: "Order"},
+		CACNamespace:         NamespaceCAC,
+		CBCNamespace:         NamespaceCBC,
+		EXTNamespace:         NamespaceEXT,
+		QDTNamespace:         NamespaceQDT,
+		UDTNamespace:         NamespaceUDT,
+		UBLNamespace:         NamespaceUBLOrder,
+		CCTSNamespace:        NamespaceCCTS,
+		XSINamespace:         NamespaceXSI,
+		SchemaLocation:       SchemaLocationOrder,
+		CustomizationID:      customizationID,
+		ProfileID:            o.context.ProfileID,
+		ID:                   invoiceNumber(ord.Series, ord.Code),
+		IssueDate:            formatDate(ord.IssueDate),
+		OrderTypeCode:        OrderTypeCodeDefault,
+		DocumentCurrencyCode: string(ord.Currency),
+		BuyerCustomerParty:   CustomerParty{Party: newParty(ord.Customer)},
+		SellerSupplierParty:  SupplierParty{Party: newParty(ord.Supplier)},
+	}
+
+	if ord.IssueTime != nil && !ord.IssueTime.IsZero() {
+		out.IssueTime = ord.IssueTime.String()
+	}
+
+	if ord.Buyer != nil {
+		out.AccountingCustomerParty = &CustomerParty{Party: newParty(ord.Buyer)}
+	}
+
+	for _, note := range ord.Notes {
+		out.Note = append(out.Note, note.Text)
+	}
+
+	if ord.Period != nil {
+		out.ValidityPeriod = []Period{
+			{
+				StartDate: formatDate(ord.Period.Start),
+				EndDate:   formatDate(ord.Period.End),
+			},
+		}
+	}
+
+	for _, ref := range ord.Preceding {
+		r := Reference{
+			ID: IDType{Value: ref.Series.Join(ref.Code).String()},
+		}
+		if ref.IssueDate != nil {
+			r.IssueDate = ref.IssueDate.String()
+		}
+		// Orders are usually preceded by the quote they accept, of which
+		// there may only be one, while any others are previous orders.
+		if out.QuotationDocumentReference == nil && (ref.Type == "" || ref.Type == bill.OrderTypeQuote) {
+			out.QuotationDocumentReference = &r
+			continue
+		}
+		out.OrderDocumentReference = append(out.OrderDocumentReference, r)
+	}
+
+	for _, contract := range ord.Contracts {
+		out.Contract = append(out.Contract, Contract{
+			ID: IDType{Value: contract.Code.String()},
+		})
+	}
+
+	out.AdditionalDocumentReference = newAttachmentReferences(ord.Attachments)
+
+	if d := newDelivery(ord.Delivery); d != nil {
+		if ord.Delivery.Period != nil {
+			d.RequestedDeliveryPeriod = &Period{
+				StartDate: formatDate(ord.Delivery.Period.Start),
+				EndDate:   formatDate(ord.Delivery.Period.End),
+			}
+		}
+		out.Delivery = []*Delivery{d}
+	}
+
+	if ord.Payment != nil && ord.Payment.Terms != nil && ord.Payment.Terms.Notes != "" {
+		out.PaymentTerms = []PaymentTerms{
+			{Note: []string{ord.Payment.Terms.Notes}},
+		}
+	}
+
+	ccy := ord.Currency.String()
+	if ord.Totals != nil {
+		for _, ch := range ord.Charges {
+			out.AllowanceCharge = append(out.AllowanceCharge, makeCharge(ch, ccy, ord.Totals.Sum))
+		}
+		for _, d := range ord.Discounts {
+			out.AllowanceCharge = append(out.AllowanceCharge, makeDiscount(d, ccy, ord.Totals.Sum))
+		}
+		out.TaxTotal = []TaxTotal{
+			{
+				TaxAmount: Amount{Value: ord.Totals.Tax.String(), CurrencyID: &ccy},
+			},
+		}
+		mt := newMonetaryTotal(ord.Totals, ccy)
+		out.AnticipatedMonetaryTotal = &mt
+	}
+
+	for _, l := range ord.Lines {
+		out.OrderLines = append(out.OrderLines, newOrderLine(l, ccy))
+	}
+
+	return out, nil
+}
+
+func newOrderLine(l *bill.Line, ccy string) OrderLine {
+	li := LineItem{
+		ID: strconv.Itoa(l.Index),
+		Quantity: &Quantity{
+			Value: l.Quantity.String(),
+		},
+	}
+	if l.Item != nil && l.Item.Unit != "" {
+		li.Quantity.UnitCode = string(l.Item.Unit.UNECE())
+	}
+	if l.Total != nil {
+		li.LineExtensionAmount = &Amount{
+			CurrencyID: &ccy,
+			Value:      l.Total.String(),
+		}
+	}
+	if l.Cost != "" {
+		li.AccountingCost = l.Cost.String()
+	}
+	for _, note := range l.Notes {
+		li.Note = append(li.Note, note.Text)
+	}
+	if len(l.Charges) > 0 || len(l.Discounts) > 0 {
+		li.AllowanceCharge = makeLineCharges(l.Charges, l.Discounts, ccy, l.Sum)
+	}
+	if l.Item != nil {
+		li.Item = newItem(l)
+		li.Price = newPrice(l.Item, ccy)
+	}
+
+	return OrderLine{LineItem: li}
+}
+
+// ConvertOrder is a convenience function that converts a GOBL envelope
+// containing an order into a UBL Order document.
+func ConvertOrder(env *gobl.Envelope, opts ...Option) (*Order, error) {
+	doc, err := Convert(env, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ord, ok := doc.(*Order)
+	if !ok {
+		return nil, fmt.Errorf("expected order, got %T", doc)
+	}
+	return ord, nil
+}
@@ -0,0 +1,207 @@
+package ubl
+
+import (
+	"strings"
+
+	"github.com/invopop/gobl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/currency"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/pay"
+	"github.com/invopop/gobl/uuid"
+)
+
+// Convert converts the UBL Order to a GOBL envelope containing a
+// purchase order.
+func (uo *Order) Convert() (*gobl.Envelope, error) {
+	ord, err := uo.goblOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	env := gobl.NewEnvelope()
+	if err := env.Insert(ord); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+func (uo *Order) goblOrder() (*bill.Order, error) {
+	out := &bill.Order{
+		Type:     bill.OrderTypePurchase,
+		Code:     cbc.Code(uo.ID),
+		Currency: currency.Code(uo.DocumentCurrencyCode),
+		Supplier: goblParty(uo.SellerSupplierParty.Party),
+		Customer: goblParty(uo.BuyerCustomerParty.Party),
+	}
+
+	issueDate, err := parseDate(uo.IssueDate)
+	if err != nil {
+		return nil, err
+	}
+	out.IssueDate = issueDate
+
+	if uo.UUID != "" {
+		id, err := uuid.Parse(uo.UUID)
+		if err == nil {
+			out.UUID = id
+		}
+	}
+
+	if uo.AccountingCustomerParty != nil {
+		out.Buyer = goblParty(uo.AccountingCustomerParty.Party)
+	}
+
+	for _, note := range uo.Note {
+		out.Notes = append(out.Notes, &org.Note{
+			Text: cleanString(note),
+		})
+	}
+
+	if len(uo.ValidityPeriod) > 0 {
+		out.Period = goblPeriodDates(&uo.ValidityPeriod[0])
+	}
+
+	if uo.QuotationDocumentReference != nil {
+		docRef, err := goblReference(uo.QuotationDocumentReference)
+		if err != nil {
+			return nil, err
+		}
+		docRef.Type = bill.OrderTypeQuote
+		out.Preceding = append(out.Preceding, docRef)
+	}
+
+	for _, ref := range uo.OrderDocumentReference {
+		docRef, err := goblReference(&ref)
+		if err != nil {
+			return nil, err
+		}
+		if docRef.Type == "" {
+			docRef.Type = bill.OrderTypePurchase
+		}
+		out.Preceding = append(out.Preceding, docRef)
+	}
+
+	for _, contract := range uo.Contract {
+		out.Contracts = append(out.Contracts, &org.DocumentRef{
+			Code: cbc.Code(contract.ID.Value),
+		})
+	}
+
+	var terms *DeliveryTerms
+	if len(uo.DeliveryTerms) > 0 {
+		terms = &uo.DeliveryTerms[0]
+	}
+	d, err := goblDeliveryDetails(uo.Delivery, terms)
+	if err != nil {
+		return nil, err
+	}
+	if d.Receiver != nil || d.Date != nil || d.Period != nil || d.Identities != nil {
+		out.Delivery = d
+	}
+
+	if len(uo.PaymentTerms) > 0 {
+		var notes []string
+		for _, term := range uo.PaymentTerms {
+			notes = append(notes, term.Note...)
+		}
+		if len(notes) > 0 {
+			out.Payment = &bill.PaymentDetails{
+				Terms: &pay.Terms{
+					Notes: cleanString(strings.Join(notes, " ")),
+				},
+			}
+		}
+	}
+
+	taxCategoryMap := taxCategoryMapFrom(uo.TaxTotal)
+	for _, ac := range uo.AllowanceCharge {
+		if ac.ChargeIndicator {
+			ch, err := goblCharge(&ac, taxCategoryMap)
+			if err != nil {
+				return nil, err
+			}
+			out.Charges = append(out.Charges, ch)
+		} else {
+			dc, err := goblDiscount(&ac, taxCategoryMap)
+			if err != nil {
+				return nil, err
+			}
+			out.Discounts = append(out.Discounts, dc)
+		}
+	}
+
+	for _, ol := range uo.OrderLines {
+		line, err := goblOrderLine(&ol.LineItem, taxCategoryMap)
+		if err != nil {
+			return nil, err
+		}
+		out.Lines = append(out.Lines, line)
+	}
+
+	out.Attachments = goblAttachments(uo.AdditionalDocumentReference)
+
+	return out, nil
+}
+
+// goblOrderLine converts an order line item by mapping it onto an invoice
+// line so that the same item, price and allowance logic is applied. Order
+// lines may legitimately omit the price, in which case only the quantity
+// and item details are kept.
+func goblOrderLine(li *LineItem, taxCategoryMap map[string]*taxCategoryInfo) (*bill.Line, error) {
+	il := &InvoiceLine{
+		ID:               li.ID,
+		Note:             li.Note,
+		InvoicedQuantity: li.Quantity,
+		AllowanceCharge:  li.AllowanceCharge,
+		Item:             li.Item,
+		Price:            li.Price,
+	}
+	if li.AccountingCost != "" {
+		il.AccountingCost = &li.AccountingCost
+	}
+	if li.Price != nil {
+		return goblConvertLine(il, taxCategoryMap)
+	}
+
+	line, err := goblUnpricedLine(li.Quantity, li.Item, taxCategoryMap)
+	if err != nil {
+		return nil, err
+	}
+	if il.AccountingCost != nil {
+		line.Cost = cbc.Code(*il.AccountingCost)
+	}
+	for _, note := range li.Note {
+		if note != "" {
+			line.Notes = append(line.Notes, &org.Note{Text: cleanString(note)})
+		}
+	}
+	return line, nil
+}
+
+// goblUnpricedLine builds a GOBL line from a quantity and item for documents
+// such as orders and despatch advices where the price is optional.
+func goblUnpricedLine(q *Quantity, di *Item, taxCategoryMap map[string]*taxCategoryInfo) (*bill.Line, error) {
+	line := &bill.Line{
+		Quantity: num.MakeAmount(1, 0),
+		Item:     new(org.Item),
+	}
+	if di != nil {
+		goblConvertLineItem(di, line.Item)
+		goblConvertLineItemTaxes(di, line, taxCategoryMap)
+	}
+	if q != nil {
+		amount, err := num.AmountFromString(normalizeNumericString(q.Value))
+		if err != nil {
+			return nil, err
+		}
+		line.Quantity = amount
+		if q.UnitCode != "" {
+			line.Item.Unit = goblUnitFromUNECE(cbc.Code(q.UnitCode))
+		}
+	}
+	return line, nil
+}
@@ -0,0 +1,76 @@
+package ubl_test
+
+import (
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/currency"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/org"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOrder(t *testing.T) {
+	data, err := testLoadXML("order/order-peppol.xml")
+	require.NoError(t, err)
+
+	doc, err := ubl.Parse(data)
+	require.NoError(t, err)
+
+	uo, ok := doc.(*ubl.Order)
+	require.True(t, ok)
+
+	env, err := uo.Convert()
+	require.NoError(t, err)
+
+	ord, ok := env.Extract().(*bill.Order)
+	require.True(t, ok)
+
+	assert.Equal(t, bill.OrderTypePurchase, ord.Type)
+	assert.Equal(t, cbc.Code("1005"), ord.Code)
+	assert.Equal(t, "2018-07-01", ord.IssueDate.String())
+	assert.Equal(t, currency.SEK, ord.Currency)
+	require.Len(t, ord.Notes, 1)
+	assert.Equal(t, "Information text for the whole order", ord.Notes[0].Text)
+
+	require.NotNil(t, ord.Period)
+	assert.Equal(t, "2018-07-31", ord.Period.End.String())
+	require.Len(t, ord.Preceding, 2)
+	assert.Equal(t, cbc.Code("QuoteID123"), ord.Preceding[0].Code)
+	assert.Equal(t, bill.OrderTypeQuote, ord.Preceding[0].Type)
+	assert.Equal(t, cbc.Code("RjectedOrderID123"), ord.Preceding[1].Code)
+	assert.Equal(t, bill.OrderTypePurchase, ord.Preceding[1].Type)
+	require.Len(t, ord.Contracts, 1)
+	assert.Equal(t, cbc.Code("34322"), ord.Contracts[0].Code)
+
+	require.NotNil(t, ord.Supplier)
+	assert.Equal(t, "Moderna Produkter AB", ord.Supplier.Name)
+	require.NotNil(t, ord.Customer)
+	assert.Equal(t, "Johnssons Byggvaror AB", ord.Customer.Name)
+
+	require.NotNil(t, ord.Delivery)
+	require.NotNil(t, ord.Delivery.Period)
+	assert.Equal(t, "2018-08-01", ord.Delivery.Period.Start.String())
+	assert.Equal(t, "2018-08-15", ord.Delivery.Period.End.String())
+	require.NotNil(t, ord.Delivery.Receiver)
+
+	require.NotNil(t, ord.Payment)
+	assert.Equal(t, "Payment within 30 days", ord.Payment.Terms.Notes)
+
+	require.Len(t, ord.Charges, 1)
+	assert.Equal(t, "Transport costs", ord.Charges[0].Reason)
+
+	require.Len(t, ord.Lines, 2)
+	assert.Equal(t, num.MakeAmount(120, 0), ord.Lines[0].Quantity)
+	assert.Equal(t, "Falu Rödfärg", ord.Lines[0].Item.Name)
+	assert.Equal(t, org.Unit("l"), ord.Lines[0].Item.Unit)
+	assert.Equal(t, "5.00", ord.Lines[0].Item.Price.String())
+	assert.Equal(t, cbc.Code("ProjectID123"), ord.Lines[0].Cost)
+
+	assert.Equal(t, "Pensel 20 mm", ord.Lines[1].Item.Name)
+	assert.Equal(t, num.MakeAmount(15, 0), ord.Lines[1].Quantity)
+	assert.Nil(t, ord.Lines[1].Item.Price)
+}
@@ -0,0 +1,138 @@
+package ubl_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/org"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertOrder(t *testing.T) {
+	t.Run("order-complete.json", func(t *testing.T) {
+		env, err := loadTestEnvelope("order-complete.json")
+		require.NoError(t, err)
+
+		doc, err := ubl.ConvertOrder(env)
+		require.NoError(t, err)
+
+		assert.Equal(t, ubl.NamespaceUBLOrder, doc.UBLNamespace)
+		assert.Equal(t, ubl.ContextPeppolOrder.CustomizationID, doc.CustomizationID)
+		assert.Equal(t, ubl.ContextPeppolOrder.ProfileID, doc.ProfileID)
+		assert.Equal(t, "PO-1001", doc.ID)
+		assert.Equal(t, "2024-05-10", doc.IssueDate)
+		assert.Equal(t, ubl.OrderTypeCodeDefault, doc.OrderTypeCode)
+		assert.Equal(t, "EUR", doc.DocumentCurrencyCode)
+		assert.Equal(t, []string{"Please quote the order number on all invoices."}, doc.Note)
+
+		require.Len(t, doc.ValidityPeriod, 1)
+		assert.Equal(t, "2024-06-10", doc.ValidityPeriod[0].EndDate)
+		require.NotNil(t, doc.QuotationDocumentReference)
+		assert.Equal(t, "QT-77", doc.QuotationDocumentReference.ID.Value)
+		assert.Equal(t, "2024-05-01", doc.QuotationDocumentReference.IssueDate)
+		assert.Empty(t, doc.OrderDocumentReference)
+		require.Len(t, doc.Contract, 1)
+		assert.Equal(t, "CON-2024-01", doc.Contract[0].ID.Value)
+
+		assert.Equal(t, "Provide One GmbH", *doc.SellerSupplierParty.Party.PartyLegalEntity.RegistrationName)
+		assert.Equal(t, "Sample Consumer GmbH", *doc.BuyerCustomerParty.Party.PartyLegalEntity.RegistrationName)
+		assert.Nil(t, doc.AccountingCustomerParty)
+
+		require.Len(t, doc.Delivery, 1)
+		require.NotNil(t, doc.Delivery[0].RequestedDeliveryPeriod)
+		assert.Equal(t, "2024-05-20", doc.Delivery[0].RequestedDeliveryPeriod.StartDate)
+		assert.Equal(t, "2024-05-31", doc.Delivery[0].RequestedDeliveryPeriod.EndDate)
+
+		require.Len(t, doc.PaymentTerms, 1)
+		assert.Equal(t, []string{"Payment within 30 days of invoice"}, doc.PaymentTerms[0].Note)
+
+		require.NotNil(t, doc.AnticipatedMonetaryTotal)
+		assert.Equal(t, "2070.00", doc.AnticipatedMonetaryTotal.LineExtensionAmount.Value)
+		assert.Equal(t, "2463.30", doc.AnticipatedMonetaryTotal.PayableAmount.Value)
+		require.Len(t, doc.TaxTotal, 1)
+		assert.Equal(t, "393.30", doc.TaxTotal[0].TaxAmount.Value)
+
+		require.Len(t, doc.OrderLines, 2)
+		line := doc.OrderLines[0].LineItem
+		assert.Equal(t, "1", line.ID)
+		assert.Equal(t, "20", line.Quantity.Value)
+		assert.Equal(t, "HUR", line.Quantity.UnitCode)
+		assert.Equal(t, "1800.00", line.LineExtensionAmount.Value)
+		assert.Equal(t, "Development services", line.Item.Name)
+		assert.Equal(t, "90.00", line.Price.PriceAmount.Value)
+		assert.Equal(t, "S", line.Item.ClassifiedTaxCategory.ID.Value)
+		assert.Equal(t, "19", *line.Item.ClassifiedTaxCategory.Percent)
+
+		line = doc.OrderLines[1].LineItem
+		require.Len(t, line.AllowanceCharge, 1)
+		assert.False(t, line.AllowanceCharge[0].ChargeIndicator)
+		assert.Equal(t, "270.00", line.LineExtensionAmount.Value)
+	})
+
+	t.Run("preceding orders", func(t *testing.T) {
+		env, err := loadTestEnvelope("order-complete.json")
+		require.NoError(t, err)
+		ord := env.Extract().(*bill.Order)
+		ord.Preceding = append(ord.Preceding, &org.DocumentRef{Type: bill.OrderTypePurchase, Code: "PO-0999"})
+
+		doc, err := ubl.ConvertOrder(env)
+		require.NoError(t, err)
+		require.NotNil(t, doc.QuotationDocumentReference)
+		assert.Equal(t, "QT-77", doc.QuotationDocumentReference.ID.Value)
+		require.Len(t, doc.OrderDocumentReference, 1)
+		assert.Equal(t, "PO-0999", doc.OrderDocumentReference[0].ID.Value)
+	})
+
+	t.Run("not an order", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+
+		_, err = ubl.ConvertOrder(env)
+		assert.ErrorContains(t, err, "expected order")
+	})
+}
+
+func TestOrderBytes(t *testing.T) {
+	env, err := loadTestEnvelope("order-complete.json")
+	require.NoError(t, err)
+
+	doc, err := ubl.ConvertOrder(env)
+	require.NoError(t, err)
+
+	data, err := ubl.Bytes(doc)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `xmlns="urn:oasis:names:specification:ubl:schema:xsd:Order-2"`)
+	assert.Contains(t, string(data), `xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2"`)
+	assert.Contains(t, string(data), "<cac:OrderLine>")
+
+	outPath := filepath.Join(getConvertPath(), "out", "order-complete.xml")
+	if *updateOut {
+		require.NoError(t, os.WriteFile(outPath, data, 0644))
+	}
+	output, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, string(output), string(data), "Output should match the expected XML. Update with --update flag.")
+
+	parsed, err := ubl.Parse(data)
+	require.NoError(t, err)
+	uo, ok := parsed.(*ubl.Order)
+	require.True(t, ok)
+
+	t.Run("round trip", func(t *testing.T) {
+		uo.PaymentTerms = append(uo.PaymentTerms, ubl.PaymentTerms{Note: []string{"2% discount if paid within 10 days"}})
+		out, err := uo.Convert()
+		require.NoError(t, err)
+
+		ord := out.Extract().(*bill.Order)
+		require.Len(t, ord.Preceding, 1)
+		assert.Equal(t, cbc.Code("QT-77"), ord.Preceding[0].Code)
+		assert.Equal(t, bill.OrderTypeQuote, ord.Preceding[0].Type)
+		require.NotNil(t, ord.Payment)
+		assert.Equal(t, "Payment within 30 days of invoice 2% discount if paid within 10 days", ord.Payment.Terms.Notes)
+	})
+}
@@ -31,6 +31,7 @@ type BillingReference struct {
 // Reference represents a reference to a document
 type Reference struct {
 	ID                  IDType      `xml:"cbc:ID"`
+	UUID                string      `xml:"cbc:UUID,omitempty"`
 	IssueDate           string      `xml:"cbc:IssueDate,omitempty"`
 	DocumentTypeCode    string      `xml:"cbc:DocumentTypeCode,omitempty"`
 	DocumentType        string      `xml:"cbc:DocumentType,omitempty"`
@@ -1,7 +1,9 @@
 package ubl
 
 import (
+	"encoding/xml"
 	"fmt"
+	"slices"
 	"strings"
 	"strconv"
 
@@ -16,9 +18,50 @@ const SchemeIDEmail = "EM"
 // TaxSchemeVAT is the tax scheme code for VAT
 const TaxSchemeVAT = "VAT"
 
+// TaxSchemeGST is the tax scheme code for GST
+const TaxSchemeGST = "GST"
+
+// TaxSchemeTAX is the tax scheme code used for the seller's tax
+// registration, such as the Norwegian "Foretaksregisteret" or the Swedish
+// "Godkänd för F-skatt" texts.
+const TaxSchemeTAX = "TAX"
+
+// noteSrcSupplier is the source of the invoice notes with the supplier's
+// tax registration text, taken from the TAX party tax scheme.
+const noteSrcSupplier cbc.Key = "supplier"
+
+// Dutch identity types, used by NLCIUS for the parties' legal entities.
+const (
+	// IdentityTypeKVK is the Chamber of Commerce (KvK) number.
+	IdentityTypeKVK cbc.Code = "KVK"
+	// IdentityTypeOIN is the government organisation identification
+	// number (OIN).
+	IdentityTypeOIN cbc.Code = "OIN"
+)
+
+// Nordic identity types for the parties' organisation numbers.
+const (
+	// IdentityTypeNOOrgNr is the Norwegian organisation number from the
+	// Central Coordinating Register for Legal Entities.
+	IdentityTypeNOOrgNr cbc.Code = "ORGNR"
+	// IdentityTypeSEOrgNr is the Swedish organisation number, as defined
+	// by the GOBL SE regime.
+	IdentityTypeSEOrgNr cbc.Code = "ON"
+)
+
+// IdentityTypeKBO is the Belgian enterprise number registered in the
+// Crossroads Bank for Enterprises (KBO/BCE).
+const IdentityTypeKBO cbc.Code = "KBO"
+
+// identitySchemes maps the identity types of national registration
+// numbers to their ISO 6523 ICD schemes, as used by some contexts for the
+// parties' legal entities.
+type identitySchemes map[cbc.Code]string
+
 // SupplierParty represents the supplier party in a transaction
 type SupplierParty struct {
-	Party *Party `xml:"cac:Party"`
+	Party         *Party   `xml:"cac:Party"`
+	SellerContact *Contact `xml:"cac:SellerContact,omitempty"`
 }
 
 // CustomerParty represents the customer party in a transaction
@@ -28,13 +71,14 @@ type CustomerParty struct {
 
 // Party represents a party involved in a transaction
 type Party struct {
-	EndpointID          *EndpointID       `xml:"cbc:EndpointID"`
-	PartyIdentification []Identification  `xml:"cac:PartyIdentification"`
-	PartyName           *PartyName        `xml:"cac:PartyName"`
-	PostalAddress       *PostalAddress    `xml:"cac:PostalAddress"`
-	PartyTaxScheme      []PartyTaxScheme  `xml:"cac:PartyTaxScheme"`
-	PartyLegalEntity    *PartyLegalEntity `xml:"cac:PartyLegalEntity"`
-	Contact             *Contact          `xml:"cac:Contact"`
+	EndpointID                 *EndpointID       `xml:"cbc:EndpointID"`
+	IndustryClassificationCode *IDType           `xml:"cbc:IndustryClassificationCode"`
+	PartyIdentification        []Identification  `xml:"cac:PartyIdentification"`
+	PartyName                  *PartyName        `xml:"cac:PartyName"`
+	PostalAddress              *PostalAddress    `xml:"cac:PostalAddress"`
+	PartyTaxScheme             []PartyTaxScheme  `xml:"cac:PartyTaxScheme"`
+	PartyLegalEntity           *PartyLegalEntity `xml:"cac:PartyLegalEntity"`
+	Contact                    *Contact          `xml:"cac:Contact"`
 }
 
 // EndpointID represents an endpoint identifier
@@ -56,6 +100,7 @@ type PartyName struct {
 
 // PostalAddress represents a postal address
 type PostalAddress struct {
+	ID                   *string             `xml:"cbc:ID,omitempty"`
 	AddressFormatCode     *IDType             `xml:"cbc:AddressFormatCode"`
 	StreetName           *string             `xml:"cbc:StreetName"`
 	BuildingNumber       *string             `xml:"cbc:BuildingNumber"`
@@ -63,6 +108,7 @@ type PostalAddress struct {
 	CityName             *string             `xml:"cbc:CityName"`
 	PostalZone           *string             `xml:"cbc:PostalZone"`
 	CountrySubentity     *string             `xml:"cbc:CountrySubentity"`
+	CountrySubentityCode *string             `xml:"cbc:CountrySubentityCode"`
 	AddressLine          []AddressLine       `xml:"cac:AddressLine"`
 	Country              *Country            `xml:"cac:Country"`
 	LocationCoordinate   *LocationCoordinate `xml:"cac:LocationCoordinate"`
@@ -88,8 +134,10 @@ type Country struct {
 
 // PartyTaxScheme represents a party's tax scheme
 type PartyTaxScheme struct {
-	CompanyID *IDType    `xml:"cbc:CompanyID"`
-	TaxScheme *TaxScheme `xml:"cac:TaxScheme"`
+	RegistrationName *string    `xml:"cbc:RegistrationName,omitempty"`
+	CompanyID        *IDType    `xml:"cbc:CompanyID"`
+	TaxLevelCode     *string    `xml:"cbc:TaxLevelCode,omitempty"`
+	TaxScheme        *TaxScheme `xml:"cac:TaxScheme"`
 }
 
 // TaxScheme represents a tax scheme
@@ -99,6 +147,21 @@ type TaxScheme struct {
 	TaxTypeCode string  `xml:"cbc:TaxTypeCode,omitempty"`
 }
 
+// MarshalXML omits the ID of tax schemes that are only identified by
+// their name or tax type code, as in UBL-TR documents.
+func (ts TaxScheme) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type taxScheme struct {
+		ID          *IDType `xml:"cbc:ID"`
+		Name        *string `xml:"cbc:Name"`
+		TaxTypeCode string  `xml:"cbc:TaxTypeCode,omitempty"`
+	}
+	out := taxScheme{Name: ts.Name, TaxTypeCode: ts.TaxTypeCode}
+	if ts.ID != (IDType{}) {
+		out.ID = &ts.ID
+	}
+	return e.EncodeElement(out, start)
+}
+
 // PartyLegalEntity represents the legal entity of a party
 type PartyLegalEntity struct {
 	RegistrationName *string `xml:"cbc:RegistrationName"`
@@ -125,6 +188,31 @@ func (p *Party) CountryCode() string {
 	return ""
 }
 
+// addTaxRegistration adds the seller's tax registration text with the TAX
+// scheme, unless the party already includes one.
+func (p *Party) addTaxRegistration(text string) {
+	if p == nil || text == "" {
+		return
+	}
+	for _, pts := range p.PartyTaxScheme {
+		if pts.TaxScheme != nil && pts.TaxScheme.ID.Value == TaxSchemeTAX {
+			return
+		}
+	}
+	p.PartyTaxScheme = append(p.PartyTaxScheme, PartyTaxScheme{
+		CompanyID: &IDType{Value: text},
+		TaxScheme: &TaxScheme{
+			ID: IDType{Value: TaxSchemeTAX},
+		},
+	})
+}
+
+// isTaxRegistrationNote checks if the note contains the supplier's tax
+// registration text.
+func isTaxRegistrationNote(n *org.Note) bool {
+	return n.Key == org.NoteKeyTax && n.Src == noteSrcSupplier
+}
+
 func newParty(party *org.Party) *Party { //nolint:gocyclo
 	if party == nil {
 		return nil
@@ -268,7 +356,7 @@ func newParty(party *org.Party) *Party { //nolint:gocyclo
 		// This includes non-scoped identities and additional legal identities after the first
 		for i, id := range party.Identities {
 			// Skip the first legal identity (already in CompanyID)
-			if id.Scope == org.IdentityScopeLegal && i == firstLegalIdx {
+			if i == firstLegalIdx {
 				continue
 			}
 			// Skip tax scope identities (already in PartyTaxScheme)
@@ -301,6 +389,95 @@ func newParty(party *org.Party) *Party { //nolint:gocyclo
 	return p
 }
 
+// legalIdentity provides the identity used as the party's legal entity:
+// the first with the legal scope or, when there is none, the first without
+// a scope whose type is one of the national registration numbers.
+func (schemes identitySchemes) legalIdentity(party *org.Party) *org.Identity {
+	for _, id := range party.Identities {
+		if id.Scope == org.IdentityScopeLegal {
+			return id
+		}
+	}
+	for _, id := range party.Identities {
+		if _, ok := schemes[id.Type]; ok && id.Scope == "" {
+			return id
+		}
+	}
+	return nil
+}
+
+// schemeID provides the ISO 6523 ICD scheme of the identity from its
+// extensions or, for national registration numbers, its type.
+func (schemes identitySchemes) schemeID(id *org.Identity) string {
+	if s := id.Ext.Get(iso.ExtKeySchemeID).String(); s != "" {
+		return s
+	}
+	return schemes[id.Type]
+}
+
+// hasScheme checks if the scheme is used by any of the identity types.
+func (schemes identitySchemes) hasScheme(scheme string) bool {
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// apply uses the party's national registration number as the legal
+// entity of the generated party, unless it has a legal identity, and
+// adds the schemes of the registration numbers that lack one.
+func (schemes identitySchemes) apply(party *org.Party, p *Party) {
+	if party == nil || p == nil {
+		return
+	}
+	legal := schemes.legalIdentity(party)
+	for _, id := range party.Identities {
+		scheme, ok := schemes[id.Type]
+		if !ok || id.Scope == org.IdentityScopeTax || id.Ext.Get(iso.ExtKeySchemeID) != cbc.CodeEmpty {
+			continue
+		}
+		code := id.Code.String()
+		if id == legal {
+			if p.PartyLegalEntity == nil {
+				p.PartyLegalEntity = new(PartyLegalEntity)
+			}
+			p.PartyLegalEntity.CompanyID = &IDType{Value: code, SchemeID: &scheme}
+			// identities without a scope are added as party identifications
+			p.PartyIdentification = slices.DeleteFunc(p.PartyIdentification, func(pi Identification) bool {
+				return id.Scope == "" && pi.ID != nil && pi.ID.Value == code && pi.ID.SchemeID == nil
+			})
+			continue
+		}
+		for _, pi := range p.PartyIdentification {
+			if pi.ID != nil && pi.ID.Value == code && pi.ID.SchemeID == nil {
+				pi.ID.SchemeID = &scheme
+				break
+			}
+		}
+	}
+}
+
+// goblTypes sets the type of the parsed party's legal identities from
+// their scheme.
+func (schemes identitySchemes) goblTypes(p *org.Party) {
+	if p == nil {
+		return
+	}
+	for _, id := range p.Identities {
+		if id.Scope != org.IdentityScopeLegal || id.Type != cbc.CodeEmpty {
+			continue
+		}
+		s := id.Ext.Get(iso.ExtKeySchemeID).String()
+		for t, ts := range schemes {
+			if ts == s {
+				id.Type = t
+			}
+		}
+	}
+}
+
 // newDeliveryParty creates a Party structure for delivery parties
 // according to UBL rules:
 //   - UBL-CR-394: A UBL invoice should not include the DeliveryParty PostalAddress
@@ -140,10 +140,34 @@ func handlePartyTaxSchemes(party *Party, p *org.Party) {
 	}
 }
 
+// goblTaxRegistrationNote provides a supplier tax note with the seller's
+// tax registration text, from the party tax scheme with the TAX scheme, or
+// nil if there is none.
+func goblTaxRegistrationNote(party *Party) *org.Note {
+	if party == nil {
+		return nil
+	}
+	for _, pts := range party.PartyTaxScheme {
+		if pts.TaxScheme == nil || pts.TaxScheme.ID.Value != TaxSchemeTAX || pts.CompanyID == nil || pts.CompanyID.Value == "" {
+			continue
+		}
+		return &org.Note{
+			Key:  org.NoteKeyTax,
+			Src:  noteSrcSupplier,
+			Text: cleanString(pts.CompanyID.Value),
+		}
+	}
+	return nil
+}
+
 func extractValidTaxSchemes(schemes []PartyTaxScheme) []PartyTaxScheme {
 	validSchemes := make([]PartyTaxScheme, 0)
 	for _, pts := range schemes {
 		if pts.CompanyID != nil && pts.CompanyID.Value != "" && pts.TaxScheme != nil {
+			if pts.TaxScheme.ID.Value == TaxSchemeTAX {
+				// Tax registration texts, kept in a note, not tax IDs
+				continue
+			}
 			validSchemes = append(validSchemes, pts)
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"github.com/invopop/gobl/bill"
 	"github.com/invopop/gobl/cbc"
 	"github.com/invopop/gobl/catalogues/untdid"
+	"github.com/invopop/gobl/pay"
 	"github.com/invopop/validation"
 )
 
@@ -58,10 +59,20 @@ type FinancialInstitution struct {
 
 // PaymentTerms represents the terms of payment
 type PaymentTerms struct {
-	Note           []string `xml:"cbc:Note"`
-	Amount         *Amount  `xml:"cbc:Amount"`
-	PaymentPercent *string  `xml:"cbc:PaymentPercent"`
-	PaymentDueDate *string  `xml:"cbc:PaymentDueDate"`
+	Note                      []string          `xml:"cbc:Note"`
+	SettlementDiscountPercent *string           `xml:"cbc:SettlementDiscountPercent,omitempty"`
+	Amount                    *Amount           `xml:"cbc:Amount"`
+	PaymentPercent            *string           `xml:"cbc:PaymentPercent"`
+	PaymentDueDate            *string           `xml:"cbc:PaymentDueDate"`
+	SettlementPeriod          *SettlementPeriod `xml:"cac:SettlementPeriod,omitempty"`
+}
+
+// SettlementPeriod represents the period in which a settlement discount
+// can be applied
+type SettlementPeriod struct {
+	StartDate       *string  `xml:"cbc:StartDate,omitempty"`
+	EndDate         *string  `xml:"cbc:EndDate,omitempty"`
+	DurationMeasure *Measure `xml:"cbc:DurationMeasure,omitempty"`
 }
 
 // PrepaidPayment represents a prepaid payment
@@ -72,7 +83,7 @@ type PrepaidPayment struct {
 	InstructionID *string `xml:"cbc:InstructionID"`
 }
 
-func (ui *Invoice) addPayment(inv *bill.Invoice, o *options) error {
+func (ui *Invoice) addPayment(inv *bill.Invoice) error {
 	if inv == nil || inv.Payment == nil {
 		return nil
 	}
@@ -90,74 +101,12 @@ func (ui *Invoice) addPayment(inv *bill.Invoice, o *options) error {
 			}
 		}
 		paymentMeansCode := pymt.Instructions.Ext.Get(untdid.ExtKeyPaymentMeans).String()
-		if o != nil && o.context.IsOIOUBL() && paymentMeansCode == "30" {
-			// OIOUBL restricts allowed payment means and expects code 31 for IBAN transfers.
-			paymentMeansCode = "31"
-		}
-
-		ui.PaymentMeans = []PaymentMeans{
-			{
-				PaymentMeansCode: IDType{Value: paymentMeansCode},
-			},
-		}
-
-		if pymt.Instructions.Meta != nil {
-			if channel, ok := pymt.Instructions.Meta[cbc.Key("payment-channel")]; ok && channel != "" {
-				ui.PaymentMeans[0].PaymentChannelCode = &IDType{Value: channel}
-			}
-		}
-
-		if ref != "" {
-			ui.PaymentMeans[0].PaymentID = &ref
-		}
-
-		if pymt.Instructions.CreditTransfer != nil {
-			pfa := new(FinancialAccount)
-
-			if pymt.Instructions.CreditTransfer[0].IBAN != "" {
-				pfa.ID = &pymt.Instructions.CreditTransfer[0].IBAN
-			} else if pymt.Instructions.CreditTransfer[0].Number != "" {
-				pfa.ID = &pymt.Instructions.CreditTransfer[0].Number
-			}
-			if pymt.Instructions.CreditTransfer[0].Name != "" {
-				pfa.Name = &pymt.Instructions.CreditTransfer[0].Name
-			}
-			if pymt.Instructions.CreditTransfer[0].BIC != "" {
-				branch := &Branch{ID: &pymt.Instructions.CreditTransfer[0].BIC}
-				if o != nil && o.context.IsOIOUBL() && paymentMeansCode == "31" {
-					branch.FinancialInstitution = &FinancialInstitution{
-						ID: &pymt.Instructions.CreditTransfer[0].BIC,
-					}
-				}
-				pfa.FinancialInstitutionBranch = branch
-			}
-			if o != nil && o.context.IsOIOUBL() && paymentMeansCode == "31" && ui.PaymentMeans[0].PaymentChannelCode == nil {
-				ui.PaymentMeans[0].PaymentChannelCode = &IDType{Value: "IBAN"}
-			}
-
-			ui.PaymentMeans[0].PayeeFinancialAccount = pfa
-		}
-		if pymt.Instructions.DirectDebit != nil {
-			ui.PaymentMeans[0].PaymentMandate = &PaymentMandate{
-				ID: IDType{Value: pymt.Instructions.DirectDebit.Ref},
-			}
-			if pymt.Instructions.DirectDebit.Account != "" {
-				ui.PaymentMeans[0].PayerFinancialAccount = &FinancialAccount{
-					ID: &pymt.Instructions.DirectDebit.Account,
-				}
-			}
-		}
-		if pymt.Instructions.Card != nil {
-			ui.PaymentMeans[0].CardAccount = &CardAccount{
-				PrimaryAccountNumberID: &pymt.Instructions.Card.Last4,
-			}
-			if pymt.Instructions.Card.Holder != "" {
-				ui.PaymentMeans[0].CardAccount.HolderName = &pymt.Instructions.Card.Holder
-			}
-		}
+		ui.PaymentMeans = newPaymentMeans(pymt.Instructions, paymentMeansCode, ref)
 	}
 
 	if pymt.Terms != nil {
+		discounts, text := parseDiscountTerms(pymt.Terms.Notes)
+		notes := formatDiscountNotes(text, discounts)
 		ui.PaymentTerms = make([]PaymentTerms, 0)
 		if (len(pymt.Terms.DueDates) > 1) || (ui.CreditNoteTypeCode != "" && len(pymt.Terms.DueDates) > 0) {
 			for _, dueDate := range pymt.Terms.DueDates {
@@ -188,7 +137,14 @@ func (ui *Invoice) addPayment(inv *bill.Invoice, o *options) error {
 			}
 		} else {
 			ui.PaymentTerms = append(ui.PaymentTerms, PaymentTerms{
-				Note: []string{pymt.Terms.Notes},
+				Note: []string{notes},
+			})
+			discounts = nil // already included in the note
+		}
+		// early payment discounts are kept in a note also with due dates
+		if len(discounts) > 0 {
+			ui.PaymentTerms = append(ui.PaymentTerms, PaymentTerms{
+				Note: []string{notes},
 			})
 		}
 	}
@@ -199,3 +155,68 @@ func (ui *Invoice) addPayment(inv *bill.Invoice, o *options) error {
 
 	return nil
 }
+
+// newPaymentMeans provides a payment means block for each of the credit
+// transfer accounts, direct debit and card of the instructions, or a
+// single block with just the payment means code when none are defined.
+func newPaymentMeans(instr *pay.Instructions, code string, ref string) []PaymentMeans {
+	base := PaymentMeans{
+		PaymentMeansCode: IDType{Value: code},
+	}
+	if instr.Meta != nil {
+		if channel, ok := instr.Meta[cbc.Key("payment-channel")]; ok && channel != "" {
+			base.PaymentChannelCode = &IDType{Value: channel}
+		}
+	}
+	if ref != "" {
+		base.PaymentID = &ref
+	}
+
+	var pms []PaymentMeans
+	for _, ct := range instr.CreditTransfer {
+		if ct == nil {
+			continue
+		}
+		pm := base
+		pfa := new(FinancialAccount)
+		if ct.IBAN != "" {
+			pfa.ID = &ct.IBAN
+		} else if ct.Number != "" {
+			pfa.ID = &ct.Number
+		}
+		if ct.Name != "" {
+			pfa.Name = &ct.Name
+		}
+		if ct.BIC != "" {
+			pfa.FinancialInstitutionBranch = &Branch{ID: &ct.BIC}
+		}
+		pm.PayeeFinancialAccount = pfa
+		pms = append(pms, pm)
+	}
+	if dd := instr.DirectDebit; dd != nil {
+		pm := base
+		pm.PaymentMandate = &PaymentMandate{
+			ID: IDType{Value: dd.Ref},
+		}
+		if dd.Account != "" {
+			pm.PayerFinancialAccount = &FinancialAccount{
+				ID: &dd.Account,
+			}
+		}
+		pms = append(pms, pm)
+	}
+	if card := instr.Card; card != nil {
+		pm := base
+		pm.CardAccount = &CardAccount{
+			PrimaryAccountNumberID: &card.Last4,
+		}
+		if card.Holder != "" {
+			pm.CardAccount.HolderName = &card.Holder
+		}
+		pms = append(pms, pm)
+	}
+	if len(pms) == 0 {
+		pms = append(pms, base)
+	}
+	return pms
+}
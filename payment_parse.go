@@ -39,8 +39,16 @@ func (ui *Invoice) goblAddPayment(out *bill.Invoice) error {
 	if len(ui.PaymentTerms) > 0 {
 		payment.Terms = &pay.Terms{}
 		note := make([]string, 0)
+		var discounts []*discountTerm
 		for _, term := range ui.PaymentTerms {
 			note = append(note, term.Note...)
+			d, err := term.goblDiscountTerm(ui.IssueDate)
+			if err != nil {
+				return err
+			}
+			if d != nil {
+				discounts = append(discounts, d)
+			}
 			if term.Amount != nil {
 				amount, err := num.AmountFromString(normalizeNumericString(term.Amount.Value))
 				if err != nil {
@@ -54,6 +62,9 @@ func (ui *Invoice) goblAddPayment(out *bill.Invoice) error {
 		if len(note) > 0 {
 			payment.Terms.Notes = cleanString(strings.Join(note, " "))
 		}
+		if len(discounts) > 0 {
+			payment.Terms.Notes = mergeDiscountNotes(payment.Terms.Notes, discounts)
+		}
 	}
 
 	dueDate := ui.DueDate
@@ -83,7 +94,7 @@ func (ui *Invoice) goblAddPayment(out *bill.Invoice) error {
 	}
 
 	if len(ui.PaymentMeans) > 0 {
-		payment.Instructions = goblInvoiceInstructions(out, &ui.PaymentMeans[0])
+		payment.Instructions = goblInvoiceInstructions(out, ui.PaymentMeans)
 	}
 
 	// We do not currently map this as Peppol and EN16931 do not use it.
@@ -130,30 +141,35 @@ func (ui *Invoice) goblAddPayment(out *bill.Invoice) error {
 	return nil
 }
 
-func goblInvoiceInstructions(out *bill.Invoice, paymentMeans *PaymentMeans) *pay.Instructions {
+// goblInvoiceInstructions collects all the payment means blocks into a
+// single set of instructions, taking the code and reference from the first.
+func goblInvoiceInstructions(out *bill.Invoice, paymentMeans []PaymentMeans) *pay.Instructions {
+	first := &paymentMeans[0]
 	instructions := &pay.Instructions{
-		Key: goblPaymentMeansCode(paymentMeans.PaymentMeansCode.Value),
+		Key: goblPaymentMeansCode(first.PaymentMeansCode.Value),
 		Ext: tax.Extensions{
-			untdid.ExtKeyPaymentMeans: cbc.Code(paymentMeans.PaymentMeansCode.Value),
+			untdid.ExtKeyPaymentMeans: cbc.Code(first.PaymentMeansCode.Value),
 		},
 	}
 
-	if paymentMeans.PaymentMeansCode.Name != nil {
-		instructions.Detail = cleanString(*paymentMeans.PaymentMeansCode.Name)
+	if first.PaymentMeansCode.Name != nil {
+		instructions.Detail = cleanString(*first.PaymentMeansCode.Name)
 	}
 
-	if paymentMeans.PaymentID != nil {
-		instructions.Ref = cbc.Code(*paymentMeans.PaymentID)
-	}
-
-	if paymentMeans.PayeeFinancialAccount != nil {
-		instructions.CreditTransfer = goblCreditTransfer(paymentMeans)
-	}
-	if paymentMeans.PaymentMandate != nil {
-		instructions.DirectDebit = goblInvoiceDirectDebit(out, paymentMeans)
-	}
-	if paymentMeans.CardAccount != nil {
-		instructions.Card = goblCard(paymentMeans)
+	for i := range paymentMeans {
+		pm := &paymentMeans[i]
+		if pm.PaymentID != nil && instructions.Ref == cbc.CodeEmpty {
+			instructions.Ref = cbc.Code(*pm.PaymentID)
+		}
+		if pm.PayeeFinancialAccount != nil {
+			instructions.CreditTransfer = append(instructions.CreditTransfer, goblCreditTransfer(pm)...)
+		}
+		if pm.PaymentMandate != nil && instructions.DirectDebit == nil {
+			instructions.DirectDebit = goblInvoiceDirectDebit(out, pm)
+		}
+		if pm.CardAccount != nil && instructions.Card == nil {
+			instructions.Card = goblCard(pm)
+		}
 	}
 
 	return instructions
@@ -3,6 +3,7 @@ package ubl_test
 import (
 	"testing"
 
+	ubl "github.com/invopop/gobl.ubl"
 	"github.com/invopop/gobl/bill"
 	"github.com/invopop/gobl/catalogues/iso"
 	"github.com/invopop/gobl/cbc"
@@ -103,6 +104,20 @@ func TestParsePaymentInstructions(t *testing.T) {
 		assert.Equal(t, "DNBANOKK", payment.Instructions.CreditTransfer[0].BIC)
 	})
 
+	t.Run("instructions with multiple credit transfers", func(t *testing.T) {
+		e, err := testParseInvoice("en16931/ubl-example1.xml")
+		require.NoError(t, err)
+
+		inv, ok := e.Extract().(*bill.Invoice)
+		require.True(t, ok)
+
+		require.NotNil(t, inv.Payment)
+		require.NotNil(t, inv.Payment.Instructions)
+		require.Len(t, inv.Payment.Instructions.CreditTransfer, 2)
+		assert.Equal(t, "NL57 RABO 0107307510", inv.Payment.Instructions.CreditTransfer[0].IBAN)
+		assert.Equal(t, "NL03 INGB 0004489902", inv.Payment.Instructions.CreditTransfer[1].IBAN)
+	})
+
 	t.Run("instructions with direct debit", func(t *testing.T) {
 		e, err := testParseInvoice("en16931/ubl-example5.xml")
 		require.NoError(t, err)
@@ -241,4 +256,27 @@ func TestPaymentRoundTrip(t *testing.T) {
 		assert.Equal(t, "123456789", resultInv.Payment.Instructions.CreditTransfer[0].Number, "Account number should be preserved in Number field")
 		assert.Equal(t, "", resultInv.Payment.Instructions.CreditTransfer[0].IBAN, "IBAN should be empty when Number was used")
 	})
+
+	t.Run("multiple payment means round trip", func(t *testing.T) {
+		doc, err := ubl.ConvertInvoice(testMultiplePaymentMeansEnvelope(t))
+		require.NoError(t, err)
+
+		resultEnv, err := doc.Convert()
+		require.NoError(t, err)
+
+		resultInv, ok := resultEnv.Extract().(*bill.Invoice)
+		require.True(t, ok)
+
+		instr := resultInv.Payment.Instructions
+		require.NotNil(t, instr)
+		assert.Equal(t, cbc.Code("0003434323213231"), instr.Ref)
+		require.Len(t, instr.CreditTransfer, 2)
+		assert.Equal(t, "123456789", instr.CreditTransfer[0].Number)
+		assert.Equal(t, "NO9386011117947", instr.CreditTransfer[1].IBAN)
+		require.NotNil(t, instr.DirectDebit)
+		assert.Equal(t, "MANDATE-1", instr.DirectDebit.Ref)
+		require.NotNil(t, instr.Card)
+		assert.Equal(t, "1234", instr.Card.Last4)
+		assert.Equal(t, "Jane Doe", instr.Card.Holder)
+	})
 }
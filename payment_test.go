@@ -3,9 +3,11 @@ package ubl_test
 import (
 	"testing"
 
+	"github.com/invopop/gobl"
 	ubl "github.com/invopop/gobl.ubl"
 	"github.com/invopop/gobl/bill"
 	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/pay"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -37,6 +39,40 @@ func TestNewPayment(t *testing.T) {
 		assert.Equal(t, "DNBANOKK", *doc.PaymentMeans[0].PayeeFinancialAccount.FinancialInstitutionBranch.ID)
 	})
 
+	t.Run("multiple payment means", func(t *testing.T) {
+		env := testMultiplePaymentMeansEnvelope(t)
+
+		doc, err := ubl.ConvertInvoice(env)
+		require.NoError(t, err)
+
+		require.Len(t, doc.PaymentMeans, 4)
+		for _, pm := range doc.PaymentMeans {
+			assert.Equal(t, "30", pm.PaymentMeansCode.Value)
+			assert.Equal(t, "0003434323213231", *pm.PaymentID)
+		}
+		assert.Equal(t, "123456789", *doc.PaymentMeans[0].PayeeFinancialAccount.ID)
+		assert.Equal(t, "NO9386011117947", *doc.PaymentMeans[1].PayeeFinancialAccount.ID)
+		assert.Nil(t, doc.PaymentMeans[1].PaymentMandate)
+		assert.Equal(t, "MANDATE-1", doc.PaymentMeans[2].PaymentMandate.ID.Value)
+		assert.Equal(t, "DE89370400440532013000", *doc.PaymentMeans[2].PayerFinancialAccount.ID)
+		assert.Nil(t, doc.PaymentMeans[2].PayeeFinancialAccount)
+		assert.Equal(t, "1234", *doc.PaymentMeans[3].CardAccount.PrimaryAccountNumberID)
+		assert.Equal(t, "Jane Doe", *doc.PaymentMeans[3].CardAccount.HolderName)
+	})
+
+	t.Run("payment means without accounts", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-account-number.json")
+		require.NoError(t, err)
+		inv := env.Extract().(*bill.Invoice)
+		inv.Payment.Instructions.CreditTransfer = nil
+
+		doc, err := ubl.ConvertInvoice(env)
+		require.NoError(t, err)
+		require.Len(t, doc.PaymentMeans, 1)
+		assert.Equal(t, "30", doc.PaymentMeans[0].PaymentMeansCode.Value)
+		assert.Nil(t, doc.PaymentMeans[0].PayeeFinancialAccount)
+	})
+
 	t.Run("document type extension", func(t *testing.T) {
 		env, err := loadTestEnvelope("invoice-minimal.json")
 		require.NoError(t, err)
@@ -123,3 +159,26 @@ func TestNewPayment(t *testing.T) {
 		assert.Empty(t, doc.DueDate)
 	})
 }
+
+// testMultiplePaymentMeansEnvelope provides an invoice with two credit
+// transfer accounts, a direct debit and a card in its instructions.
+func testMultiplePaymentMeansEnvelope(t *testing.T) *gobl.Envelope {
+	t.Helper()
+	env, err := loadTestEnvelope("invoice-account-number.json")
+	require.NoError(t, err)
+	inv, ok := env.Extract().(*bill.Invoice)
+	require.True(t, ok)
+	instr := inv.Payment.Instructions
+	instr.CreditTransfer = append(instr.CreditTransfer, &pay.CreditTransfer{
+		IBAN: "NO9386011117947",
+	})
+	instr.DirectDebit = &pay.DirectDebit{
+		Ref:     "MANDATE-1",
+		Account: "DE89370400440532013000",
+	}
+	instr.Card = &pay.Card{
+		Last4:  "1234",
+		Holder: "Jane Doe",
+	}
+	return env
+}
@@ -0,0 +1,123 @@
+package ubl
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/phive"
+)
+
+var (
+	// phiveRuleID extracts rule identifiers from messages that follow the
+	// Schematron convention of "[BR-01]-Message".
+	phiveRuleID = regexp.MustCompile(`^\[([^\]]+)\]-?\s*`)
+	// phiveLine extracts the line number from locations such as "[12:34]".
+	phiveLine = regexp.MustCompile(`(\d+):\d+`)
+)
+
+// PhiveValidator validates documents using a remote Phive service over
+// gRPC. Unless a VESID is provided, it is determined from the context
+// that matches the document's CustomizationID and ProfileID.
+type PhiveValidator struct {
+	client phive.ValidationServiceClient
+
+	// VESID optionally forces the validation execution set to use.
+	VESID string
+}
+
+// NewPhiveValidator prepares a new validator using the provided Phive
+// gRPC client.
+func NewPhiveValidator(client phive.ValidationServiceClient) *PhiveValidator {
+	return &PhiveValidator{client: client}
+}
+
+// Validate sends the document to Phive and converts the errors and warnings
+// of each validation layer into findings.
+func (pv *PhiveValidator) Validate(ctx context.Context, data []byte) ([]Finding, error) {
+	vesid := pv.VESID
+	if vesid == "" {
+		var err error
+		vesid, err = documentVESID(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := pv.client.ValidateXml(ctx, &phive.ValidateXmlRequest{
+		Vesid:      vesid,
+		XmlContent: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("phive: %w", err)
+	}
+	if resp.ErrorMessage != "" {
+		return nil, fmt.Errorf("phive: %s", resp.ErrorMessage)
+	}
+
+	var out []Finding
+	for _, layer := range resp.Results {
+		syntax := strings.EqualFold(layer.ValidationType, "xsd")
+		for _, e := range layer.Errors {
+			out = append(out, phiveFinding(e, FlagFatal, syntax))
+		}
+		for _, e := range layer.Warnings {
+			out = append(out, phiveFinding(e, FlagWarning, syntax))
+		}
+	}
+	return out, nil
+}
+
+func phiveFinding(e *phive.ValidationError, flag string, syntax bool) Finding {
+	f := Finding{
+		RuleID:   e.TestId,
+		Flag:     flag,
+		Location: e.Xpath,
+		Message:  e.Message,
+		Syntax:   syntax,
+	}
+	if m := phiveRuleID.FindStringSubmatch(f.Message); m != nil {
+		if f.RuleID == "" {
+			f.RuleID = m[1]
+		}
+		f.Message = f.Message[len(m[0]):]
+	}
+	if m := phiveLine.FindStringSubmatch(e.Location); m != nil {
+		f.Line, _ = strconv.Atoi(m[1])
+	}
+	if f.Location == "" {
+		f.Location = e.Location
+	}
+	return f
+}
+
+// documentVESID determines the VESID to use for the document from the
+// context matching its root element, CustomizationID and ProfileID.
+func documentVESID(data []byte) (string, error) {
+	var head struct {
+		XMLName         xml.Name
+		CustomizationID string `xml:"CustomizationID"`
+		ProfileID       string `xml:"ProfileID"`
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&head); err != nil && err != io.EOF {
+		return "", fmt.Errorf("phive: %w", err)
+	}
+	c := FindContext(head.CustomizationID, head.ProfileID)
+	if c == nil {
+		return "", fmt.Errorf("phive: no context found for %q", head.CustomizationID)
+	}
+	vesid := c.VESIDs.Invoice
+	if head.XMLName.Space == NamespaceUBLCreditNote && c.VESIDs.CreditNote != "" {
+		vesid = c.VESIDs.CreditNote
+	}
+	if vesid == "" {
+		return "", fmt.Errorf("phive: no VESID defined for %q", head.CustomizationID)
+	}
+	return vesid, nil
+}
@@ -0,0 +1,165 @@
+package ubl_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/phive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// phiveServer is a local stand-in for the Phive validation service that
+// records the VESID requested and replies with a prepared response.
+type phiveServer struct {
+	phive.UnimplementedValidationServiceServer
+	vesid string
+	resp  *phive.ValidateXmlResponse
+}
+
+func (s *phiveServer) ValidateXml(_ context.Context, req *phive.ValidateXmlRequest) (*phive.ValidateXmlResponse, error) {
+	s.vesid = req.Vesid
+	return s.resp, nil
+}
+
+func newPhiveClient(t *testing.T, srv *phiveServer) phive.ValidationServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer()
+	phive.RegisterValidationServiceServer(gs, srv)
+	go gs.Serve(lis) //nolint:errcheck
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck
+	return phive.NewValidationServiceClient(conn)
+}
+
+func TestPhiveValidator(t *testing.T) {
+	t.Run("findings from each layer", func(t *testing.T) {
+		srv := &phiveServer{
+			resp: &phive.ValidateXmlResponse{
+				Results: []*phive.ValidationLayerResult{
+					{
+						ValidationType: "xsd",
+						Success:        false,
+						Errors: []*phive.ValidationError{
+							{
+								Level:    "ERROR",
+								Message:  "cvc-datatype-valid.1.2.1: '13/11/2017' is not a valid value for 'date'.",
+								Location: "[8:47]",
+							},
+						},
+					},
+					{
+						ValidationType: "schematron-xslt",
+						Success:        true,
+						Warnings: []*phive.ValidationError{
+							{
+								Level:   "WARN",
+								Message: "[PEPPOL-EN16931-R002]-No more than one note is allowed on document level.",
+								Xpath:   "/*:Invoice[1]",
+							},
+						},
+					},
+				},
+			},
+		}
+		pv := ubl.NewPhiveValidator(newPhiveClient(t, srv))
+
+		data, err := testLoadXML("peppol/base-example.xml")
+		require.NoError(t, err)
+		findings, err := pv.Validate(context.Background(), data)
+		require.NoError(t, err)
+		assert.Equal(t, ubl.ContextPeppol.VESIDs.Invoice, srv.vesid)
+
+		require.Len(t, findings, 2)
+		f := findings[0]
+		assert.True(t, f.IsFatal())
+		assert.True(t, f.Syntax)
+		assert.Equal(t, 8, f.Line)
+		f = findings[1]
+		assert.False(t, f.IsFatal())
+		assert.False(t, f.Syntax)
+		assert.Equal(t, "PEPPOL-EN16931-R002", f.RuleID)
+		assert.Equal(t, "No more than one note is allowed on document level.", f.Message)
+		assert.Equal(t, "/*:Invoice[1]", f.Location)
+	})
+
+	t.Run("credit note VESID", func(t *testing.T) {
+		srv := &phiveServer{resp: &phive.ValidateXmlResponse{Success: true}}
+		pv := ubl.NewPhiveValidator(newPhiveClient(t, srv))
+
+		data, err := testLoadXML("peppol/base-creditnote-correction.xml")
+		require.NoError(t, err)
+		findings, err := pv.Validate(context.Background(), data)
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+		assert.Equal(t, ubl.ContextPeppol.VESIDs.CreditNote, srv.vesid)
+	})
+
+	t.Run("fixed VESID", func(t *testing.T) {
+		srv := &phiveServer{resp: &phive.ValidateXmlResponse{Success: true}}
+		pv := ubl.NewPhiveValidator(newPhiveClient(t, srv))
+		pv.VESID = "eu.cen.en16931:ubl:latest"
+
+		data, err := testLoadXML("peppol/base-example.xml")
+		require.NoError(t, err)
+		_, err = pv.Validate(context.Background(), data)
+		require.NoError(t, err)
+		assert.Equal(t, "eu.cen.en16931:ubl:latest", srv.vesid)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		srv := &phiveServer{resp: &phive.ValidateXmlResponse{ErrorMessage: "Validation failed: unknown VESID"}}
+		pv := ubl.NewPhiveValidator(newPhiveClient(t, srv))
+
+		data, err := testLoadXML("peppol/base-example.xml")
+		require.NoError(t, err)
+		_, err = pv.Validate(context.Background(), data)
+		assert.ErrorContains(t, err, "phive: Validation failed: unknown VESID")
+	})
+
+	t.Run("unknown context", func(t *testing.T) {
+		pv := ubl.NewPhiveValidator(nil)
+		_, err := pv.Validate(context.Background(), []byte(`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"><CustomizationID>foo</CustomizationID></Invoice>`))
+		assert.ErrorContains(t, err, `phive: no context found for "foo"`)
+	})
+
+	t.Run("convert with phive", func(t *testing.T) {
+		srv := &phiveServer{
+			resp: &phive.ValidateXmlResponse{
+				Results: []*phive.ValidationLayerResult{
+					{
+						ValidationType: "schematron-xslt",
+						Errors: []*phive.ValidationError{
+							{Level: "ERROR", Message: "[BR-CO-10]-Sum of Invoice line net amount."},
+						},
+					},
+				},
+			},
+		}
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		_, err = ubl.Convert(env,
+			ubl.WithContext(ubl.ContextPeppol),
+			ubl.WithValidator(ubl.NewPhiveValidator(newPhiveClient(t, srv))),
+		)
+		var ve *ubl.ValidationError
+		require.ErrorAs(t, err, &ve)
+		assert.Equal(t, "BR-CO-10", ve.Findings[0].RuleID)
+		assert.Equal(t, ubl.ContextPeppol.VESIDs.Invoice, srv.vesid)
+	})
+}
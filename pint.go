@@ -0,0 +1,111 @@
+package ubl
+
+import (
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/catalogues/untdid"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/tax"
+)
+
+// PeppolPINTProfileID is the business process used by the Peppol PINT
+// billing specializations.
+const PeppolPINTProfileID = "urn:peppol:bis:billing"
+
+// ContextPINTAUNZ defines the context for Peppol PINT A-NZ documents,
+// used in Australia and New Zealand with the GST tax scheme.
+var ContextPINTAUNZ = Context{
+	CustomizationID: "urn:peppol:pint:billing-1@aunz-1",
+	ProfileID:       PeppolPINTProfileID,
+	Output: OutputRules{
+		UUID: true,
+	},
+	Tax: TaxRules{
+		Scheme: TaxSchemeGST,
+	},
+	Hooks: pintHooks{},
+}
+
+// ContextPINTSG defines the context for Peppol PINT Singapore
+// (InvoiceNow) documents, which use the GST tax scheme with the IRAS tax
+// categories.
+var ContextPINTSG = Context{
+	CustomizationID: "urn:peppol:pint:billing-1@sg-1",
+	ProfileID:       PeppolPINTProfileID,
+	Output: OutputRules{
+		UUID: true,
+	},
+	Tax: TaxRules{
+		Scheme: TaxSchemeGST,
+		Categories: map[string]string{
+			"S":  "SR",   // standard-rated supplies
+			"Z":  "ZR",   // zero-rated supplies
+			"E":  "ES33", // exempt supplies
+			"AE": "SRRC", // reverse charge
+			"O":  "OS",   // out-of-scope supplies
+		},
+	},
+	Hooks: pintHooks{},
+}
+
+// ContextPINTJP defines the context for Peppol PINT Japan documents,
+// where the reduced consumption tax rate uses the "AA" tax category.
+var ContextPINTJP = Context{
+	CustomizationID: "urn:peppol:pint:billing-1@jp-1",
+	ProfileID:       PeppolPINTProfileID,
+	Output: OutputRules{
+		UUID: true,
+	},
+	Hooks: pintHooks{},
+}
+
+// ContextPINTAE defines the context for Peppol PINT United Arab Emirates
+// documents.
+var ContextPINTAE = Context{
+	CustomizationID: "urn:peppol:pint:billing-1@ae-1",
+	ProfileID:       PeppolPINTProfileID,
+	Output: OutputRules{
+		UUID: true,
+	},
+	Hooks: pintHooks{},
+}
+
+// pintHooks prepares invoices for the PINT contexts, which do not require
+// the EN16931 addon.
+type pintHooks struct {
+	BaseHooks
+}
+
+// BeforeConvert ensures the invoice has a document type.
+func (pintHooks) BeforeConvert(inv *bill.Invoice) error {
+	setDocumentType(inv)
+	return nil
+}
+
+// AfterParse sets the tax keys from the tax categories, which would
+// otherwise be determined by the EN16931 addon.
+func (pintHooks) AfterParse(_ *Invoice, inv *bill.Invoice) error {
+	keys := make(map[string]cbc.Key, len(taxCategoryKeys))
+	for k, c := range taxCategoryKeys {
+		keys[c] = k
+	}
+	setKeys := func(set tax.Set) {
+		for _, tc := range set {
+			if tc == nil || tc.Key != "" {
+				continue
+			}
+			if k, ok := keys[tc.Ext.Get(untdid.ExtKeyTaxCategory).String()]; ok {
+				tc.Key = k
+			}
+		}
+	}
+	for _, l := range inv.Lines {
+		setKeys(l.Taxes)
+	}
+	for _, c := range inv.Charges {
+		setKeys(c.Taxes)
+	}
+	for _, d := range inv.Discounts {
+		setKeys(d.Taxes)
+	}
+	return nil
+}
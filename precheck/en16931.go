@@ -0,0 +1,385 @@
+package precheck
+
+import (
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/num"
+)
+
+// Common paths shared between invoices and credit notes.
+const (
+	pathLines         = "cac:InvoiceLine|cac:CreditNoteLine"
+	pathLineQuantity  = "cbc:InvoicedQuantity|cbc:CreditedQuantity"
+	pathTypeCode      = "cbc:InvoiceTypeCode|cbc:CreditNoteTypeCode"
+	pathSupplierParty = "cac:AccountingSupplierParty/cac:Party"
+	pathCustomerParty = "cac:AccountingCustomerParty/cac:Party"
+	pathTotals        = "cac:LegalMonetaryTotal"
+	pathTaxSubtotals  = "cac:TaxTotal/cac:TaxSubtotal"
+)
+
+// linePath prepares a path to the provided elements inside both invoice
+// and credit note lines.
+func linePath(path string) string {
+	return "cac:InvoiceLine/" + path + "|cac:CreditNoteLine/" + path
+}
+
+// EN16931 contains a subset of the core rules of the European semantic
+// data model for electronic invoices (CEN EN16931-1) applied to UBL:
+// BR-01 to BR-16, BR-21 to BR-27, BR-45 to BR-48, BR-CO-10 to BR-CO-18
+// and BR-CO-25. BR-CO-17 accepts a difference of one cent caused by
+// rounding at line level.
+var EN16931 = &RuleSet{
+	Name:           "EN16931",
+	Specifications: []string{"urn:cen.eu:en16931:2017"},
+	Rules: []*Rule{
+		{
+			ID:      "BR-01",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have a Specification identifier (BT-24).",
+			Assert:  exists("cbc:CustomizationID"),
+		},
+		{
+			ID:      "BR-02",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have an Invoice number (BT-1).",
+			Assert:  exists("cbc:ID"),
+		},
+		{
+			ID:      "BR-03",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have an Invoice issue date (BT-2).",
+			Assert:  exists("cbc:IssueDate"),
+		},
+		{
+			ID:      "BR-04",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have an Invoice type code (BT-3).",
+			Assert:  exists(pathTypeCode),
+		},
+		{
+			ID:      "BR-05",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have an Invoice currency code (BT-5).",
+			Assert:  exists("cbc:DocumentCurrencyCode"),
+		},
+		{
+			ID:      "BR-06",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall contain the Seller name (BT-27).",
+			Assert:  exists(pathSupplierParty + "/cac:PartyLegalEntity/cbc:RegistrationName"),
+		},
+		{
+			ID:      "BR-07",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall contain the Buyer name (BT-44).",
+			Assert:  exists(pathCustomerParty + "/cac:PartyLegalEntity/cbc:RegistrationName"),
+		},
+		{
+			ID:      "BR-08",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall contain the Seller postal address (BG-5).",
+			Assert:  exists(pathSupplierParty + "/cac:PostalAddress"),
+		},
+		{
+			ID:      "BR-09",
+			Flag:    ubl.FlagFatal,
+			Context: pathSupplierParty + "/cac:PostalAddress",
+			Message: "The Seller postal address (BG-5) shall contain a Seller country code (BT-40).",
+			Assert:  exists("cac:Country/cbc:IdentificationCode"),
+		},
+		{
+			ID:      "BR-10",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall contain the Buyer postal address (BG-8).",
+			Assert:  exists(pathCustomerParty + "/cac:PostalAddress"),
+		},
+		{
+			ID:      "BR-11",
+			Flag:    ubl.FlagFatal,
+			Context: pathCustomerParty + "/cac:PostalAddress",
+			Message: "The Buyer postal address shall contain a Buyer country code (BT-55).",
+			Assert:  exists("cac:Country/cbc:IdentificationCode"),
+		},
+		{
+			ID:      "BR-12",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have the Sum of Invoice line net amount (BT-106).",
+			Assert:  exists(pathTotals + "/cbc:LineExtensionAmount"),
+		},
+		{
+			ID:      "BR-13",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have the Invoice total amount without VAT (BT-109).",
+			Assert:  exists(pathTotals + "/cbc:TaxExclusiveAmount"),
+		},
+		{
+			ID:      "BR-14",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have the Invoice total amount with VAT (BT-112).",
+			Assert:  exists(pathTotals + "/cbc:TaxInclusiveAmount"),
+		},
+		{
+			ID:      "BR-15",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have the Amount due for payment (BT-115).",
+			Assert:  exists(pathTotals + "/cbc:PayableAmount"),
+		},
+		{
+			ID:      "BR-16",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall have at least one Invoice line (BG-25).",
+			Assert:  exists(pathLines),
+		},
+		{
+			ID:      "BR-21",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "Each Invoice line (BG-25) shall have an Invoice line identifier (BT-126).",
+			Assert:  exists("cbc:ID"),
+		},
+		{
+			ID:      "BR-22",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "Each Invoice line (BG-25) shall have an Invoiced quantity (BT-129).",
+			Assert:  exists(pathLineQuantity),
+		},
+		{
+			ID:      "BR-23",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "An Invoice line (BG-25) shall have an Invoiced quantity unit of measure code (BT-130).",
+			Assert: func(n *Node) bool {
+				q := n.First(pathLineQuantity)
+				return q != nil && q.Attr("unitCode") != ""
+			},
+		},
+		{
+			ID:      "BR-24",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "Each Invoice line (BG-25) shall have an Invoice line net amount (BT-131).",
+			Assert:  exists("cbc:LineExtensionAmount"),
+		},
+		{
+			ID:      "BR-25",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "Each Invoice line (BG-25) shall contain the Item name (BT-153).",
+			Assert:  exists("cac:Item/cbc:Name"),
+		},
+		{
+			ID:      "BR-26",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "Each Invoice line (BG-25) shall contain the Item net price (BT-146).",
+			Assert:  exists("cac:Price/cbc:PriceAmount"),
+		},
+		{
+			ID:      "BR-27",
+			Flag:    ubl.FlagFatal,
+			Context: linePath("cac:Price"),
+			Message: "The Item net price (BT-146) shall NOT be negative.",
+			Assert: func(n *Node) bool {
+				a, ok := n.Amount("cbc:PriceAmount")
+				return !ok || !a.IsNegative()
+			},
+		},
+		{
+			ID:      "BR-45",
+			Flag:    ubl.FlagFatal,
+			Context: pathTaxSubtotals,
+			Message: "Each VAT breakdown (BG-23) shall have a VAT category taxable amount (BT-116).",
+			Assert:  exists("cbc:TaxableAmount"),
+		},
+		{
+			ID:      "BR-46",
+			Flag:    ubl.FlagFatal,
+			Context: pathTaxSubtotals,
+			Message: "Each VAT breakdown (BG-23) shall have a VAT category tax amount (BT-117).",
+			Assert:  exists("cbc:TaxAmount"),
+		},
+		{
+			ID:      "BR-47",
+			Flag:    ubl.FlagFatal,
+			Context: pathTaxSubtotals,
+			Message: "Each VAT breakdown (BG-23) shall be defined through a VAT category code (BT-118).",
+			Assert:  exists("cac:TaxCategory/cbc:ID"),
+		},
+		{
+			ID:      "BR-48",
+			Flag:    ubl.FlagFatal,
+			Context: pathTaxSubtotals,
+			Message: "Each VAT breakdown (BG-23) shall have a VAT category rate (BT-119), except if the Invoice is not subject to VAT.",
+			Assert: func(n *Node) bool {
+				return n.Value("cac:TaxCategory/cbc:ID") == "O" || n.Exists("cac:TaxCategory/cbc:Percent")
+			},
+		},
+		{
+			ID:      "BR-CO-10",
+			Flag:    ubl.FlagFatal,
+			Context: pathTotals,
+			Message: "Sum of Invoice line net amount (BT-106) = Σ Invoice line net amount (BT-131).",
+			Assert: func(n *Node) bool {
+				total, ok := n.Amount("cbc:LineExtensionAmount")
+				if !ok {
+					return true
+				}
+				return total.Equals(n.Root().Sum(linePath("cbc:LineExtensionAmount")))
+			},
+		},
+		{
+			ID:      "BR-CO-11",
+			Flag:    ubl.FlagFatal,
+			Context: pathTotals,
+			Message: "Sum of allowances on document level (BT-107) = Σ Document level allowance amount (BT-92).",
+			Assert: func(n *Node) bool {
+				return documentChargesMatch(n, "cbc:AllowanceTotalAmount", false)
+			},
+		},
+		{
+			ID:      "BR-CO-12",
+			Flag:    ubl.FlagFatal,
+			Context: pathTotals,
+			Message: "Sum of charges on document level (BT-108) = Σ Document level charge amount (BT-99).",
+			Assert: func(n *Node) bool {
+				return documentChargesMatch(n, "cbc:ChargeTotalAmount", true)
+			},
+		},
+		{
+			ID:      "BR-CO-13",
+			Flag:    ubl.FlagFatal,
+			Context: pathTotals,
+			Message: "Invoice total amount without VAT (BT-109) = Σ Invoice line net amount (BT-131) - Sum of allowances on document level (BT-107) + Sum of charges on document level (BT-108).",
+			Assert: func(n *Node) bool {
+				total, ok := n.Amount("cbc:TaxExclusiveAmount")
+				if !ok {
+					return true
+				}
+				sum := sub(n.Sum("cbc:LineExtensionAmount"), n.Sum("cbc:AllowanceTotalAmount"))
+				sum = add(sum, n.Sum("cbc:ChargeTotalAmount"))
+				return total.Equals(sum)
+			},
+		},
+		{
+			ID:      "BR-CO-14",
+			Flag:    ubl.FlagFatal,
+			Context: "cac:TaxTotal",
+			Message: "Invoice total VAT amount (BT-110) = Σ VAT category tax amount (BT-117).",
+			Assert: func(n *Node) bool {
+				if !n.Exists("cac:TaxSubtotal") {
+					return true
+				}
+				total, ok := n.Amount("cbc:TaxAmount")
+				return !ok || total.Equals(n.Sum("cac:TaxSubtotal/cbc:TaxAmount"))
+			},
+		},
+		{
+			ID:      "BR-CO-15",
+			Flag:    ubl.FlagFatal,
+			Context: pathTotals,
+			Message: "Invoice total amount with VAT (BT-112) = Invoice total amount without VAT (BT-109) + Invoice total VAT amount (BT-110).",
+			Assert: func(n *Node) bool {
+				total, ok := n.Amount("cbc:TaxInclusiveAmount")
+				if !ok {
+					return true
+				}
+				return total.Equals(add(n.Sum("cbc:TaxExclusiveAmount"), documentTaxAmount(n.Root())))
+			},
+		},
+		{
+			ID:      "BR-CO-16",
+			Flag:    ubl.FlagFatal,
+			Context: pathTotals,
+			Message: "Amount due for payment (BT-115) = Invoice total amount with VAT (BT-112) - Paid amount (BT-113) + Rounding amount (BT-114).",
+			Assert: func(n *Node) bool {
+				total, ok := n.Amount("cbc:PayableAmount")
+				if !ok {
+					return true
+				}
+				sum := sub(n.Sum("cbc:TaxInclusiveAmount"), n.Sum("cbc:PrepaidAmount"))
+				sum = add(sum, n.Sum("cbc:PayableRoundingAmount"))
+				return total.Equals(sum)
+			},
+		},
+		{
+			ID:      "BR-CO-17",
+			Flag:    ubl.FlagFatal,
+			Context: pathTaxSubtotals,
+			Message: "VAT category tax amount (BT-117) = VAT category taxable amount (BT-116) x (VAT category rate (BT-119) / 100), rounded to two decimals.",
+			Assert: func(n *Node) bool {
+				taxable, ok1 := n.Amount("cbc:TaxableAmount")
+				amount, ok2 := n.Amount("cbc:TaxAmount")
+				percent, ok3 := n.Amount("cac:TaxCategory/cbc:Percent")
+				if !ok1 || !ok2 || !ok3 {
+					return true
+				}
+				expected := taxable.Rescale(4).Multiply(percent).Divide(num.MakeAmount(100, 0)).Rescale(2)
+				// allow for a single cent of difference caused by rounding
+				// at line level.
+				return sub(amount, expected).Abs().Compare(num.MakeAmount(1, 2)) <= 0
+			},
+		},
+		{
+			ID:      "BR-CO-18",
+			Flag:    ubl.FlagFatal,
+			Message: "An Invoice shall at least have one VAT breakdown group (BG-23).",
+			Assert:  exists(pathTaxSubtotals),
+		},
+		{
+			ID:      "BR-CO-25",
+			Flag:    ubl.FlagFatal,
+			Message: "In case the Amount due for payment (BT-115) is positive, either the Payment due date (BT-9) or the Payment terms (BT-20) shall be present.",
+			Assert: func(n *Node) bool {
+				a, ok := n.Amount(pathTotals + "/cbc:PayableAmount")
+				if !ok || !a.IsPositive() {
+					return true
+				}
+				return n.Exists("cbc:DueDate|cac:PaymentMeans/cbc:PaymentDueDate|cac:PaymentTerms/cbc:Note")
+			},
+		},
+	},
+}
+
+// documentChargesMatch checks that the total amount at the provided path
+// matches the sum of the document level allowances or charges.
+func documentChargesMatch(totals *Node, path string, charge bool) bool {
+	sum := num.AmountZero
+	for _, ac := range totals.Root().Find("cac:AllowanceCharge") {
+		if isCharge(ac) == charge {
+			sum = add(sum, ac.Sum("cbc:Amount"))
+		}
+	}
+	total, ok := totals.Amount(path)
+	if !ok {
+		return sum.IsZero()
+	}
+	return total.Equals(sum)
+}
+
+// documentTaxAmount provides the total tax amount expressed in the
+// document's currency, ignoring any additional tax totals in the tax
+// accounting currency.
+func documentTaxAmount(root *Node) num.Amount {
+	currency := root.Value("cbc:DocumentCurrencyCode")
+	for _, tt := range root.Find("cac:TaxTotal") {
+		ta := tt.First("cbc:TaxAmount")
+		if ta == nil {
+			continue
+		}
+		if c := ta.Attr("currencyID"); c == "" || c == currency {
+			a, _ := tt.Amount("cbc:TaxAmount")
+			return a
+		}
+	}
+	return num.AmountZero
+}
+
+// isCharge returns true if the allowance or charge node has its charge
+// indicator set, which may be expressed as either "true" or "1".
+func isCharge(ac *Node) bool {
+	switch ac.Value("cbc:ChargeIndicator") {
+	case "true", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+package precheck
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/num"
+)
+
+// namespaces maps the prefixes that may be used in rule paths to their
+// namespace. Steps with any other prefix, or none at all, will match
+// elements on their local name only.
+var namespaces = map[string]string{
+	"cbc": ubl.NamespaceCBC,
+	"cac": ubl.NamespaceCAC,
+}
+
+// Node is an element of the XML document that rules are evaluated
+// against.
+type Node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr
+	Text     string
+	Line     int
+	Parent   *Node
+	Children []*Node
+
+	path string
+}
+
+// parse builds the tree of nodes from the raw XML document and returns
+// the root element.
+func parse(data []byte) (*Node, error) {
+	var root *Node
+	var current *Node
+	counts := []map[string]int{make(map[string]int)}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, _ := dec.InputPos()
+			n := &Node{
+				XMLName: t.Name,
+				Attrs:   t.Attr,
+				Line:    line,
+				Parent:  current,
+			}
+			step := prefixFor(t.Name.Space) + t.Name.Local
+			siblings := counts[len(counts)-1]
+			siblings[step]++
+			step += "[" + strconv.Itoa(siblings[step]) + "]"
+			if current == nil {
+				root = n
+				n.path = "/" + step
+			} else {
+				current.Children = append(current.Children, n)
+				n.path = current.path + "/" + step
+			}
+			counts = append(counts, make(map[string]int))
+			current = n
+		case xml.EndElement:
+			current.Text = strings.TrimSpace(current.Text)
+			current = current.Parent
+			counts = counts[:len(counts)-1]
+		case xml.CharData:
+			if current != nil {
+				current.Text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, ubl.ErrUnknownDocumentType
+	}
+	return root, nil
+}
+
+func prefixFor(ns string) string {
+	for p, v := range namespaces {
+		if v == ns {
+			return p + ":"
+		}
+	}
+	if ns == "" {
+		return ""
+	}
+	return "*:"
+}
+
+// Path provides the XPath expression that identifies the node in the
+// document, such as "/*:Invoice[1]/cac:LegalMonetaryTotal[1]".
+func (n *Node) Path() string {
+	return n.path
+}
+
+// Attr returns the value of the attribute with the provided local name.
+func (n *Node) Attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Root returns the root element of the document the node belongs to.
+func (n *Node) Root() *Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// Find returns all the nodes that match the path relative to the current
+// node. Paths are a simplified form of XPath with steps separated by "/",
+// where each step may be a prefixed name such as "cbc:ID", a wildcard "*",
+// or ".." for the parent. A leading "/" starts from the root element and
+// a leading "//" matches the first step at any depth. Several paths may be
+// combined with "|".
+func (n *Node) Find(path string) []*Node {
+	var out []*Node
+	for _, p := range strings.Split(path, "|") {
+		out = append(out, n.find(strings.TrimSpace(p))...)
+	}
+	return out
+}
+
+func (n *Node) find(path string) []*Node {
+	if path == "" || path == "." {
+		return []*Node{n}
+	}
+	nodes := []*Node{n}
+	switch {
+	case strings.HasPrefix(path, "//"):
+		path = path[2:]
+		nodes = n.Root().descendants()
+		steps := strings.Split(path, "/")
+		nodes = filter(nodes, steps[0])
+		return walk(nodes, steps[1:])
+	case strings.HasPrefix(path, "/"):
+		path = path[1:]
+		nodes = []*Node{n.Root()}
+	}
+	return walk(nodes, strings.Split(path, "/"))
+}
+
+func walk(nodes []*Node, steps []string) []*Node {
+	for _, step := range steps {
+		var next []*Node
+		for _, c := range nodes {
+			switch step {
+			case "", ".":
+				next = append(next, c)
+			case "..":
+				if c.Parent != nil {
+					next = append(next, c.Parent)
+				}
+			default:
+				next = append(next, filter(c.Children, step)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func filter(nodes []*Node, step string) []*Node {
+	var out []*Node
+	for _, c := range nodes {
+		if c.matches(step) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (n *Node) descendants() []*Node {
+	out := []*Node{n}
+	for _, c := range n.Children {
+		out = append(out, c.descendants()...)
+	}
+	return out
+}
+
+func (n *Node) matches(step string) bool {
+	if step == "*" {
+		return true
+	}
+	prefix, local, ok := strings.Cut(step, ":")
+	if !ok {
+		return n.XMLName.Local == step
+	}
+	if n.XMLName.Local != local {
+		return false
+	}
+	if ns, ok := namespaces[prefix]; ok {
+		return n.XMLName.Space == ns
+	}
+	return true
+}
+
+// First returns the first node that matches the path, or nil.
+func (n *Node) First(path string) *Node {
+	if nodes := n.Find(path); len(nodes) > 0 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// Exists returns true if at least one node matches the path and has
+// some content.
+func (n *Node) Exists(path string) bool {
+	for _, c := range n.Find(path) {
+		if c.Text != "" || len(c.Children) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the text of the first node that matches the path.
+func (n *Node) Value(path string) string {
+	if c := n.First(path); c != nil {
+		return c.Text
+	}
+	return ""
+}
+
+// Amount returns the decimal value of the first node that matches the
+// path, or false if it is not present or could not be parsed.
+func (n *Node) Amount(path string) (num.Amount, bool) {
+	c := n.First(path)
+	if c == nil {
+		return num.AmountZero, false
+	}
+	a, err := num.AmountFromString(c.Text)
+	if err != nil {
+		return num.AmountZero, false
+	}
+	return a, true
+}
+
+// Sum adds up the decimal values of all the nodes that match the path,
+// ignoring any that cannot be parsed.
+func (n *Node) Sum(path string) num.Amount {
+	total := num.AmountZero
+	for _, c := range n.Find(path) {
+		if a, err := num.AmountFromString(c.Text); err == nil {
+			total = add(total, a)
+		}
+	}
+	return total
+}
+
+// add sums two amounts without losing the precision of either.
+func add(a, b num.Amount) num.Amount {
+	if b.Exp() > a.Exp() {
+		a = a.Rescale(b.Exp())
+	}
+	return a.Add(b)
+}
+
+// sub subtracts b from a without losing the precision of either.
+func sub(a, b num.Amount) num.Amount {
+	return add(a, b.Negate())
+}
@@ -0,0 +1,146 @@
+package precheck
+
+import (
+	"math"
+
+	ubl "github.com/invopop/gobl.ubl"
+)
+
+// PeppolBIS3 contains a subset of the additional rules defined by Peppol
+// BIS Billing 3.0 on top of EN16931: PEPPOL-EN16931-R001, R003, R005,
+// R008, R010, R020, R053, R061, R120, R121 and R130.
+var PeppolBIS3 = &RuleSet{
+	Name:           "Peppol BIS Billing 3.0",
+	Specifications: []string{"urn:fdc:peppol.eu:2017:poacc:billing:3.0"},
+	Rules: []*Rule{
+		{
+			ID:      "PEPPOL-EN16931-R001",
+			Flag:    ubl.FlagFatal,
+			Message: "Business process MUST be provided.",
+			Assert:  exists("cbc:ProfileID"),
+		},
+		{
+			ID:      "PEPPOL-EN16931-R003",
+			Flag:    ubl.FlagFatal,
+			Message: "A buyer reference or purchase order reference MUST be provided.",
+			Assert:  exists("cbc:BuyerReference|cac:OrderReference/cbc:ID"),
+		},
+		{
+			ID:      "PEPPOL-EN16931-R005",
+			Flag:    ubl.FlagFatal,
+			Message: "VAT accounting currency code MUST be different from invoice currency code when provided.",
+			Assert: func(n *Node) bool {
+				tc := n.Value("cbc:TaxCurrencyCode")
+				return tc == "" || tc != n.Value("cbc:DocumentCurrencyCode")
+			},
+		},
+		{
+			ID:      "PEPPOL-EN16931-R008",
+			Flag:    ubl.FlagFatal,
+			Context: "//*",
+			Message: "Document MUST not contain empty elements.",
+			Assert: func(n *Node) bool {
+				return len(n.Children) > 0 || n.Text != ""
+			},
+		},
+		{
+			ID:      "PEPPOL-EN16931-R010",
+			Flag:    ubl.FlagFatal,
+			Message: "Buyer electronic address MUST be provided.",
+			Assert:  exists(pathCustomerParty + "/cbc:EndpointID"),
+		},
+		{
+			ID:      "PEPPOL-EN16931-R020",
+			Flag:    ubl.FlagFatal,
+			Message: "Seller electronic address MUST be provided.",
+			Assert:  exists(pathSupplierParty + "/cbc:EndpointID"),
+		},
+		{
+			ID:      "PEPPOL-EN16931-R053",
+			Flag:    ubl.FlagFatal,
+			Message: "Only one tax total with tax subtotals MUST be provided.",
+			Assert: func(n *Node) bool {
+				count := 0
+				for _, tt := range n.Find("cac:TaxTotal") {
+					if tt.Exists("cac:TaxSubtotal") {
+						count++
+					}
+				}
+				return count == 1
+			},
+		},
+		{
+			ID:      "PEPPOL-EN16931-R061",
+			Flag:    ubl.FlagFatal,
+			Context: "cac:PaymentMeans",
+			Message: "Mandate reference MUST be provided for direct debit.",
+			Assert: func(n *Node) bool {
+				switch n.Value("cbc:PaymentMeansCode") {
+				case "49", "59":
+					return n.Exists("cac:PaymentMandate/cbc:ID")
+				}
+				return true
+			},
+		},
+		{
+			ID:      "PEPPOL-EN16931-R120",
+			Flag:    ubl.FlagFatal,
+			Context: pathLines,
+			Message: "Invoice line net amount MUST equal (Invoiced quantity * (Item net price/item price base quantity) + Sum of invoice line charge amount - sum of invoice line allowance amount",
+			Assert:  lineAmountMatches,
+		},
+		{
+			ID:      "PEPPOL-EN16931-R121",
+			Flag:    ubl.FlagFatal,
+			Context: linePath("cac:Price/cbc:BaseQuantity"),
+			Message: "Base quantity MUST be a positive number above zero.",
+			Assert: func(n *Node) bool {
+				a, ok := n.Amount(".")
+				return ok && a.IsPositive()
+			},
+		},
+		{
+			ID:      "PEPPOL-EN16931-R130",
+			Flag:    ubl.FlagFatal,
+			Context: linePath("cac:Price/cbc:BaseQuantity"),
+			Message: "Unit code of price base quantity MUST be same as invoiced quantity.",
+			Assert: func(n *Node) bool {
+				unit := n.Attr("unitCode")
+				if unit == "" {
+					return true
+				}
+				q := n.Parent.Parent.First(pathLineQuantity)
+				return q == nil || q.Attr("unitCode") == unit
+			},
+		},
+	},
+}
+
+// lineAmountMatches checks the line net amount against the quantity,
+// price and line level allowances and charges, allowing for the same
+// 0.02 of slack permitted by Peppol.
+func lineAmountMatches(n *Node) bool {
+	amount, ok := n.Amount("cbc:LineExtensionAmount")
+	if !ok {
+		return true
+	}
+	qty, ok1 := n.Amount(pathLineQuantity)
+	price, ok2 := n.Amount("cac:Price/cbc:PriceAmount")
+	if !ok1 || !ok2 {
+		return true
+	}
+	base := 1.0
+	if b, ok := n.Amount("cac:Price/cbc:BaseQuantity"); ok && !b.IsZero() {
+		base = b.Float64()
+	}
+	expected := qty.Float64() * (price.Float64() / base)
+	for _, ac := range n.Find("cac:AllowanceCharge") {
+		a, _ := ac.Amount("cbc:Amount")
+		if isCharge(ac) {
+			expected += a.Float64()
+		} else {
+			expected -= a.Float64()
+		}
+	}
+	return math.Abs(amount.Float64()-expected) <= 0.02+1e-9
+}
@@ -0,0 +1,167 @@
+// Package precheck runs a small number of business rules against UBL
+// invoices and credit notes in pure Go, as a quick pre-check that spots
+// the most common problems without Java or a remote service.
+//
+// It is not a validator. The rules are ported by hand from the EN16931,
+// Peppol BIS Billing 3.0 and XRechnung Schematron files, and only those
+// listed in each rule set are covered, so documents that pass the checks
+// may still be rejected by the official artefacts. These should be used,
+// for example through a Phive service, when the document needs to be
+// validated.
+//
+// Rules follow the Schematron model: a context path selects the elements
+// each rule applies to, and an assertion must hold for every one of them.
+// Rule sets are selected automatically using the document's specification
+// identifier.
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ubl "github.com/invopop/gobl.ubl"
+)
+
+// Rule is a single assertion to evaluate against a document.
+type Rule struct {
+	// ID of the rule, such as BR-01 or PEPPOL-EN16931-R001.
+	ID string
+	// Flag is either ubl.FlagFatal or ubl.FlagWarning.
+	Flag string
+	// Context is the path, relative to the root element, of the nodes
+	// the assertion will be evaluated on. An empty context implies the
+	// root element itself.
+	Context string
+	// Message describes what is expected by the rule.
+	Message string
+	// Assert returns true when the node complies with the rule.
+	Assert func(n *Node) bool
+}
+
+// RuleSet groups together the rules defined by a specification.
+type RuleSet struct {
+	// Name of the rule set.
+	Name string
+	// Specifications contains the fragments of the specification
+	// identifier (BT-24) that indicate the rule set should be applied.
+	Specifications []string
+	// Rules to be evaluated.
+	Rules []*Rule
+}
+
+// RuleSets contains the rule sets that will be considered by Check, in
+// order.
+var RuleSets = []*RuleSet{
+	EN16931,
+	PeppolBIS3,
+	XRechnung,
+}
+
+// AppliesTo returns true if the rule set should be used to check
+// documents with the provided specification identifier.
+func (rs *RuleSet) AppliesTo(customizationID string) bool {
+	for _, s := range rs.Specifications {
+		if strings.Contains(customizationID, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check parses the UBL document and runs all the rule sets that apply to
+// its specification identifier, returning the list of findings. An error
+// is only returned if the document cannot be parsed.
+func Check(data []byte) ([]ubl.Finding, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	cid := root.Value("cbc:CustomizationID")
+	var out []ubl.Finding
+	for _, rs := range RuleSets {
+		if rs.AppliesTo(cid) {
+			out = append(out, rs.run(root)...)
+		}
+	}
+	return out, nil
+}
+
+// Run evaluates the provided rule sets against the UBL document,
+// regardless of its specification identifier.
+func Run(data []byte, sets ...*RuleSet) ([]ubl.Finding, error) {
+	root, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+	var out []ubl.Finding
+	for _, rs := range sets {
+		out = append(out, rs.run(root)...)
+	}
+	return out, nil
+}
+
+// Checker implements the ubl.Validator interface so that the rule sets
+// may be run automatically during conversion and parsing.
+type Checker struct {
+	sets []*RuleSet
+}
+
+// New prepares a checker that will evaluate the provided rule sets. If
+// none are provided, the rule sets that apply to each document's
+// specification identifier will be used, just like Check.
+func New(sets ...*RuleSet) *Checker {
+	return &Checker{sets: sets}
+}
+
+// Validate runs the rule sets against the document, as required by the
+// ubl.Validator interface.
+func (c *Checker) Validate(_ context.Context, data []byte) ([]ubl.Finding, error) {
+	if len(c.sets) == 0 {
+		return Check(data)
+	}
+	return Run(data, c.sets...)
+}
+
+func (rs *RuleSet) run(root *Node) []ubl.Finding {
+	var out []ubl.Finding
+	for _, r := range rs.Rules {
+		for _, n := range root.Find(r.Context) {
+			if r.Assert(n) {
+				continue
+			}
+			out = append(out, ubl.Finding{
+				RuleID:   r.ID,
+				Flag:     r.Flag,
+				Location: n.Path(),
+				Line:     n.Line,
+				Message:  r.Message,
+			})
+		}
+	}
+	return out
+}
+
+// exists prepares an assertion that expects the path to be present.
+func exists(path string) func(n *Node) bool {
+	return func(n *Node) bool {
+		return n.Exists(path)
+	}
+}
+
+// oneOf prepares an assertion that expects the value of the path, if
+// present, to be one of the provided codes.
+func oneOf(path string, codes ...string) func(n *Node) bool {
+	return func(n *Node) bool {
+		v := n.Value(path)
+		if v == "" {
+			return true
+		}
+		for _, c := range codes {
+			if v == c {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,159 @@
+package precheck_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl.ubl/precheck"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadXML(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "test", "data", name))
+	require.NoError(t, err)
+	return data
+}
+
+func ruleIDs(findings []ubl.Finding) []string {
+	ids := make([]string, len(findings))
+	for i, f := range findings {
+		ids[i] = f.RuleID
+	}
+	return ids
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("valid documents", func(t *testing.T) {
+		for _, name := range []string{
+			"parse/peppol/base-example.xml",
+			"parse/peppol/base-creditnote-correction.xml",
+			"parse/en16931/ubl-example2.xml",
+			"convert/en16931/out/invoice-complete.xml",
+			"convert/xrechnung/out/invoice-xr-minimal.xml",
+		} {
+			findings, err := precheck.Check(loadXML(t, name))
+			require.NoError(t, err)
+			assert.Empty(t, findings, name)
+		}
+	})
+
+	t.Run("business rule failures", func(t *testing.T) {
+		data := string(loadXML(t, "parse/peppol/base-example.xml"))
+		data = strings.Replace(data, `<cbc:PayableAmount currencyID="EUR">1656.25</cbc:PayableAmount>`, `<cbc:PayableAmount currencyID="EUR">1000.00</cbc:PayableAmount>`, 1)
+		data = strings.Replace(data, `<cbc:EndpointID schemeID="0088">9482348239847239874</cbc:EndpointID>`, "", 1)
+		data = strings.Replace(data, "<cbc:BuyerReference>0150abc</cbc:BuyerReference>", "<cbc:BuyerReference></cbc:BuyerReference>", 1)
+
+		findings, err := precheck.Check([]byte(data))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"BR-CO-16", "PEPPOL-EN16931-R003", "PEPPOL-EN16931-R008", "PEPPOL-EN16931-R020"}, ruleIDs(findings))
+
+		for _, f := range findings {
+			assert.True(t, f.IsFatal())
+			assert.False(t, f.Syntax)
+			switch f.RuleID {
+			case "BR-CO-16":
+				assert.Equal(t, "/*:Invoice[1]/cac:LegalMonetaryTotal[1]", f.Location)
+			case "PEPPOL-EN16931-R008":
+				assert.Equal(t, "/*:Invoice[1]/cbc:BuyerReference[1]", f.Location)
+				assert.Equal(t, 13, f.Line)
+			}
+		}
+	})
+
+	t.Run("xrechnung rules", func(t *testing.T) {
+		data := string(loadXML(t, "convert/xrechnung/out/invoice-xr-minimal.xml"))
+		data = strings.Replace(data, "<cbc:BuyerReference>", "<cbc:AccountingCost>", 1)
+		data = strings.Replace(data, "</cbc:BuyerReference>", "</cbc:AccountingCost>", 1)
+
+		findings, err := precheck.Check([]byte(data))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"BR-DE-15"}, ruleIDs(findings))
+	})
+
+	t.Run("other specifications", func(t *testing.T) {
+		findings, err := precheck.Check(loadXML(t, "convert/out/oioubl21-invoice-minimal.xml"))
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("malformed XML", func(t *testing.T) {
+		_, err := precheck.Check([]byte("<Invoice>"))
+		assert.ErrorContains(t, err, "parsing document")
+	})
+}
+
+func TestRuleSets(t *testing.T) {
+	// The covered rules are listed in the documentation, which must be
+	// updated whenever rules are added.
+	ids := func(rs *precheck.RuleSet) []string {
+		out := make([]string, len(rs.Rules))
+		for i, r := range rs.Rules {
+			out[i] = r.ID
+		}
+		return out
+	}
+	assert.Equal(t, []string{
+		"BR-01", "BR-02", "BR-03", "BR-04", "BR-05", "BR-06", "BR-07", "BR-08",
+		"BR-09", "BR-10", "BR-11", "BR-12", "BR-13", "BR-14", "BR-15", "BR-16",
+		"BR-21", "BR-22", "BR-23", "BR-24", "BR-25", "BR-26", "BR-27",
+		"BR-45", "BR-46", "BR-47", "BR-48",
+		"BR-CO-10", "BR-CO-11", "BR-CO-12", "BR-CO-13", "BR-CO-14", "BR-CO-15",
+		"BR-CO-16", "BR-CO-17", "BR-CO-18", "BR-CO-25",
+	}, ids(precheck.EN16931))
+	assert.Equal(t, []string{
+		"PEPPOL-EN16931-R001", "PEPPOL-EN16931-R003", "PEPPOL-EN16931-R005",
+		"PEPPOL-EN16931-R008", "PEPPOL-EN16931-R010", "PEPPOL-EN16931-R020",
+		"PEPPOL-EN16931-R053", "PEPPOL-EN16931-R061", "PEPPOL-EN16931-R120",
+		"PEPPOL-EN16931-R121", "PEPPOL-EN16931-R130",
+	}, ids(precheck.PeppolBIS3))
+	assert.Equal(t, []string{
+		"BR-DE-1", "BR-DE-2", "BR-DE-3", "BR-DE-4", "BR-DE-5", "BR-DE-6",
+		"BR-DE-7", "BR-DE-8", "BR-DE-9", "BR-DE-15", "BR-DE-16", "BR-DE-17",
+		"BR-DE-21",
+	}, ids(precheck.XRechnung))
+}
+
+func TestRun(t *testing.T) {
+	rs := &precheck.RuleSet{
+		Name: "Custom",
+		Rules: []*precheck.Rule{
+			{
+				ID:      "CUSTOM-01",
+				Flag:    ubl.FlagWarning,
+				Context: "cac:InvoiceLine",
+				Message: "Lines should include a note.",
+				Assert: func(n *precheck.Node) bool {
+					return n.Exists("cbc:Note")
+				},
+			},
+		},
+	}
+	findings, err := precheck.Run(loadXML(t, "parse/peppol/base-example.xml"), rs)
+	require.NoError(t, err)
+	require.Len(t, findings, 2)
+	f := findings[1]
+	assert.Equal(t, "CUSTOM-01", f.RuleID)
+	assert.False(t, f.IsFatal())
+	assert.Equal(t, "/*:Invoice[1]/cac:InvoiceLine[2]", f.Location)
+	assert.Equal(t, 179, f.Line)
+}
+
+func TestChecker(t *testing.T) {
+	var _ ubl.Validator = precheck.New()
+
+	data := string(loadXML(t, "parse/peppol/base-example.xml"))
+	doc, err := ubl.Parse([]byte(data), ubl.WithValidator(precheck.New()))
+	require.NoError(t, err)
+	assert.IsType(t, &ubl.Invoice{}, doc)
+
+	data = strings.Replace(data, "<cbc:BuyerReference>0150abc</cbc:BuyerReference>", "", 1)
+	_, err = ubl.Parse([]byte(data), ubl.WithValidator(precheck.New(precheck.PeppolBIS3)))
+	var ve *ubl.ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.Equal(t, []string{"PEPPOL-EN16931-R003"}, ruleIDs(ve.Findings))
+}
@@ -0,0 +1,108 @@
+package precheck
+
+import ubl "github.com/invopop/gobl.ubl"
+
+// XRechnung contains a subset of the national rules of the German CIUS
+// on top of EN16931: BR-DE-1 to BR-DE-9, BR-DE-15 to BR-DE-17 and
+// BR-DE-21.
+var XRechnung = &RuleSet{
+	Name:           "XRechnung",
+	Specifications: []string{"urn:xeinkauf.de:kosit:xrechnung"},
+	Rules: []*Rule{
+		{
+			ID:      "BR-DE-1",
+			Flag:    ubl.FlagFatal,
+			Message: "Eine Rechnung (INVOICE) muss Angaben zu \"PAYMENT INSTRUCTIONS\" (BG-16) enthalten.",
+			Assert:  exists("cac:PaymentMeans"),
+		},
+		{
+			ID:      "BR-DE-2",
+			Flag:    ubl.FlagFatal,
+			Message: "Die Gruppe \"SELLER CONTACT\" (BG-6) muss übermittelt werden.",
+			Assert:  exists(pathSupplierParty + "/cac:Contact"),
+		},
+		{
+			ID:      "BR-DE-3",
+			Flag:    ubl.FlagFatal,
+			Context: pathSupplierParty + "/cac:PostalAddress",
+			Message: "Das Element \"Seller city\" (BT-37) muss übermittelt werden.",
+			Assert:  exists("cbc:CityName"),
+		},
+		{
+			ID:      "BR-DE-4",
+			Flag:    ubl.FlagFatal,
+			Context: pathSupplierParty + "/cac:PostalAddress",
+			Message: "Das Element \"Seller post code\" (BT-38) muss übermittelt werden.",
+			Assert:  exists("cbc:PostalZone"),
+		},
+		{
+			ID:      "BR-DE-5",
+			Flag:    ubl.FlagFatal,
+			Context: pathSupplierParty + "/cac:Contact",
+			Message: "Das Element \"Seller contact point\" (BT-41) muss übermittelt werden.",
+			Assert:  exists("cbc:Name"),
+		},
+		{
+			ID:      "BR-DE-6",
+			Flag:    ubl.FlagFatal,
+			Context: pathSupplierParty + "/cac:Contact",
+			Message: "Das Element \"Seller contact telephone number\" (BT-42) muss übermittelt werden.",
+			Assert:  exists("cbc:Telephone"),
+		},
+		{
+			ID:      "BR-DE-7",
+			Flag:    ubl.FlagFatal,
+			Context: pathSupplierParty + "/cac:Contact",
+			Message: "Das Element \"Seller contact email address\" (BT-43) muss übermittelt werden.",
+			Assert:  exists("cbc:ElectronicMail"),
+		},
+		{
+			ID:      "BR-DE-8",
+			Flag:    ubl.FlagFatal,
+			Context: pathCustomerParty + "/cac:PostalAddress",
+			Message: "Das Element \"Buyer city\" (BT-52) muss übermittelt werden.",
+			Assert:  exists("cbc:CityName"),
+		},
+		{
+			ID:      "BR-DE-9",
+			Flag:    ubl.FlagFatal,
+			Context: pathCustomerParty + "/cac:PostalAddress",
+			Message: "Das Element \"Buyer post code\" (BT-53) muss übermittelt werden.",
+			Assert:  exists("cbc:PostalZone"),
+		},
+		{
+			ID:      "BR-DE-15",
+			Flag:    ubl.FlagFatal,
+			Message: "Das Element \"Buyer reference\" (BT-10) muss übermittelt werden.",
+			Assert:  exists("cbc:BuyerReference"),
+		},
+		{
+			ID:      "BR-DE-16",
+			Flag:    ubl.FlagFatal,
+			Message: "In der Rechnung muss mindestens eines der Elemente \"Seller VAT identifier\" (BT-31), \"Seller tax registration identifier\" (BT-32) oder \"SELLER TAX REPRESENTATIVE PARTY\" (BG-11) übermittelt werden.",
+			Assert: func(n *Node) bool {
+				for _, c := range n.Find(pathTaxSubtotals + "/cac:TaxCategory/cbc:ID") {
+					if c.Text == "O" {
+						return true
+					}
+				}
+				return n.Exists(pathSupplierParty + "/cac:PartyTaxScheme/cbc:CompanyID|cac:TaxRepresentativeParty")
+			},
+		},
+		{
+			ID:      "BR-DE-17",
+			Flag:    ubl.FlagWarning,
+			Message: "Mit dem Element \"Invoice type code\" (BT-3) sollen ausschließlich folgende Codes aus der Codeliste UNTDID 1001 übermittelt werden: 326 (Partial invoice), 380 (Commercial invoice), 384 (Corrected invoice), 389 (Self-billed invoice), 381 (Credit note), 875 (Partial construction invoice), 876 (Partial final construction invoice), 877 (Final construction invoice).",
+			Assert:  oneOf(pathTypeCode, "326", "380", "384", "389", "381", "875", "876", "877"),
+		},
+		{
+			ID:      "BR-DE-21",
+			Flag:    ubl.FlagWarning,
+			Message: "Das Element \"Specification identifier\" (BT-24) soll syntaktisch der Kennung des Standards XRechnung entsprechen.",
+			Assert: oneOf("cbc:CustomizationID",
+				"urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0",
+				"urn:cen.eu:en16931:2017#compliant#urn:xeinkauf.de:kosit:xrechnung_3.0#conformant#urn:xeinkauf.de:kosit:extension:xrechnung_3.0",
+			),
+		},
+	},
+}
@@ -0,0 +1,218 @@
+package ubl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/gobl/addons/eu/en16931"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cbc"
+)
+
+// ContextROCIUS defines the context for the Romanian RO_CIUS documents
+// submitted to the ANAF e-Factura system.
+var ContextROCIUS = Context{
+	CustomizationID: "urn:cen.eu:en16931:2017#compliant#urn:efactura.mfinante.ro:CIUS-RO:1.0.1",
+	Addons:          []cbc.Key{en16931.V2017},
+	Hooks:           roCIUSHooks{},
+}
+
+// roBucharest is the ISO 3166-2:RO code for Bucharest, whose addresses
+// use the sector as the city name.
+const roBucharest = "RO-B"
+
+// roCounties maps the ISO 3166-2:RO county codes to their names without
+// diacritics.
+var roCounties = map[string]string{
+	"AB": "Alba",
+	"AR": "Arad",
+	"AG": "Arges",
+	"BC": "Bacau",
+	"BH": "Bihor",
+	"BN": "Bistrita-Nasaud",
+	"BT": "Botosani",
+	"BV": "Brasov",
+	"BR": "Braila",
+	"B":  "Bucuresti",
+	"BZ": "Buzau",
+	"CS": "Caras-Severin",
+	"CL": "Calarasi",
+	"CJ": "Cluj",
+	"CT": "Constanta",
+	"CV": "Covasna",
+	"DB": "Dambovita",
+	"DJ": "Dolj",
+	"GL": "Galati",
+	"GR": "Giurgiu",
+	"GJ": "Gorj",
+	"HR": "Harghita",
+	"HD": "Hunedoara",
+	"IL": "Ialomita",
+	"IS": "Iasi",
+	"IF": "Ilfov",
+	"MM": "Maramures",
+	"MH": "Mehedinti",
+	"MS": "Mures",
+	"NT": "Neamt",
+	"OT": "Olt",
+	"PH": "Prahova",
+	"SM": "Satu Mare",
+	"SJ": "Salaj",
+	"SB": "Sibiu",
+	"SV": "Suceava",
+	"TR": "Teleorman",
+	"TM": "Timis",
+	"TL": "Tulcea",
+	"VS": "Vaslui",
+	"VL": "Valcea",
+	"VN": "Vrancea",
+}
+
+var (
+	roSector     = regexp.MustCompile(`(?i)\bsector(?:ul)?\s*([1-6])\b`)
+	roSectorCode = regexp.MustCompile(`^SECTOR[1-6]$`)
+)
+
+var roDiacritics = strings.NewReplacer(
+	"ă", "a", "Ă", "A", "â", "a", "Â", "A", "î", "i", "Î", "I",
+	"ș", "s", "Ș", "S", "ş", "s", "Ş", "S", "ț", "t", "Ț", "T", "ţ", "t", "Ţ", "T",
+)
+
+// roCountyKey simplifies county names for comparison.
+func roCountyKey(name string) string {
+	name = strings.ToLower(roDiacritics.Replace(name))
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(name)
+}
+
+// roCountySubentity provides the ISO 3166-2:RO code for the region, which
+// may already be a code or the county's name, or the region itself if
+// the county is unknown.
+func roCountySubentity(region string) string {
+	code := strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(region), "RO-"))
+	if _, ok := roCounties[code]; ok {
+		return "RO-" + code
+	}
+	key := roCountyKey(region)
+	switch key {
+	case "bucharest", "municipiulbucuresti":
+		return roBucharest
+	}
+	for c, name := range roCounties {
+		if key == roCountyKey(name) || key == "judetul"+roCountyKey(name) {
+			return "RO-" + c
+		}
+	}
+	return region
+}
+
+// normalizeROAddress converts the region of Romanian addresses into the
+// ISO 3166-2:RO format, and the city of Bucharest addresses into their
+// sector code.
+func normalizeROAddress(addr *PostalAddress) {
+	if addr == nil || addr.Country == nil || addr.Country.IdentificationCode != "RO" || addr.CountrySubentity == nil {
+		return
+	}
+	sub := roCountySubentity(*addr.CountrySubentity)
+	addr.CountrySubentity = &sub
+	if sub != roBucharest || addr.CityName == nil {
+		return
+	}
+	if m := roSector.FindStringSubmatch(*addr.CityName); m != nil {
+		city := "SECTOR" + m[1]
+		addr.CityName = &city
+	}
+}
+
+// roCIUSHooks checks the generated documents against the RO_CIUS
+// addresses and length constraints.
+type roCIUSHooks struct {
+	BaseHooks
+}
+
+// AfterConvert normalizes the Romanian addresses of the generated invoice
+// and checks it, returning the list of problems found.
+func (roCIUSHooks) AfterConvert(_ *bill.Invoice, out *Invoice) error {
+	for _, p := range []*Party{out.AccountingSupplierParty.Party, out.AccountingCustomerParty.Party} {
+		if p != nil {
+			normalizeROAddress(p.PostalAddress)
+		}
+	}
+	for _, d := range out.Delivery {
+		if d != nil && d.DeliveryLocation != nil {
+			normalizeROAddress(d.DeliveryLocation.Address)
+		}
+	}
+
+	var errs []error
+	maxLen := func(field string, v *string, n int) {
+		if v != nil && utf8.RuneCountInString(*v) > n {
+			errs = append(errs, fmt.Errorf("ro-cius: %s must not exceed %d characters", field, n))
+		}
+	}
+
+	for _, n := range out.Note {
+		maxLen("invoice note (BT-22)", &n, 300)
+	}
+	parties := []struct {
+		name  string
+		bt    [4]string
+		party *Party
+	}{
+		{"seller", [4]string{"BT-27", "BT-35", "BT-36", "BT-37"}, out.AccountingSupplierParty.Party},
+		{"buyer", [4]string{"BT-44", "BT-50", "BT-51", "BT-52"}, out.AccountingCustomerParty.Party},
+	}
+	for _, p := range parties {
+		if p.party == nil {
+			continue
+		}
+		if le := p.party.PartyLegalEntity; le != nil {
+			maxLen(fmt.Sprintf("%s name (%s)", p.name, p.bt[0]), le.RegistrationName, 200)
+		}
+		addr := p.party.PostalAddress
+		if addr == nil {
+			continue
+		}
+		maxLen(fmt.Sprintf("%s street (%s)", p.name, p.bt[1]), addr.StreetName, 150)
+		maxLen(fmt.Sprintf("%s additional street (%s)", p.name, p.bt[2]), addr.AdditionalStreetName, 100)
+		maxLen(fmt.Sprintf("%s city (%s)", p.name, p.bt[3]), addr.CityName, 50)
+		if err := checkROAddress(p.name, addr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	lines := out.InvoiceLines
+	if len(out.CreditNoteLines) > 0 {
+		lines = out.CreditNoteLines
+	}
+	for _, l := range lines {
+		for _, n := range l.Note {
+			maxLen(fmt.Sprintf("line %s note (BT-127)", l.ID), &n, 300)
+		}
+		if l.Item != nil {
+			maxLen(fmt.Sprintf("line %s item name (BT-153)", l.ID), &l.Item.Name, 100)
+			maxLen(fmt.Sprintf("line %s item description (BT-154)", l.ID), l.Item.Description, 200)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkROAddress ensures Romanian addresses use ISO 3166-2:RO county
+// codes, and Bucharest addresses a sector as the city.
+func checkROAddress(party string, addr *PostalAddress) error {
+	if addr.Country == nil || addr.Country.IdentificationCode != "RO" {
+		return nil
+	}
+	if addr.CountrySubentity == nil {
+		return fmt.Errorf("ro-cius: %s county is required", party)
+	}
+	sub := *addr.CountrySubentity
+	if _, ok := roCounties[strings.TrimPrefix(sub, "RO-")]; !ok || !strings.HasPrefix(sub, "RO-") {
+		return fmt.Errorf("ro-cius: %s county %q is not an ISO 3166-2:RO code", party, sub)
+	}
+	if sub == roBucharest && (addr.CityName == nil || !roSectorCode.MatchString(*addr.CityName)) {
+		return fmt.Errorf("ro-cius: %s city in Bucharest must be a sector, SECTOR1 to SECTOR6", party)
+	}
+	return nil
+}
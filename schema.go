@@ -0,0 +1,323 @@
+//go:build cgo
+
+package ubl
+
+/*
+#cgo pkg-config: libxml-2.0
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <libxml/tree.h>
+#include <libxml/xmlerror.h>
+#include <libxml/xmlschemas.h>
+
+#define SCHEMA_MAX_ERRORS 256
+
+typedef struct {
+	int count;
+	char *messages[SCHEMA_MAX_ERRORS];
+	int lines[SCHEMA_MAX_ERRORS];
+	xmlNodePtr nodes[SCHEMA_MAX_ERRORS];
+} schemaErrors;
+
+static void schemaCollectError(void *ctx, xmlErrorPtr err) {
+	schemaErrors *errs = (schemaErrors *) ctx;
+	if (err == NULL || errs->count >= SCHEMA_MAX_ERRORS) {
+		return;
+	}
+	errs->messages[errs->count] = strdup(err->message != NULL ? err->message : "");
+	errs->lines[errs->count] = err->line;
+	errs->nodes[errs->count] = (xmlNodePtr) err->node;
+	errs->count++;
+}
+
+static int schemaValidate(uintptr_t schema, uintptr_t doc, schemaErrors *errs) {
+	int res;
+	xmlSchemaValidCtxtPtr ctxt = xmlSchemaNewValidCtxt((xmlSchemaPtr) schema);
+	if (ctxt == NULL) {
+		return -1;
+	}
+	xmlSchemaSetValidStructuredErrors(ctxt, (xmlStructuredErrorFunc) schemaCollectError, errs);
+	res = xmlSchemaValidateDoc(ctxt, (xmlDocPtr) doc);
+	xmlSchemaFreeValidCtxt(ctxt);
+	return res;
+}
+
+static void schemaFreeErrors(schemaErrors *errs) {
+	int i;
+	for (i = 0; i < errs->count; i++) {
+		free(errs->messages[i]);
+	}
+	free(errs);
+}
+
+static char *schemaError(schemaErrors *errs, int i) { return errs->messages[i]; }
+static int schemaErrorLine(schemaErrors *errs, int i) { return errs->lines[i]; }
+static xmlNodePtr schemaErrorNode(schemaErrors *errs, int i) { return errs->nodes[i]; }
+*/
+import "C"
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/lestrrat-go/libxml2"
+	"github.com/lestrrat-go/libxml2/xsd"
+)
+
+//go:embed schema/common schema/maindoc
+var schemaFS embed.FS
+
+// schemaFiles maps root namespaces to their main document schema.
+var schemaFiles = map[string]string{
+	NamespaceUBLInvoice:             "schema/maindoc/UBL-Invoice-2.1.xsd",
+	NamespaceUBLCreditNote:          "schema/maindoc/UBL-CreditNote-2.1.xsd",
+	NamespaceUBLOrder:               "schema/maindoc/UBL-Order-2.1.xsd",
+	NamespaceUBLDespatchAdvice:      "schema/maindoc/UBL-DespatchAdvice-2.1.xsd",
+	NamespaceUBLApplicationResponse: "schema/maindoc/UBL-ApplicationResponse-2.1.xsd",
+}
+
+var (
+	schemasOnce sync.Once
+	schemas     map[string]*xsd.Schema
+	schemasErr  error
+)
+
+// schemaErrorElement extracts the namespace of the element from libxml2
+// validation messages, which look like: "Element '{ns}Name': ...".
+var schemaErrorElement = regexp.MustCompile(`^Element '\{([^}]*)\}`)
+
+// ValidateSchema checks the provided Invoice, CreditNote, Order,
+// DespatchAdvice or ApplicationResponse XML document against the bundled
+// UBL 2.1 XSD schemas. A *ValidationError is returned with a finding per
+// problem detected, including the XPath and line number of the offending
+// element as reported by libxml2.
+func ValidateSchema(data []byte) error {
+	ns, err := schemaRootNamespace(data)
+	if errors.Is(err, ErrUnknownDocumentType) {
+		return err
+	}
+	if err != nil {
+		return schemaSyntaxError(err)
+	}
+	if _, ok := schemaFiles[ns]; !ok {
+		return ErrUnsupportedDocumentType
+	}
+
+	schemasOnce.Do(loadSchemas)
+	if schemasErr != nil {
+		return schemasErr
+	}
+
+	doc, err := libxml2.Parse(data)
+	if err != nil {
+		return schemaSyntaxError(err)
+	}
+	defer doc.Free()
+
+	errs := (*C.schemaErrors)(C.calloc(1, C.sizeof_schemaErrors))
+	defer C.schemaFreeErrors(errs)
+	res := C.schemaValidate(C.uintptr_t(schemas[ns].Pointer()), C.uintptr_t(doc.Pointer()), errs)
+	if res < 0 {
+		return errors.New("schema validation could not be performed")
+	}
+	if res == 0 {
+		return nil
+	}
+
+	findings := make([]Finding, 0, int(errs.count))
+	for i := C.int(0); i < errs.count; i++ {
+		findings = append(findings, schemaFinding(
+			C.GoString(C.schemaError(errs, i)),
+			int(C.schemaErrorLine(errs, i)),
+			C.schemaErrorNode(errs, i),
+		))
+	}
+	return &ValidationError{Findings: findings}
+}
+
+// SchemaValidator checks documents against the bundled UBL 2.1 XSD
+// schemas, and may be used with WithValidator. Document types without a
+// bundled schema are ignored.
+type SchemaValidator struct{}
+
+// NewSchemaValidator prepares a new XSD schema validator.
+func NewSchemaValidator() *SchemaValidator {
+	return new(SchemaValidator)
+}
+
+// Validate checks the document against the XSD schemas.
+func (v *SchemaValidator) Validate(_ context.Context, data []byte) ([]Finding, error) {
+	err := ValidateSchema(data)
+	if err == nil || errors.Is(err, ErrUnsupportedDocumentType) {
+		return nil, nil
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Findings, nil
+	}
+	return nil, err
+}
+
+// loadSchemas extracts the embedded schemas into a temporary directory so
+// that libxml2 is able to resolve the imports between files.
+func loadSchemas() {
+	dir, err := os.MkdirTemp("", "gobl-ubl-schema")
+	if err != nil {
+		schemasErr = err
+		return
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	err = fs.WalkDir(schemaFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		data, err := schemaFS.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	})
+	if err != nil {
+		schemasErr = err
+		return
+	}
+
+	schemas = make(map[string]*xsd.Schema)
+	for ns, path := range schemaFiles {
+		s, err := xsd.ParseFromFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			schemasErr = err
+			return
+		}
+		schemas[ns] = s
+	}
+}
+
+// schemaRootNamespace checks the document is well formed, so that syntax
+// errors are reported with their line, and provides the root element's
+// namespace.
+func schemaRootNamespace(data []byte) (string, error) {
+	ns := ""
+	root := false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if t, ok := tok.(xml.StartElement); ok && !root {
+			ns = t.Name.Space
+			root = true
+		}
+	}
+	if !root {
+		return "", ErrUnknownDocumentType
+	}
+	return ns, nil
+}
+
+// schemaFinding converts a libxml2 validation error into a finding, using
+// the line and node it reports to locate the offending element.
+func schemaFinding(msg string, line int, node C.xmlNodePtr) Finding {
+	msg = strings.TrimSpace(msg)
+	if m := schemaErrorElement.FindStringSubmatch(msg); m != nil {
+		msg = strings.Replace(msg, "{"+m[1]+"}", schemaPrefix(m[1]), 1)
+	}
+	return Finding{
+		Flag:     FlagFatal,
+		Message:  msg,
+		Location: schemaNodePath(node),
+		Line:     line,
+		Syntax:   true,
+	}
+}
+
+// schemaNodePath provides the XPath of the element, or of the attribute's
+// element, with the position of each step among its siblings.
+func schemaNodePath(node C.xmlNodePtr) string {
+	if node == nil {
+		return ""
+	}
+	attr := ""
+	if node._type == C.XML_ATTRIBUTE_NODE {
+		attr = "/@" + schemaNodeName(node)
+		node = node.parent
+	}
+	var steps []string
+	for n := node; n != nil && n._type == C.XML_ELEMENT_NODE; n = n.parent {
+		name, ns := schemaNodeName(n), schemaNodeNamespace(n)
+		pos := 1
+		for s := n.prev; s != nil; s = s.prev {
+			if s._type == C.XML_ELEMENT_NODE && schemaNodeName(s) == name && schemaNodeNamespace(s) == ns {
+				pos++
+			}
+		}
+		steps = append([]string{schemaPrefix(ns) + name + "[" + strconv.Itoa(pos) + "]"}, steps...)
+	}
+	if len(steps) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(steps, "/") + attr
+}
+
+func schemaNodeName(n C.xmlNodePtr) string {
+	return C.GoString((*C.char)(unsafe.Pointer(n.name)))
+}
+
+func schemaNodeNamespace(n C.xmlNodePtr) string {
+	if n.ns == nil || n.ns.href == nil {
+		return ""
+	}
+	return C.GoString((*C.char)(unsafe.Pointer(n.ns.href)))
+}
+
+// schemaPrefix provides the conventional prefix used in XPath expressions
+// for the given namespace.
+func schemaPrefix(ns string) string {
+	switch ns {
+	case NamespaceCBC:
+		return "cbc:"
+	case NamespaceCAC:
+		return "cac:"
+	case "":
+		return ""
+	}
+	return "*:"
+}
+
+// schemaSyntaxError wraps an XML parsing error as a single syntax
+// finding.
+func schemaSyntaxError(err error) error {
+	f := Finding{
+		Flag:    FlagFatal,
+		Message: err.Error(),
+		Syntax:  true,
+	}
+	var se *xml.SyntaxError
+	if errors.As(err, &se) {
+		f.Line = se.Line
+		f.Message = se.Msg
+	}
+	return &ValidationError{Findings: []Finding{f}}
+}
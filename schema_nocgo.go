@@ -0,0 +1,32 @@
+//go:build !cgo
+
+package ubl
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrSchemaValidationUnavailable is returned by ValidateSchema when the
+// package was built without cgo, which is required by libxml2.
+var ErrSchemaValidationUnavailable = fmt.Errorf("schema validation requires cgo")
+
+// ValidateSchema is not available without cgo and will always return
+// ErrSchemaValidationUnavailable.
+func ValidateSchema(_ []byte) error {
+	return ErrSchemaValidationUnavailable
+}
+
+// SchemaValidator checks documents against the XSD schemas, which is not
+// available without cgo.
+type SchemaValidator struct{}
+
+// NewSchemaValidator prepares a new XSD schema validator.
+func NewSchemaValidator() *SchemaValidator {
+	return new(SchemaValidator)
+}
+
+// Validate always returns ErrSchemaValidationUnavailable.
+func (v *SchemaValidator) Validate(_ context.Context, _ []byte) ([]Finding, error) {
+	return nil, ErrSchemaValidationUnavailable
+}
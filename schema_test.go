@@ -0,0 +1,127 @@
+//go:build cgo
+
+package ubl_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadSchemaXML(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(getDataPath(), name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestValidateSchema(t *testing.T) {
+	t.Run("valid documents", func(t *testing.T) {
+		for _, name := range []string{
+			"parse/peppol/base-example.xml",
+			"parse/peppol/base-creditnote-correction.xml",
+			"parse/en16931/custom-namespace-prefixes.xml",
+			"convert/en16931/out/invoice-complete.xml",
+			"parse/order/order-peppol.xml",
+			"convert/out/order-complete.xml",
+			"parse/despatch-advice/despatch-advice-peppol.xml",
+		} {
+			assert.NoError(t, ubl.ValidateSchema(loadSchemaXML(t, name)), name)
+		}
+	})
+
+	t.Run("invalid elements", func(t *testing.T) {
+		err := ubl.ValidateSchema(loadSchemaXML(t, "validate/invoice-invalid.xml"))
+		var ve *ubl.ValidationError
+		require.True(t, errors.As(err, &ve))
+		require.Len(t, ve.Findings, 2)
+
+		f := ve.Findings[0]
+		assert.True(t, f.Syntax)
+		assert.True(t, f.IsFatal())
+		assert.Equal(t, 8, f.Line)
+		assert.Equal(t, "/*:Invoice[1]/cbc:IssueDate[1]", f.Location)
+		assert.Contains(t, f.Message, "'13/11/2017' is not a valid value of the atomic type 'xs:date'")
+
+		f = ve.Findings[1]
+		assert.Equal(t, 52, f.Line)
+		assert.Equal(t, "/*:Invoice[1]/cac:AccountingCustomerParty[1]/cac:Party[1]/cac:PartyName[1]/cbc:Nickname[1]", f.Location)
+		assert.Contains(t, f.Message, "Element 'cbc:Nickname': This element is not expected")
+	})
+
+	t.Run("malformed XML", func(t *testing.T) {
+		data := []byte("<Invoice xmlns=\"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2\">\n<cbc:ID>")
+		err := ubl.ValidateSchema(data)
+		var ve *ubl.ValidationError
+		require.True(t, errors.As(err, &ve))
+		require.Len(t, ve.Findings, 1)
+		assert.True(t, ve.Findings[0].Syntax)
+		assert.Equal(t, 2, ve.Findings[0].Line)
+	})
+
+	t.Run("converted documents", func(t *testing.T) {
+		env, err := loadTestEnvelope("delivery-advice.json")
+		require.NoError(t, err)
+		da, err := ubl.ConvertDespatchAdvice(env)
+		require.NoError(t, err)
+		data, err := ubl.Bytes(da)
+		require.NoError(t, err)
+		assert.NoError(t, ubl.ValidateSchema(data))
+
+		inv := testParsedInvoice(t, "peppol/base-example.xml")
+		ar, err := ubl.NewInvoiceResponse(inv, ubl.InvoiceResponse{
+			Code: ubl.ResponseCodeRejected,
+			Clarifications: []ubl.Clarification{
+				{Code: ubl.StatusReasonReferences, Text: "Order reference is unknown"},
+			},
+		})
+		require.NoError(t, err)
+		data, err = ubl.Bytes(ar)
+		require.NoError(t, err)
+		assert.NoError(t, ubl.ValidateSchema(data))
+	})
+
+	t.Run("invalid order", func(t *testing.T) {
+		data := loadSchemaXML(t, "parse/order/order-peppol.xml")
+		data = bytes.Replace(data, []byte("<cac:OrderLine>"), []byte("<cac:OrderLine><cbc:Nickname>X</cbc:Nickname>"), 1)
+		err := ubl.ValidateSchema(data)
+		var ve *ubl.ValidationError
+		require.True(t, errors.As(err, &ve))
+		assert.Contains(t, ve.Findings[0].Location, "/*:Order[1]/cac:OrderLine[1]")
+	})
+
+	t.Run("unsupported document", func(t *testing.T) {
+		data := []byte(`<Catalogue xmlns="urn:oasis:names:specification:ubl:schema:xsd:Catalogue-2"/>`)
+		err := ubl.ValidateSchema(data)
+		assert.ErrorIs(t, err, ubl.ErrUnsupportedDocumentType)
+	})
+}
+
+func TestSchemaValidator(t *testing.T) {
+	t.Run("parse invalid document", func(t *testing.T) {
+		_, err := ubl.Parse(loadSchemaXML(t, "validate/invoice-invalid.xml"), ubl.WithValidator(ubl.NewSchemaValidator()))
+		var ve *ubl.ValidationError
+		require.True(t, errors.As(err, &ve))
+		require.Len(t, ve.Findings, 2)
+		assert.True(t, ve.Findings[0].Syntax)
+		assert.Equal(t, 8, ve.Findings[0].Line)
+	})
+
+	t.Run("parse valid document", func(t *testing.T) {
+		doc, err := ubl.Parse(loadSchemaXML(t, "parse/peppol/base-example.xml"), ubl.WithValidator(ubl.NewSchemaValidator()))
+		require.NoError(t, err)
+		assert.IsType(t, &ubl.Invoice{}, doc)
+	})
+
+	t.Run("parse valid order", func(t *testing.T) {
+		doc, err := ubl.Parse(loadSchemaXML(t, "parse/order/order-peppol.xml"), ubl.WithValidator(ubl.NewSchemaValidator()))
+		require.NoError(t, err)
+		assert.IsType(t, &ubl.Order{}, doc)
+	})
+}
@@ -0,0 +1,336 @@
+package ubl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/catalogues/iso"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
+)
+
+// SUNAT document type codes (catalogue 01) and the default operation type
+// (catalogue 51), used as the ProfileID.
+const (
+	SUNATTypeInvoice = "01"
+	SUNATTypeReceipt = "03"
+
+	SUNATOperationSale = "0101"
+)
+
+// Peruvian identity types used in party identifications.
+const (
+	// IdentityTypeDNI is the national identity document of Peruvian
+	// citizens.
+	IdentityTypeDNI cbc.Code = "DNI"
+	// IdentityTypeCE is the identity card of foreign residents (carné de
+	// extranjería).
+	IdentityTypeCE cbc.Code = "CE"
+)
+
+const (
+	// sunatSchemeRUC is the identity document type of RUC tax numbers.
+	sunatSchemeRUC = "6"
+	// sunatSignatureID identifies the signature of the supplier.
+	sunatSignatureID = "SignatureSP"
+)
+
+// sunatMetaHash is the meta key used for the digest value of the
+// signature of parsed documents, printed on their representations.
+const sunatMetaHash cbc.Key = "sunat-hash"
+
+// sunatIdentitySchemes maps identity types to SUNAT identity document
+// types (catalogue 06).
+var sunatIdentitySchemes = map[cbc.Code]string{
+	IdentityTypeDNI: "1",
+	IdentityTypeCE:  "4",
+}
+
+// sunatTaxScheme contains the SUNAT tax code, name and type (catalogue
+// 05) together with the IGV affectation code (catalogue 07) of lines.
+type sunatTaxScheme struct {
+	id       string
+	name     string
+	typeCode string
+	reason   string
+}
+
+// sunatTaxSchemes maps the UNTDID 5305 categories of VAT to the SUNAT
+// taxes.
+var sunatTaxSchemes = map[string]sunatTaxScheme{
+	"S": {id: "1000", name: "IGV", typeCode: "VAT", reason: "10"},
+	"E": {id: "9997", name: "EXO", typeCode: "VAT", reason: "20"},
+	"O": {id: "9998", name: "INA", typeCode: "FRE", reason: "30"},
+	"G": {id: "9995", name: "EXP", typeCode: "FRE", reason: "40"},
+}
+
+// ContextSUNAT defines the context for Peruvian SUNAT UBL 2.1 invoices
+// (facturas) and receipts (boletas de venta). The ProfileID defaults to
+// the domestic sale operation type, and may be replaced with the
+// ubl-profile meta key.
+//
+// Documents must be signed by the supplier, for which an empty UBL
+// extension is included.
+var ContextSUNAT = Context{
+	CustomizationID:       "SUNAT-2.0",
+	OutputCustomizationID: "2.0",
+	Output: OutputRules{
+		LineTaxTotals: true,
+	},
+	Hooks: sunatHooks{},
+}
+
+// sunatHooks maps documents to and from the UBL 2.1 format of the
+// Peruvian tax authority (SUNAT).
+type sunatHooks struct {
+	BaseHooks
+}
+
+// BeforeConvert ensures the invoice has a document type so that the
+// regular conversion can take place.
+func (sunatHooks) BeforeConvert(inv *bill.Invoice) error {
+	setDocumentType(inv)
+	return nil
+}
+
+// AfterConvert applies the SUNAT mapping rules to the generated document.
+func (sunatHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	if inv.Type != bill.InvoiceTypeStandard {
+		return fmt.Errorf("sunat: unsupported invoice type %q", inv.Type)
+	}
+	ruc := sunatRUC(inv.Supplier)
+	if ruc == "" {
+		return errors.New("sunat: supplier requires a Peruvian RUC")
+	}
+
+	out.UBLVersionID = Version
+	if out.ProfileID == "" {
+		out.ProfileID = SUNATOperationSale
+	}
+	// Receipts are issued to customers without a RUC.
+	tc := SUNATTypeReceipt
+	if sunatRUC(inv.Customer) != "" {
+		tc = SUNATTypeInvoice
+	}
+	out.useInvoiceRoot(tc)
+	listID := out.ProfileID
+	out.TypeCodeAttributes = &Attributes{ListID: &listID}
+	if inv.IssueTime != nil {
+		out.IssueTime = inv.IssueTime.String()
+	}
+	if r := out.OrderReference; r != nil && r.ID == notApplicable {
+		out.OrderReference = nil
+	}
+
+	out.Signature = []Signature{sunatSignature(ruc, inv.Supplier.Name)}
+
+	applySUNATParty(inv.Supplier, out.AccountingSupplierParty.Party)
+	if inv.Customer != nil {
+		applySUNATParty(inv.Customer, out.AccountingCustomerParty.Party)
+	}
+
+	out.eachTaxCategory(func(id *IDType, ts *TaxScheme) {
+		if id != nil {
+			applySUNATTaxScheme(id.Value, ts)
+		}
+	})
+	for i := range out.InvoiceLines {
+		for _, tt := range out.InvoiceLines[i].TaxTotal {
+			for j := range tt.TaxSubtotal {
+				tc := &tt.TaxSubtotal[j].TaxCategory
+				if tc.ID == nil {
+					continue
+				}
+				if sts, ok := sunatTaxSchemes[tc.ID.Value]; ok {
+					reason := sts.reason
+					tc.TaxExemptionReasonCode = &reason
+				}
+			}
+		}
+	}
+	// Line taxes are only provided in the line's tax total.
+	out.removeItemTaxCategories()
+	return nil
+}
+
+// BeforeParse maps the SUNAT taxes back into tax schemes, and prepares
+// the tax categories expected by the regular parsing process.
+func (sunatHooks) BeforeParse(in *Invoice) error {
+	in.eachTaxCategory(func(_ *IDType, ts *TaxScheme) {
+		parseSUNATTaxScheme(ts)
+	})
+	in.eachTaxSubtotal(func(st *TaxSubtotal) {
+		// IGV affectation codes are not exemption reason codes.
+		st.TaxCategory.TaxExemptionReasonCode = nil
+	})
+	in.addItemTaxCategories()
+	return nil
+}
+
+// AfterParse maps the SUNAT specific fields back into the invoice. The
+// regime is left to the supplier's tax ID.
+func (sunatHooks) AfterParse(in *Invoice, inv *bill.Invoice) error {
+	inv.Type = bill.InvoiceTypeStandard
+
+	if i := strings.LastIndex(in.ID, "-"); i > 0 {
+		inv.Series = cbc.Code(in.ID[:i])
+		inv.Code = cbc.Code(in.ID[i+1:])
+	}
+	if in.ProfileID != "" && in.ProfileID != SUNATOperationSale {
+		if inv.Meta == nil {
+			inv.Meta = make(cbc.Meta)
+		}
+		inv.Meta[cbc.Key("ubl-profile")] = in.ProfileID
+	}
+	if in.IssueTime != "" {
+		t, err := time.Parse("15:04:05", in.IssueTime)
+		if err != nil {
+			return fmt.Errorf("sunat: parsing issue time: %w", err)
+		}
+		inv.IssueTime = cal.NewTime(t.Hour(), t.Minute(), t.Second())
+	}
+
+	hash, err := parseSUNATHash(in)
+	if err != nil {
+		return err
+	}
+	if hash != "" {
+		if inv.Meta == nil {
+			inv.Meta = make(cbc.Meta)
+		}
+		inv.Meta[sunatMetaHash] = hash
+	}
+
+	if inv.Supplier != nil {
+		parseSUNATParty(inv.Supplier)
+	}
+	if inv.Customer != nil {
+		parseSUNATParty(inv.Customer)
+	}
+	return nil
+}
+
+// sunatRUC provides the party's Peruvian tax number, or an empty string
+// when not available.
+func sunatRUC(party *org.Party) string {
+	if party == nil || party.TaxID == nil || party.TaxID.Country != "PE" {
+		return ""
+	}
+	return party.TaxID.Code.String()
+}
+
+// sunatSignature provides the signature block that references the
+// supplier's signature, which is added when the document is signed.
+func sunatSignature(ruc, name string) Signature {
+	return Signature{
+		ID: sunatSignatureID,
+		SignatoryParty: &Party{
+			PartyIdentification: []Identification{{ID: &IDType{Value: ruc}}},
+			PartyName:           &PartyName{Name: name},
+		},
+		DigitalSignatureAttachment: &Attachment{
+			ExternalReference: &ExternalReference{URI: "#" + sunatSignatureID},
+		},
+	}
+}
+
+// applySUNATParty replaces the party's identification details with the
+// RUC or identity document and its SUNAT type.
+func applySUNATParty(party *org.Party, p *Party) {
+	if p == nil {
+		return
+	}
+	p.PartyTaxScheme = nil
+	if p.PartyLegalEntity != nil {
+		p.PartyLegalEntity.CompanyID = nil
+	}
+	if ruc := sunatRUC(party); ruc != "" {
+		s := sunatSchemeRUC
+		p.PartyIdentification = []Identification{{ID: &IDType{SchemeID: &s, Value: ruc}}}
+		return
+	}
+	for _, id := range party.Identities {
+		if s, ok := sunatIdentitySchemes[id.Type]; ok {
+			p.PartyIdentification = []Identification{{ID: &IDType{SchemeID: &s, Value: id.Code.String()}}}
+			return
+		}
+	}
+}
+
+// parseSUNATParty maps the party identifications with SUNAT identity
+// document types back into the GOBL party's tax ID and identities.
+func parseSUNATParty(party *org.Party) {
+	var ids []*org.Identity
+	for _, id := range party.Identities {
+		s := id.Ext.Get(iso.ExtKeySchemeID).String()
+		if s == sunatSchemeRUC {
+			party.TaxID = &tax.Identity{Country: "PE", Code: id.Code}
+			continue
+		}
+		for t, scheme := range sunatIdentitySchemes {
+			if scheme == s {
+				id.Type = t
+				id.Ext = nil
+			}
+		}
+		ids = append(ids, id)
+	}
+	party.Identities = ids
+}
+
+// applySUNATTaxScheme replaces the VAT tax scheme with the SUNAT tax for
+// the UNTDID 5305 tax category.
+func applySUNATTaxScheme(category string, ts *TaxScheme) {
+	if ts == nil || ts.ID.Value != TaxSchemeVAT {
+		return
+	}
+	sts, ok := sunatTaxSchemes[category]
+	if !ok {
+		return
+	}
+	name := sts.name
+	ts.ID.Value = sts.id
+	ts.Name = &name
+	ts.TaxTypeCode = sts.typeCode
+}
+
+// parseSUNATTaxScheme sets the VAT tax scheme from the SUNAT tax.
+func parseSUNATTaxScheme(ts *TaxScheme) {
+	if ts == nil {
+		return
+	}
+	for _, sts := range sunatTaxSchemes {
+		if sts.id == ts.ID.Value {
+			ts.ID.Value = TaxSchemeVAT
+			ts.Name = nil
+			ts.TaxTypeCode = ""
+			return
+		}
+	}
+}
+
+// parseSUNATHash provides the digest value of the document's signature
+// extension, or an empty string if the document is not signed.
+func parseSUNATHash(in *Invoice) (string, error) {
+	if in.UBLExtensions == nil {
+		return "", nil
+	}
+	for _, e := range in.UBLExtensions.Extension {
+		if e.ExtensionContent == nil || strings.TrimSpace(e.ExtensionContent.Value) == "" {
+			continue
+		}
+		n, err := parseXMLTree([]byte(e.ExtensionContent.Value))
+		if err != nil {
+			return "", fmt.Errorf("sunat: parsing extensions: %w", err)
+		}
+		if dv := n.find("DigestValue"); dv != nil {
+			return strings.TrimSpace(dv.text()), nil
+		}
+	}
+	return "", nil
+}
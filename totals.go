@@ -5,8 +5,10 @@ import (
 	"github.com/invopop/gobl/catalogues/cef"
 	"github.com/invopop/gobl/catalogues/untdid"
 	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/currency"
 	"github.com/invopop/gobl/num"
 	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
 )
 
 // TaxTotal represents a tax total
@@ -19,6 +21,7 @@ type TaxTotal struct {
 type TaxSubtotal struct {
 	TaxableAmount Amount      `xml:"cbc:TaxableAmount,omitempty"`
 	TaxAmount     Amount      `xml:"cbc:TaxAmount"`
+	Percent       *string     `xml:"cbc:Percent,omitempty"`
 	TaxCategory   TaxCategory `xml:"cac:TaxCategory"`
 }
 
@@ -43,7 +46,10 @@ type MonetaryTotal struct {
 	PayableAmount         *Amount `xml:"cbc:PayableAmount,omitempty"`
 }
 
-func (ui *Invoice) addTotals(inv *bill.Invoice) {
+// addTotals adds the monetary and tax totals. Retained taxes are only left
+// out of the tax total when they are provided in a withholding tax total
+// instead.
+func (ui *Invoice) addTotals(inv *bill.Invoice, withholding bool) {
 	if inv == nil || inv.Totals == nil {
 		return
 	}
@@ -51,7 +57,169 @@ func (ui *Invoice) addTotals(inv *bill.Invoice) {
 
 	currency := inv.Currency.String()
 
-	ui.LegalMonetaryTotal = MonetaryTotal{
+	ui.LegalMonetaryTotal = newMonetaryTotal(t, currency)
+
+	ui.TaxTotal = []TaxTotal{
+		{
+			TaxAmount: Amount{Value: t.Tax.String(), CurrencyID: &currency},
+		},
+	}
+	if t.Taxes != nil && len(t.Taxes.Categories) > 0 {
+		for _, cat := range t.Taxes.Categories {
+			if cat.Retained && withholding {
+				continue
+			}
+			ui.TaxTotal[0].TaxSubtotal = append(ui.TaxTotal[0].TaxSubtotal, newTaxSubtotals(inv, cat, currency)...)
+		}
+	}
+}
+
+// newTaxSubtotals provides a tax subtotal for each of the rates of the
+// tax category total.
+func newTaxSubtotals(inv *bill.Invoice, cat *tax.CategoryTotal, currency string) []TaxSubtotal {
+	var subtotals []TaxSubtotal
+	for _, r := range cat.Rates {
+		subtotal := TaxSubtotal{
+			TaxAmount: Amount{Value: r.Amount.String(), CurrencyID: &currency},
+		}
+		if r.Base != (num.Amount{}) {
+			subtotal.TaxableAmount = Amount{Value: r.Base.String(), CurrencyID: &currency}
+		}
+		taxCat := TaxCategory{
+			ID: taxCategoryID(r.Key, r.Ext),
+		}
+
+		if r.Ext != nil {
+			if r.Ext[cef.ExtKeyVATEX].String() != "" {
+				v := r.Ext[cef.ExtKeyVATEX].String()
+				taxCat.TaxExemptionReasonCode = &v
+			}
+		}
+
+		// Set percent: required unless category is "O" (outside scope)
+		if r.Percent != nil {
+			p := r.Percent.StringWithoutSymbol()
+			taxCat.Percent = &p
+		} else if taxCat.ID == nil || taxCat.ID.Value != "O" {
+			// Default to 0% when not outside scope
+			p := "0"
+			taxCat.Percent = &p
+		}
+
+		if inv.Notes != nil {
+			for _, n := range inv.Notes {
+				if n.Key == org.NoteKeyLegal {
+					reason := n.Text
+					taxCat.TaxExemptionReason = &reason
+					break
+				}
+			}
+		}
+
+		if cat.Code != cbc.CodeEmpty {
+			taxCat.TaxScheme = &TaxScheme{ID: IDType{Value: cat.Code.String()}}
+		}
+		subtotal.TaxCategory = taxCat
+		subtotals = append(subtotals, subtotal)
+	}
+	return subtotals
+}
+
+// addWithholdingTaxTotals adds a withholding tax total with the subtotals
+// of the retained tax categories.
+func (ui *Invoice) addWithholdingTaxTotals(inv *bill.Invoice) {
+	if inv.Totals == nil || inv.Totals.Taxes == nil || inv.Totals.RetainedTax == nil {
+		return
+	}
+	currency := inv.Currency.String()
+	wt := TaxTotal{
+		TaxAmount: Amount{Value: inv.Totals.RetainedTax.String(), CurrencyID: &currency},
+	}
+	for _, cat := range inv.Totals.Taxes.Categories {
+		if cat.Retained {
+			wt.TaxSubtotal = append(wt.TaxSubtotal, newTaxSubtotals(inv, cat, currency)...)
+		}
+	}
+	ui.WithholdingTaxTotal = []TaxTotal{wt}
+}
+
+// addTaxCurrency adds the tax total in the currency of the invoice's tax
+// regime (BT-111), together with the exchange rate used, for invoices
+// issued in another currency.
+func (ui *Invoice) addTaxCurrency(inv *bill.Invoice) {
+	if inv.Totals == nil || len(ui.TaxTotal) == 0 {
+		return
+	}
+	r := inv.RegimeDef()
+	if r == nil {
+		return
+	}
+	rate := currency.MatchExchangeRate(inv.ExchangeRates, inv.Currency, r.Currency)
+	if rate == nil {
+		return
+	}
+	src := inv.Currency.String()
+	tc := rate.To.String()
+	calc := rate.Amount.String()
+	ui.TaxCurrencyCode = tc
+	ui.TaxExchangeRate = &ExchangeRate{
+		SourceCurrencyCode: &src,
+		TargetCurrencyCode: &tc,
+		CalculationRate:    &calc,
+	}
+	if rate.At != nil {
+		d := formatDate(rate.At.Date())
+		ui.TaxExchangeRate.Date = &d
+	}
+	amount := rate.Convert(inv.Totals.Tax)
+	ui.TaxTotal = append(ui.TaxTotal, TaxTotal{
+		TaxAmount: Amount{Value: amount.String(), CurrencyID: &tc},
+	})
+}
+
+// retainedCategories provides the codes of the retained tax categories
+// in the invoice's totals.
+func retainedCategories(inv *bill.Invoice) map[cbc.Code]bool {
+	retained := make(map[cbc.Code]bool)
+	if inv.Totals == nil || inv.Totals.Taxes == nil {
+		return retained
+	}
+	for _, cat := range inv.Totals.Taxes.Categories {
+		if cat.Retained {
+			retained[cat.Code] = true
+		}
+	}
+	return retained
+}
+
+// taxCategoryKeys maps GOBL tax keys to the UNTDID 5305 tax category codes
+// to use when taxes do not define the tax category extension, as happens
+// in regimes without the EN16931 addon.
+var taxCategoryKeys = map[cbc.Key]string{
+	tax.KeyStandard:       "S",
+	tax.KeyZero:           "Z",
+	tax.KeyExempt:         "E",
+	tax.KeyReverseCharge:  "AE",
+	tax.KeyIntraCommunity: "K",
+	tax.KeyExport:         "G",
+	tax.KeyOutsideScope:   "O",
+}
+
+// taxCategoryID provides the tax category from the tax extensions, or
+// from the tax key otherwise.
+func taxCategoryID(key cbc.Key, ext tax.Extensions) *IDType {
+	if c := ext.Get(untdid.ExtKeyTaxCategory).String(); c != "" {
+		return &IDType{Value: c}
+	}
+	if c, ok := taxCategoryKeys[key]; ok {
+		return &IDType{Value: c}
+	}
+	return nil
+}
+
+// newMonetaryTotal maps the GOBL document totals to a UBL monetary total.
+func newMonetaryTotal(t *bill.Totals, currency string) MonetaryTotal {
+	mt := MonetaryTotal{
 		LineExtensionAmount: Amount{Value: t.Sum.String(), CurrencyID: &currency},
 		TaxExclusiveAmount:  Amount{Value: t.Total.String(), CurrencyID: &currency},
 		TaxInclusiveAmount:  Amount{Value: t.TotalWithTax.String(), CurrencyID: &currency},
@@ -59,76 +227,21 @@ func (ui *Invoice) addTotals(inv *bill.Invoice) {
 	}
 
 	if t.Discount != nil {
-		ui.LegalMonetaryTotal.AllowanceTotalAmount = &Amount{Value: t.Discount.String(), CurrencyID: &currency}
+		mt.AllowanceTotalAmount = &Amount{Value: t.Discount.String(), CurrencyID: &currency}
 	}
 	if t.Charge != nil {
-		ui.LegalMonetaryTotal.ChargeTotalAmount = &Amount{Value: t.Charge.String(), CurrencyID: &currency}
+		mt.ChargeTotalAmount = &Amount{Value: t.Charge.String(), CurrencyID: &currency}
 	}
 	if t.Rounding != nil {
-		ui.LegalMonetaryTotal.PayableRoundingAmount = &Amount{Value: t.Rounding.String(), CurrencyID: &currency}
+		mt.PayableRoundingAmount = &Amount{Value: t.Rounding.String(), CurrencyID: &currency}
 	}
 	if t.Advances != nil {
-		ui.LegalMonetaryTotal.PrepaidAmount = &Amount{Value: t.Advances.String(), CurrencyID: &currency}
+		mt.PrepaidAmount = &Amount{Value: t.Advances.String(), CurrencyID: &currency}
 	}
 	if t.Due != nil {
-		ui.LegalMonetaryTotal.PayableAmount = &Amount{Value: t.Due.String(), CurrencyID: &currency}
-	}
-
-	ui.TaxTotal = []TaxTotal{
-		{
-			TaxAmount: Amount{Value: t.Tax.String(), CurrencyID: &currency},
-		},
-	}
-	if t.Taxes != nil && len(t.Taxes.Categories) > 0 {
-		for _, cat := range t.Taxes.Categories {
-			for _, r := range cat.Rates {
-				subtotal := TaxSubtotal{
-					TaxAmount: Amount{Value: r.Amount.String(), CurrencyID: &currency},
-				}
-				if r.Base != (num.Amount{}) {
-					subtotal.TaxableAmount = Amount{Value: r.Base.String(), CurrencyID: &currency}
-				}
-				taxCat := TaxCategory{}
-
-				if r.Ext != nil {
-					if r.Ext[untdid.ExtKeyTaxCategory].String() != "" {
-						k := r.Ext[untdid.ExtKeyTaxCategory].String()
-						taxCat.ID = &IDType{Value: k}
-					}
-					if r.Ext[cef.ExtKeyVATEX].String() != "" {
-						v := r.Ext[cef.ExtKeyVATEX].String()
-						taxCat.TaxExemptionReasonCode = &v
-					}
-				}
-
-				// Set percent: required unless category is "O" (outside scope)
-				if r.Percent != nil {
-					p := r.Percent.StringWithoutSymbol()
-					taxCat.Percent = &p
-				} else if taxCat.ID == nil || taxCat.ID.Value != "O" {
-					// Default to 0% when not outside scope
-					p := "0"
-					taxCat.Percent = &p
-				}
-
-				if inv.Notes != nil {
-					for _, n := range inv.Notes {
-						if n.Key == org.NoteKeyLegal {
-							reason := n.Text
-							taxCat.TaxExemptionReason = &reason
-							break
-						}
-					}
-				}
-
-				if cat.Code != cbc.CodeEmpty {
-					taxCat.TaxScheme = &TaxScheme{ID: IDType{Value: cat.Code.String()}}
-				}
-				subtotal.TaxCategory = taxCat
-				ui.TaxTotal[0].TaxSubtotal = append(ui.TaxTotal[0].TaxSubtotal, subtotal)
-			}
-		}
+		mt.PayableAmount = &Amount{Value: t.Due.String(), CurrencyID: &currency}
 	}
+	return mt
 }
 
 // taxCategoryInfo holds tax category information from TaxTotal
@@ -138,9 +251,15 @@ type taxCategoryInfo struct {
 
 // buildTaxCategoryMap builds a map of tax category information from TaxTotal
 func (ui *Invoice) buildTaxCategoryMap() map[string]*taxCategoryInfo {
+	return taxCategoryMapFrom(ui.TaxTotal)
+}
+
+// taxCategoryMapFrom builds a map of tax category information from a set
+// of tax totals, keyed by tax scheme and category.
+func taxCategoryMapFrom(totals []TaxTotal) map[string]*taxCategoryInfo {
 	categoryMap := make(map[string]*taxCategoryInfo)
 
-	for _, taxTotal := range ui.TaxTotal {
+	for _, taxTotal := range totals {
 		for _, subtotal := range taxTotal.TaxSubtotal {
 			if subtotal.TaxCategory.ID != nil && subtotal.TaxCategory.TaxScheme != nil {
 				key := buildTaxCategoryKey(subtotal.TaxCategory.TaxScheme.ID.Value, subtotal.TaxCategory.ID.Value)
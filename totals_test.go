@@ -1,12 +1,34 @@
 package ubl_test
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/invopop/gobl"
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/num"
+	"github.com/invopop/gobl/tax"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testRetainedEnvelope provides a Spanish invoice whose lines include
+// IRPF, a retained tax.
+func testRetainedEnvelope(t *testing.T) *gobl.Envelope {
+	t.Helper()
+	env, err := loadTestEnvelope("peppol/peppol-1.json")
+	require.NoError(t, err)
+	inv, ok := env.Extract().(*bill.Invoice)
+	require.True(t, ok)
+	for _, l := range inv.Lines {
+		p := num.MakePercentage(15, 2)
+		l.Taxes = append(l.Taxes, &tax.Combo{Category: "IRPF", Percent: &p})
+	}
+	require.NoError(t, inv.Calculate())
+	return env
+}
+
 func TestNewTotals(t *testing.T) {
 	t.Run("invoice-de-de.json", func(t *testing.T) {
 		doc, err := testInvoiceFrom("invoice-de-de.json")
@@ -39,4 +61,87 @@ func TestNewTotals(t *testing.T) {
 		assert.Equal(t, "VAT", doc.TaxTotal[0].TaxSubtotal[0].TaxCategory.TaxScheme.ID.Value)
 		assert.Equal(t, "21.0", *doc.TaxTotal[0].TaxSubtotal[0].TaxCategory.Percent)
 	})
+
+	t.Run("retained taxes", func(t *testing.T) {
+		env := testRetainedEnvelope(t)
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ubl.ContextEN16931))
+		require.NoError(t, err)
+
+		require.Len(t, doc.TaxTotal, 1)
+		assert.Equal(t, "340.20", doc.TaxTotal[0].TaxAmount.Value)
+		require.Len(t, doc.TaxTotal[0].TaxSubtotal, 2)
+		assert.Equal(t, "VAT", doc.TaxTotal[0].TaxSubtotal[0].TaxCategory.TaxScheme.ID.Value)
+		assert.Equal(t, "IRPF", doc.TaxTotal[0].TaxSubtotal[1].TaxCategory.TaxScheme.ID.Value)
+		assert.Equal(t, "243.00", doc.TaxTotal[0].TaxSubtotal[1].TaxAmount.Value)
+		assert.Empty(t, doc.WithholdingTaxTotal)
+		assert.Equal(t, "1717.20", doc.LegalMonetaryTotal.PayableAmount.Value)
+	})
+
+	t.Run("retained taxes in default context", func(t *testing.T) {
+		doc, err := ubl.ConvertInvoice(testRetainedEnvelope(t))
+		require.NoError(t, err)
+		assert.Equal(t, ubl.ContextEN16931.CustomizationID, doc.CustomizationID)
+
+		require.Len(t, doc.TaxTotal, 1)
+		var irpf *ubl.TaxSubtotal
+		for i, st := range doc.TaxTotal[0].TaxSubtotal {
+			if st.TaxCategory.TaxScheme.ID.Value == "IRPF" {
+				irpf = &doc.TaxTotal[0].TaxSubtotal[i]
+			}
+		}
+		require.NotNil(t, irpf, "IRPF should be included in the tax total")
+		assert.Equal(t, "1620.00", irpf.TaxableAmount.Value)
+		assert.Equal(t, "243.00", irpf.TaxAmount.Value)
+		assert.Equal(t, "15", *irpf.TaxCategory.Percent)
+		assert.Empty(t, doc.WithholdingTaxTotal)
+	})
+
+	t.Run("withholding tax totals", func(t *testing.T) {
+		env := testRetainedEnvelope(t)
+		ctx := ubl.ContextEN16931
+		ctx.Output.LineTaxTotals = true
+		ctx.Output.WithholdingTaxTotals = true
+		doc, err := ubl.ConvertInvoice(env, ubl.WithContext(ctx))
+		require.NoError(t, err)
+
+		require.Len(t, doc.TaxTotal[0].TaxSubtotal, 1)
+		require.Len(t, doc.WithholdingTaxTotal, 1)
+		wt := doc.WithholdingTaxTotal[0]
+		assert.Equal(t, "243.00", wt.TaxAmount.Value)
+		require.Len(t, wt.TaxSubtotal, 1)
+		assert.Equal(t, "1620.00", wt.TaxSubtotal[0].TaxableAmount.Value)
+		assert.Equal(t, "15", *wt.TaxSubtotal[0].TaxCategory.Percent)
+		assert.Equal(t, "IRPF", wt.TaxSubtotal[0].TaxCategory.TaxScheme.ID.Value)
+
+		line := doc.InvoiceLines[0]
+		require.Len(t, line.TaxTotal, 1)
+		assert.Equal(t, "340.20", line.TaxTotal[0].TaxAmount.Value)
+		assert.Len(t, line.TaxTotal[0].TaxSubtotal, 1)
+		require.Len(t, line.WithholdingTaxTotal, 1)
+		assert.Equal(t, "243.00", line.WithholdingTaxTotal[0].TaxAmount.Value)
+	})
+
+	t.Run("tax currency", func(t *testing.T) {
+		doc, err := testInvoiceFromContext(filepath.Join("en16931", "invoice-foreign-currency.json"), ubl.ContextEN16931)
+		require.NoError(t, err)
+
+		assert.Equal(t, "USD", doc.DocumentCurrencyCode)
+		assert.Equal(t, "EUR", doc.TaxCurrencyCode)
+		require.NotNil(t, doc.TaxExchangeRate)
+		assert.Equal(t, "0.875", *doc.TaxExchangeRate.CalculationRate)
+		assert.Equal(t, "2024-05-14", *doc.TaxExchangeRate.Date)
+		require.Len(t, doc.TaxTotal, 2)
+		assert.Equal(t, "342.00", doc.TaxTotal[0].TaxAmount.Value)
+		assert.Equal(t, "299.25", doc.TaxTotal[1].TaxAmount.Value)
+		assert.Equal(t, "EUR", *doc.TaxTotal[1].TaxAmount.CurrencyID)
+		assert.Empty(t, doc.TaxTotal[1].TaxSubtotal)
+	})
+
+	t.Run("same tax currency", func(t *testing.T) {
+		doc, err := testInvoiceFrom("invoice-de-de.json")
+		require.NoError(t, err)
+		assert.Empty(t, doc.TaxCurrencyCode)
+		assert.Nil(t, doc.TaxExchangeRate)
+		assert.Len(t, doc.TaxTotal, 1)
+	})
 }
@@ -21,6 +21,10 @@ var (
 	// ErrUnsupportedDocumentType is returned when the document type
 	// is not supported for conversion.
 	ErrUnsupportedDocumentType = fmt.Errorf("unsupported document type")
+
+	// ErrContextRegistered is returned when loading a context whose name,
+	// aliases or identifiers are already used by a registered context.
+	ErrContextRegistered = fmt.Errorf("context already registered")
 )
 
 // Version is the version of UBL documents that will be generated
@@ -32,6 +36,9 @@ const Version = "2.1"
 //
 // Supported types:
 //   - *Invoice (for both Invoice and CreditNote documents)
+//   - *Order
+//   - *DespatchAdvice
+//   - *ApplicationResponse
 //
 // Example usage:
 //
@@ -44,7 +51,17 @@ const Version = "2.1"
 //	    attachments := inv.ExtractBinaryAttachments()
 //	    // ...
 //	}
-func Parse(data []byte) (any, error) {
+//
+// Add a WithValidator option to check the document before it is parsed.
+func Parse(data []byte, opts ...Option) (any, error) {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.validate(data); err != nil {
+		return nil, err
+	}
+
 	ns, err := extractRootNamespace(data)
 	if err != nil {
 		return nil, err
@@ -53,56 +70,94 @@ func Parse(data []byte) (any, error) {
 	switch ns {
 	case NamespaceUBLInvoice, NamespaceUBLCreditNote:
 		in := new(Invoice)
-		if err := xmlctx.Unmarshal(data, in, xmlctx.WithNamespaces(map[string]string{
-			"":     ns,
-			"cbc":  NamespaceCBC,
-			"cac":  NamespaceCAC,
-			"qdt":  NamespaceQDT,
-			"udt":  NamespaceUDT,
-			"ccts": NamespaceCCTS,
-			"xsi":  NamespaceXSI,
-		})); err != nil {
+		if err := unmarshal(data, ns, in); err != nil {
+			return nil, err
+		}
+		if err := in.parseAttributes(data, ns); err != nil {
 			return nil, err
 		}
 		return in, nil
 
-	// Future document types can be added here
-	// case NamespaceUBLOrder:
-	//     order := new(Order)
-	//     if err := xmlctx.Parse(data, order, xmlctx.WithNamespaces(map[string]string{
-	//         "cbc":  NamespaceCBC,
-	//         "cac":  NamespaceCAC,
-	//         "qdt":  NamespaceQDT,
-	//         "udt":  NamespaceUDT,
-	//         "ccts": NamespaceCCTS,
-	//         "xsi":  NamespaceXSI,
-	//         "ext":  "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2",
-	//     })); err != nil {
-	//         return nil, err
-	//     }
-	//     return order, nil
+	case NamespaceUBLOrder:
+		ord := new(Order)
+		if err := unmarshal(data, ns, ord); err != nil {
+			return nil, err
+		}
+		return ord, nil
+
+	case NamespaceUBLDespatchAdvice:
+		da := new(DespatchAdvice)
+		if err := unmarshal(data, ns, da); err != nil {
+			return nil, err
+		}
+		return da, nil
+
+	case NamespaceUBLApplicationResponse:
+		ar := new(ApplicationResponse)
+		if err := unmarshal(data, ns, ar); err != nil {
+			return nil, err
+		}
+		return ar, nil
 
 	default:
 		return nil, ErrUnknownDocumentType
 	}
 }
 
+// unmarshal decodes the document using the common UBL namespace prefixes
+// together with the provided root namespace.
+func unmarshal(data []byte, ns string, v any) error {
+	return xmlctx.Unmarshal(data, v, xmlctx.WithNamespaces(map[string]string{
+		"":     ns,
+		"cbc":  NamespaceCBC,
+		"cac":  NamespaceCAC,
+		"ext":  NamespaceEXT,
+		"qdt":  NamespaceQDT,
+		"udt":  NamespaceUDT,
+		"ccts": NamespaceCCTS,
+		"xsi":  NamespaceXSI,
+	}))
+}
+
 // Convert takes a GOBL envelope and converts to a UBL document of one
 // of the supported types.
 //
 // Add a WithContext option to specify the desired UBL Guideline and Profile ID.
-// If none is provided, EN16931 will be used by default.
+// If none is provided, EN16931 will be used by default for invoices,
+// Peppol Ordering for orders, and Peppol Despatch Advice for deliveries.
+//
+// Add a WithValidator option to check the generated document before it
+// is returned.
 func Convert(env *gobl.Envelope, opts ...Option) (any, error) {
-	o := &options{
-		context: ContextEN16931,
-	}
+	o := new(options)
 	for _, opt := range opts {
 		opt(o)
 	}
 
+	out, err := convert(env, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.validators) > 0 {
+		data, err := Bytes(out)
+		if err != nil {
+			return nil, err
+		}
+		if err := o.validate(data); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func convert(env *gobl.Envelope, o *options) (any, error) {
 	doc := env.Extract()
 	switch d := doc.(type) {
 	case *bill.Invoice:
+		if o.context.CustomizationID == "" {
+			o.context = ContextEN16931
+		}
+
 		// Check and add missing addons
 		if err := ensureAddons(d, o.context.Addons); err != nil {
 			return nil, err
@@ -113,7 +168,27 @@ func Convert(env *gobl.Envelope, opts ...Option) (any, error) {
 			return nil, fmt.Errorf("cannot convert invoice with included taxes: %w", err)
 		}
 
-		return ublInvoice(d, o)
+		if err := o.context.beforeConvert(d); err != nil {
+			return nil, err
+		}
+		out, err := ublInvoice(d, o)
+		if err != nil {
+			return nil, err
+		}
+		if err := o.context.afterConvert(d, out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	case *bill.Order:
+		if o.context.CustomizationID == "" {
+			o.context = ContextPeppolOrder
+		}
+		return ublOrder(d, o)
+	case *bill.Delivery:
+		if o.context.CustomizationID == "" {
+			o.context = ContextPeppolDespatchAdvice
+		}
+		return ublDespatchAdvice(d, o)
 	default:
 		return nil, ErrUnsupportedDocumentType
 	}
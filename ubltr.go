@@ -0,0 +1,330 @@
+package ubl
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/catalogues/iso"
+	"github.com/invopop/gobl/cbc"
+	"github.com/invopop/gobl/org"
+	"github.com/invopop/gobl/tax"
+)
+
+// UBL-TR profile IDs, which determine the e-invoicing scenario.
+const (
+	UBLTRProfileBasic      = "TEMELFATURA"
+	UBLTRProfileCommercial = "TICARIFATURA"
+	UBLTRProfileArchive    = "EARSIVFATURA"
+)
+
+// UBL-TR invoice type codes.
+const (
+	UBLTRTypeSale        = "SATIS"
+	UBLTRTypeReturn      = "IADE"
+	UBLTRTypeWithholding = "TEVKIFAT"
+	UBLTRTypeExempt      = "ISTISNA"
+)
+
+// Turkish identity types used in party identifications.
+const (
+	// IdentityTypeVKN is the tax number of Turkish companies.
+	IdentityTypeVKN cbc.Code = "VKN"
+	// IdentityTypeTCKN is the national identity number of Turkish
+	// citizens, used as the tax number of individuals.
+	IdentityTypeTCKN cbc.Code = "TCKN"
+	// IdentityTypeMERSIS is the central registry number of Turkish
+	// companies.
+	IdentityTypeMERSIS cbc.Code = "MERSISNO"
+	// IdentityTypeTradeRegistry is the trade registry number of Turkish
+	// companies.
+	IdentityTypeTradeRegistry cbc.Code = "TICARETSICILNO"
+)
+
+// ublTRSignatureScheme identifies the tax number of the signatory.
+const ublTRSignatureScheme = "VKN_TCKN"
+
+var ublTRPartySchemes = []cbc.Code{
+	IdentityTypeMERSIS,
+	IdentityTypeTradeRegistry,
+}
+
+// ublTRTaxType contains the code and name of a Turkish tax type.
+type ublTRTaxType struct {
+	code string
+	name string
+}
+
+// ublTRTaxTypes maps GOBL tax categories to the tax types of the Turkish
+// Revenue Administration.
+var ublTRTaxTypes = map[cbc.Code]ublTRTaxType{
+	tax.CategoryVAT: {code: "0015", name: "KDV GERCEK"},
+}
+
+// ContextUBLTR defines the context for Turkish UBL-TR 1.2 e-Fatura and
+// e-Arşiv documents. The ProfileID defaults to TEMELFATURA, and may be
+// replaced with the ubl-profile meta key.
+var ContextUBLTR = Context{
+	CustomizationID: "TR1.2",
+	Output: OutputRules{
+		VersionID:            true,
+		LineTaxTotals:        true,
+		CopyIndicator:        true,
+		WithholdingTaxTotals: true,
+	},
+	Hooks: ublTRHooks{},
+}
+
+// ublTRHooks maps documents to and from the UBL-TR format of the Turkish
+// Revenue Administration (GİB).
+type ublTRHooks struct {
+	BaseHooks
+}
+
+// BeforeConvert ensures the invoice has a document type so that the
+// regular conversion can take place, as Turkish invoices do not use the
+// EN16931 addon.
+func (ublTRHooks) BeforeConvert(inv *bill.Invoice) error {
+	setDocumentType(inv)
+	return nil
+}
+
+// AfterConvert applies the UBL-TR mapping rules to the generated
+// document.
+func (ublTRHooks) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	if out.UUID == "" {
+		return errors.New("ubl-tr: invoice UUID is required")
+	}
+	tc, err := ublTRTypeCode(inv, out)
+	if err != nil {
+		return err
+	}
+	if out.ProfileID == "" {
+		out.ProfileID = UBLTRProfileBasic
+	}
+
+	// Returns are also issued as Invoice documents.
+	out.useInvoiceRoot(tc)
+	out.LineCountNumeric = len(out.InvoiceLines)
+	if r := out.OrderReference; r != nil && r.ID == notApplicable {
+		// Order references are optional, but require an issue date.
+		out.OrderReference = nil
+	}
+	if inv.IssueTime != nil {
+		out.IssueTime = inv.IssueTime.String()
+	}
+
+	if inv.Supplier != nil && out.AccountingSupplierParty.Party != nil {
+		p := out.AccountingSupplierParty.Party
+		num, scheme := ublTRTaxNumber(inv.Supplier)
+		if num == "" {
+			return errors.New("ubl-tr: supplier requires a Turkish tax ID")
+		}
+		applyUBLTRParty(inv.Supplier, p)
+		out.Signature = []Signature{ublTRSignature(out.ID, num, scheme, p)}
+	}
+	if inv.Customer != nil && out.AccountingCustomerParty.Party != nil {
+		applyUBLTRParty(inv.Customer, out.AccountingCustomerParty.Party)
+	}
+
+	out.eachTaxCategory(func(_ *IDType, ts *TaxScheme) {
+		applyUBLTRTaxScheme(ts)
+	})
+	// UNTDID 5305 tax categories are not used in Turkey.
+	out.removeTaxCategoryIDs()
+	out.eachTaxSubtotal(func(st *TaxSubtotal) {
+		// Rates are expected in the subtotal instead of the category.
+		st.Percent = st.TaxCategory.Percent
+		st.TaxCategory.Percent = nil
+	})
+	// Line taxes are only provided in the line's tax total.
+	out.removeItemTaxCategories()
+	return nil
+}
+
+// BeforeParse maps the Turkish tax types back into tax schemes, and
+// prepares the tax categories expected by the regular parsing process.
+func (ublTRHooks) BeforeParse(in *Invoice) error {
+	in.eachTaxCategory(func(_ *IDType, ts *TaxScheme) {
+		parseUBLTRTaxScheme(ts)
+	})
+	in.eachTaxSubtotal(func(st *TaxSubtotal) {
+		if st.TaxCategory.Percent == nil {
+			st.TaxCategory.Percent = st.Percent
+		}
+	})
+	in.addItemTaxCategories()
+	return nil
+}
+
+// AfterParse maps the UBL-TR specific fields back into the invoice. The
+// regime is left to the supplier's tax ID.
+func (ublTRHooks) AfterParse(in *Invoice, inv *bill.Invoice) error {
+	switch in.typeCode() {
+	case UBLTRTypeReturn:
+		inv.Type = bill.InvoiceTypeCreditNote
+	default:
+		inv.Type = bill.InvoiceTypeStandard
+	}
+
+	if in.ProfileID != "" && in.ProfileID != UBLTRProfileBasic {
+		if inv.Meta == nil {
+			inv.Meta = make(cbc.Meta)
+		}
+		inv.Meta[cbc.Key("ubl-profile")] = in.ProfileID
+	}
+
+	if in.IssueTime != "" {
+		t, err := time.Parse("15:04:05", in.IssueTime)
+		if err != nil {
+			return fmt.Errorf("ubl-tr: parsing issue time: %w", err)
+		}
+		inv.IssueTime = cal.NewTime(t.Hour(), t.Minute(), t.Second())
+	}
+
+	if inv.Supplier != nil {
+		parseUBLTRParty(in.AccountingSupplierParty.Party, inv.Supplier)
+	}
+	if inv.Customer != nil {
+		parseUBLTRParty(in.AccountingCustomerParty.Party, inv.Customer)
+	}
+	return nil
+}
+
+// ublTRTypeCode determines the UBL-TR type code for the invoice, using
+// the withholding type when the document includes retained taxes.
+func ublTRTypeCode(inv *bill.Invoice, out *Invoice) (string, error) {
+	switch inv.Type {
+	case bill.InvoiceTypeStandard:
+		if len(out.WithholdingTaxTotal) > 0 {
+			return UBLTRTypeWithholding, nil
+		}
+		return UBLTRTypeSale, nil
+	case bill.InvoiceTypeCreditNote:
+		return UBLTRTypeReturn, nil
+	default:
+		return "", fmt.Errorf("ubl-tr: unsupported invoice type %q", inv.Type)
+	}
+}
+
+// ublTRTaxNumber provides the party's Turkish tax number together with
+// its scheme: VKN for companies and TCKN for individuals.
+func ublTRTaxNumber(party *org.Party) (string, cbc.Code) {
+	if party.TaxID == nil || party.TaxID.Country != "TR" || party.TaxID.Code == "" {
+		return "", ""
+	}
+	code := party.TaxID.Code.String()
+	if len(code) == 11 {
+		return code, IdentityTypeTCKN
+	}
+	return code, IdentityTypeVKN
+}
+
+// ublTRSignature provides the signature block that references the
+// supplier's XAdES signature, which is added when the document is
+// signed.
+func ublTRSignature(id, num string, scheme cbc.Code, p *Party) Signature {
+	s := ublTRSignatureScheme
+	return Signature{
+		ID:           num,
+		IDAttributes: &Attributes{SchemeID: &s},
+		SignatoryParty: &Party{
+			PartyIdentification: []Identification{ublTRIdentification(scheme, num)},
+			PostalAddress:       p.PostalAddress,
+		},
+		DigitalSignatureAttachment: &Attachment{
+			ExternalReference: &ExternalReference{URI: "#Signature_" + id},
+		},
+	}
+}
+
+// applyUBLTRParty replaces the party's identification details with the
+// Turkish tax number and registry identities, and states the tax office
+// from the party's registration.
+func applyUBLTRParty(party *org.Party, p *Party) {
+	var ids []Identification
+	if num, scheme := ublTRTaxNumber(party); num != "" {
+		ids = append(ids, ublTRIdentification(scheme, num))
+	}
+	for _, id := range party.Identities {
+		if id.Type.In(ublTRPartySchemes...) {
+			ids = append(ids, ublTRIdentification(id.Type, id.Code.String()))
+		}
+	}
+	p.PartyIdentification = ids
+
+	p.PartyTaxScheme = nil
+	if r := party.Registration; r != nil && r.Office != "" {
+		office := r.Office
+		p.PartyTaxScheme = []PartyTaxScheme{{TaxScheme: &TaxScheme{Name: &office}}}
+	}
+	if p.PartyLegalEntity != nil {
+		p.PartyLegalEntity.CompanyID = nil
+	}
+}
+
+// parseUBLTRParty maps the UBL-TR party identifications back into the
+// GOBL party's tax ID, identities and registration.
+func parseUBLTRParty(p *Party, party *org.Party) {
+	if p == nil {
+		return
+	}
+	var ids []*org.Identity
+	for _, id := range party.Identities {
+		switch s := id.Ext.Get(iso.ExtKeySchemeID); {
+		case s.In(IdentityTypeVKN, IdentityTypeTCKN):
+			party.TaxID = &tax.Identity{Country: "TR", Code: id.Code}
+		case s.In(ublTRPartySchemes...):
+			id.Type = s
+			id.Ext = nil
+			ids = append(ids, id)
+		default:
+			ids = append(ids, id)
+		}
+	}
+	party.Identities = ids
+
+	for _, pts := range p.PartyTaxScheme {
+		if pts.CompanyID == nil && pts.TaxScheme != nil && pts.TaxScheme.Name != nil {
+			party.Registration = &org.Registration{Office: cleanString(*pts.TaxScheme.Name)}
+			break
+		}
+	}
+}
+
+func ublTRIdentification(scheme cbc.Code, code string) Identification {
+	s := scheme.String()
+	return Identification{ID: &IDType{SchemeID: &s, Value: code}}
+}
+
+// applyUBLTRTaxScheme replaces the tax scheme's category with the Turkish
+// tax type code and name.
+func applyUBLTRTaxScheme(ts *TaxScheme) {
+	if ts == nil || ts.ID.Value == "" {
+		return
+	}
+	tt, ok := ublTRTaxTypes[cbc.Code(ts.ID.Value)]
+	if !ok {
+		return
+	}
+	name := tt.name
+	ts.ID.Value = ""
+	ts.Name = &name
+	ts.TaxTypeCode = tt.code
+}
+
+// parseUBLTRTaxScheme sets the tax scheme's category from its Turkish tax
+// type code.
+func parseUBLTRTaxScheme(ts *TaxScheme) {
+	if ts == nil || ts.ID.Value != "" {
+		return
+	}
+	for cat, tt := range ublTRTaxTypes {
+		if tt.code == ts.TaxTypeCode {
+			ts.ID.Value = cat.String()
+			return
+		}
+	}
+}
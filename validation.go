@@ -0,0 +1,122 @@
+package ubl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Finding flags indicate how serious a validation finding is.
+const (
+	FlagFatal   = "fatal"
+	FlagWarning = "warning"
+)
+
+// Finding describes a single issue detected while validating a UBL
+// document, either by the XML schema or by a business rule set.
+type Finding struct {
+	// RuleID is the identifier of the rule that failed, such as BR-01 or
+	// PEPPOL-EN16931-R001.
+	RuleID string
+	// Flag is either FlagFatal or FlagWarning.
+	Flag string
+	// Location is the XPath of the element the finding applies to.
+	Location string
+	// Line is the line number in the source document where the element
+	// starts, when known.
+	Line int
+	// Message is the human readable description of the problem.
+	Message string
+	// Syntax is true for structural problems, such as malformed XML or
+	// schema violations, as opposed to business rule failures.
+	Syntax bool
+}
+
+// IsFatal returns true when the finding should cause the document to be
+// rejected.
+func (f Finding) IsFatal() bool {
+	return f.Flag != FlagWarning
+}
+
+// String provides a single line description of the finding.
+func (f Finding) String() string {
+	var b strings.Builder
+	if f.RuleID != "" {
+		b.WriteString("[" + f.RuleID + "] ")
+	}
+	if f.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", f.Line)
+	}
+	if f.Location != "" {
+		b.WriteString(f.Location + ": ")
+	}
+	b.WriteString(f.Message)
+	return b.String()
+}
+
+// ValidationError is returned when a document fails validation and
+// includes all the findings that were reported.
+type ValidationError struct {
+	Findings []Finding
+}
+
+// Error provides a summary of all the findings.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		msgs[i] = f.String()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validator is implemented by services able to check UBL documents
+// and report any problems found as a list of findings. An error should
+// only be returned if the validation itself could not be performed.
+type Validator interface {
+	Validate(ctx context.Context, data []byte) ([]Finding, error)
+}
+
+// WithValidator adds a validator that will be used to check documents
+// generated by Convert or provided to Parse. A *ValidationError will be
+// returned if any fatal findings are reported. The option may be used
+// multiple times to run several validators in order.
+func WithValidator(v Validator) Option {
+	return func(o *options) {
+		o.validators = append(o.validators, v)
+	}
+}
+
+// WithValidationContext sets the context.Context passed on to validators,
+// so that callers may cancel or apply deadlines to validations that
+// depend on remote services. context.Background is used by default.
+func WithValidationContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// validate runs all the configured validators against the document.
+func (o *options) validate(data []byte) error {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var findings []Finding
+	fatal := false
+	for _, v := range o.validators {
+		res, err := v.Validate(ctx, data)
+		if err != nil {
+			return fmt.Errorf("validating document: %w", err)
+		}
+		for _, f := range res {
+			if f.IsFatal() {
+				fatal = true
+			}
+		}
+		findings = append(findings, res...)
+	}
+	if fatal {
+		return &ValidationError{Findings: findings}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ubl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValidator struct {
+	findings []ubl.Finding
+	err      error
+	data     []byte
+	ctx      context.Context
+}
+
+func (tv *testValidator) Validate(ctx context.Context, data []byte) ([]ubl.Finding, error) {
+	tv.data = data
+	tv.ctx = ctx
+	return tv.findings, tv.err
+}
+
+func TestWithValidator(t *testing.T) {
+	t.Run("convert with fatal findings", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		tv := &testValidator{
+			findings: []ubl.Finding{
+				{RuleID: "BR-01", Flag: ubl.FlagFatal, Location: "/*:Invoice[1]", Message: "missing"},
+				{RuleID: "BR-02", Flag: ubl.FlagWarning, Message: "check"},
+			},
+		}
+		doc, err := ubl.Convert(env, ubl.WithValidator(tv))
+		assert.Nil(t, doc)
+		var ve *ubl.ValidationError
+		require.True(t, errors.As(err, &ve))
+		assert.Len(t, ve.Findings, 2)
+		assert.Contains(t, err.Error(), "[BR-01] /*:Invoice[1]: missing")
+		assert.Contains(t, string(tv.data), "<Invoice")
+	})
+
+	t.Run("convert with warnings only", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		tv := &testValidator{
+			findings: []ubl.Finding{
+				{RuleID: "BR-02", Flag: ubl.FlagWarning, Message: "check"},
+			},
+		}
+		doc, err := ubl.Convert(env, ubl.WithValidator(tv))
+		require.NoError(t, err)
+		assert.IsType(t, &ubl.Invoice{}, doc)
+	})
+
+	t.Run("validator failure", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		tv := &testValidator{err: errors.New("unavailable")}
+		_, err = ubl.Convert(env, ubl.WithValidator(tv))
+		assert.ErrorContains(t, err, "validating document: unavailable")
+	})
+	t.Run("validation context", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		tv := &testValidator{}
+		_, err = ubl.Convert(env, ubl.WithValidator(tv), ubl.WithValidationContext(ctx))
+		require.NoError(t, err)
+		assert.Equal(t, ctx, tv.ctx)
+		assert.ErrorIs(t, tv.ctx.Err(), context.Canceled)
+	})
+
+	t.Run("default validation context", func(t *testing.T) {
+		env, err := loadTestEnvelope("invoice-minimal.json")
+		require.NoError(t, err)
+		tv := &testValidator{}
+		_, err = ubl.Convert(env, ubl.WithValidator(tv))
+		require.NoError(t, err)
+		assert.Equal(t, context.Background(), tv.ctx)
+	})
+}
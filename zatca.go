@@ -0,0 +1,183 @@
+package ubl
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/tax"
+)
+
+// ZATCA profile and invoice type codes.
+const (
+	ZATCAProfileID = "reporting:1.0"
+
+	ZATCATypeInvoice    = "388"
+	ZATCATypeCreditNote = "381"
+	ZATCATypeDebitNote  = "383"
+)
+
+// zatcaCurrency is the currency used for tax amounts in ZATCA documents.
+const zatcaCurrency = "SAR"
+
+// ErrZATCAStampRequired is returned when trying to convert an invoice
+// with the ZATCA context without a cryptographic stamp.
+var ErrZATCAStampRequired = errors.New("zatca: documents must be signed, use a ZATCAStamp as the context hooks")
+
+// ErrZATCAOutOfSequence is returned when committing a document that does
+// not follow the last one committed to the stamp.
+var ErrZATCAOutOfSequence = errors.New("zatca: document does not follow the last committed document")
+
+// ContextZATCA defines the context for Saudi Arabian ZATCA (FATOORA)
+// documents. ZATCA documents do not include a CustomizationID, so it is
+// only used to identify the context, and incoming documents are detected
+// from their ProfileID instead.
+//
+// Generated documents must be signed with the taxpayer's cryptographic
+// stamp, so the context's hooks need to be replaced with a ZATCAStamp:
+//
+//	ctx := ubl.ContextZATCA
+//	ctx.Hooks = ubl.NewZATCAStamp(cert, key)
+var ContextZATCA = Context{
+	CustomizationID: "FATOORA-2.0",
+	ProfileID:       ZATCAProfileID,
+	Hooks:           zatcaHooks{},
+}
+
+// zatcaHooks applies the ZATCA mapping rules, which are shared with the
+// ZATCAStamp.
+type zatcaHooks struct {
+	BaseHooks
+}
+
+// Detect checks for documents without a CustomizationID that use the
+// ZATCA profile.
+func (zatcaHooks) Detect(in *Invoice) bool {
+	return in.CustomizationID == "" && in.ProfileID == ZATCAProfileID
+}
+
+// BeforeConvert ensures the invoice has a document type so that the
+// regular conversion can take place.
+func (zatcaHooks) BeforeConvert(inv *bill.Invoice) error {
+	setDocumentType(inv)
+	return nil
+}
+
+// AfterConvert fails, as documents cannot be issued without a stamp.
+func (zatcaHooks) AfterConvert(_ *bill.Invoice, _ *Invoice) error {
+	return ErrZATCAStampRequired
+}
+
+// AfterParse maps the ZATCA specific fields back into the invoice. The
+// regime is left to the supplier's tax ID.
+func (zatcaHooks) AfterParse(in *Invoice, inv *bill.Invoice) error {
+	switch in.typeCode() {
+	case ZATCATypeCreditNote:
+		inv.Type = bill.InvoiceTypeCreditNote
+	case ZATCATypeDebitNote:
+		inv.Type = bill.InvoiceTypeDebitNote
+	default:
+		inv.Type = bill.InvoiceTypeStandard
+	}
+
+	if in.IssueTime != "" {
+		t, err := time.Parse("15:04:05", in.IssueTime)
+		if err != nil {
+			return fmt.Errorf("zatca: parsing issue time: %w", err)
+		}
+		inv.IssueTime = cal.NewTime(t.Hour(), t.Minute(), t.Second())
+	}
+	return nil
+}
+
+// apply adapts the generated document to the ZATCA requirements, before
+// it is signed.
+func (zatcaHooks) apply(inv *bill.Invoice, out *Invoice) error {
+	var tc string
+	switch inv.Type {
+	case bill.InvoiceTypeStandard:
+		tc = ZATCATypeInvoice
+	case bill.InvoiceTypeCreditNote:
+		tc = ZATCATypeCreditNote
+	case bill.InvoiceTypeDebitNote:
+		tc = ZATCATypeDebitNote
+	default:
+		return fmt.Errorf("zatca: unsupported invoice type %q", inv.Type)
+	}
+
+	out.CustomizationID = ""
+	out.ProfileID = ZATCAProfileID
+	out.EXTNamespace = NamespaceEXT
+
+	// Credit and debit notes are also issued as Invoice documents.
+	out.useInvoiceRoot(tc)
+	name := zatcaTransactionCode(inv)
+	out.TypeCodeAttributes = &Attributes{Name: &name}
+
+	out.UUID = inv.UUID.String()
+	out.IssueTime = "00:00:00"
+	if inv.IssueTime != nil {
+		out.IssueTime = inv.IssueTime.String()
+	}
+
+	// Tax totals must also be provided in the accounting currency.
+	out.TaxCurrencyCode = zatcaCurrency
+	if out.DocumentCurrencyCode == zatcaCurrency && len(out.TaxTotal) == 1 {
+		out.TaxTotal = append(out.TaxTotal, TaxTotal{TaxAmount: out.TaxTotal[0].TaxAmount})
+	}
+	return nil
+}
+
+// zatcaTransactionCode provides the invoice transaction code, whose first
+// two digits indicate standard (01) or simplified (02) invoices, followed
+// by flags for third party, nominal, export, summary and self-billed
+// invoices.
+func zatcaTransactionCode(inv *bill.Invoice) string {
+	code := []byte("0100000")
+	if inv.HasTags(tax.TagSimplified) {
+		code[1] = '2'
+	}
+	if inv.HasTags(tax.TagSelfBilled) {
+		code[6] = '1'
+	}
+	return string(code)
+}
+
+// formatZATCATimestamp provides the issue date and time of the invoice as
+// a timestamp.
+func formatZATCATimestamp(out *Invoice) string {
+	return out.IssueDate + "T" + out.IssueTime
+}
+
+// ZATCA document references.
+const (
+	zatcaRefCounter      = "ICV"
+	zatcaRefPreviousHash = "PIH"
+	zatcaRefQR           = "QR"
+)
+
+// zatcaAttachment creates a document reference with embedded text.
+func zatcaAttachment(id, value string) Reference {
+	mime := "text/plain"
+	return Reference{
+		ID: IDType{Value: id},
+		Attachment: &Attachment{
+			EmbeddedDocumentBinaryObject: &BinaryObject{
+				MimeCode: &mime,
+				Value:    value,
+			},
+		},
+	}
+}
+
+// zatcaReference finds a document reference by ID.
+func zatcaReference(in *Invoice, id string) *Reference {
+	for i := range in.AdditionalDocumentReference {
+		if in.AdditionalDocumentReference[i].ID.Value == id {
+			return &in.AdditionalDocumentReference[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,479 @@
+package ubl
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/tax"
+)
+
+// ZATCAInitialHash is the previous invoice hash (PIH) used for the first
+// document signed by a device, the base64 encoded SHA-256 hash of "0".
+const ZATCAInitialHash = "NWZlY2ViNjZmZmM4NmYzOGQ5NTI3ODZjNmQ2OTZjNzljMmRiYzIzOWRkNGU5MWI0NjcyOWQ3M2EyN2ZiNTdlOQ=="
+
+// ZATCA signature identifiers and namespaces
+const (
+	zatcaSignatureID            = "urn:oasis:names:specification:ubl:signature:Invoice"
+	zatcaSignatureInformationID = "urn:oasis:names:specification:ubl:signature:1"
+	zatcaSignatureMethod        = "urn:oasis:names:specification:ubl:dsig:enveloped:xades"
+
+	namespaceSIG   = "urn:oasis:names:specification:ubl:schema:xsd:CommonSignatureComponents-2"
+	namespaceSAC   = "urn:oasis:names:specification:ubl:schema:xsd:SignatureAggregateComponents-2"
+	namespaceSBC   = "urn:oasis:names:specification:ubl:schema:xsd:SignatureBasicComponents-2"
+	namespaceDS    = "http://www.w3.org/2000/09/xmldsig#"
+	namespaceXAdES = "http://uri.etsi.org/01903/v1.3.2#"
+)
+
+// Signature algorithms
+const (
+	algorithmC14N11      = "http://www.w3.org/2006/12/xml-c14n11"
+	algorithmECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	algorithmSHA256      = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algorithmXPath       = "http://www.w3.org/TR/1999/REC-xpath-19991116"
+)
+
+// zatcaExcludedPaths are removed from the document before calculating the
+// invoice hash.
+var zatcaExcludedPaths = []string{
+	"not(//ancestor-or-self::ext:UBLExtensions)",
+	"not(//ancestor-or-self::cac:Signature)",
+	"not(//ancestor-or-self::cac:AdditionalDocumentReference[cbc:ID='QR'])",
+}
+
+// ZATCAStamp signs ZATCA documents with the taxpayer's cryptographic stamp
+// and chains them together using the invoice counter value (ICV) and the
+// previous invoice hash (PIH). A stamp should be used for all the
+// documents issued by a device, in order. Converting a document does not
+// change the stamp's state: once it has been issued, Commit must be
+// called so that the next document follows it.
+type ZATCAStamp struct {
+	zatcaHooks
+
+	// Certificate is the cryptographic stamp identifier (CSID) issued by
+	// ZATCA.
+	Certificate *x509.Certificate
+	// Key is the certificate's private key.
+	Key crypto.Signer
+	// Rand is the source of randomness used for signing. When nil, ECDSA
+	// keys produce deterministic signatures.
+	Rand io.Reader
+	// Counter is the invoice counter value of the last committed document.
+	Counter int64
+	// PreviousHash is the hash of the last committed document.
+	PreviousHash string
+
+	mu sync.Mutex
+}
+
+// NewZATCAStamp prepares a stamp for the first document of a device using
+// the provided certificate and key.
+func NewZATCAStamp(cert *x509.Certificate, key crypto.Signer) *ZATCAStamp {
+	return &ZATCAStamp{
+		Certificate:  cert,
+		Key:          key,
+		PreviousHash: ZATCAInitialHash,
+	}
+}
+
+// LoadZATCAStamp prepares a stamp from a PEM encoded certificate and
+// PKCS #8 or SEC 1 private key.
+func LoadZATCAStamp(certPEM, keyPEM []byte) (*ZATCAStamp, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("zatca: invalid certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("zatca: parsing certificate: %w", err)
+	}
+
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("zatca: invalid private key")
+	}
+	var key any
+	if block.Type == "EC PRIVATE KEY" {
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("zatca: parsing private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("zatca: unsupported private key %T", key)
+	}
+	return NewZATCAStamp(cert, signer), nil
+}
+
+// AfterConvert applies the ZATCA mapping rules and signs the document,
+// adding the invoice counter, previous invoice hash and QR code. The
+// document follows the last one committed.
+func (s *ZATCAStamp) AfterConvert(inv *bill.Invoice, out *Invoice) error {
+	if s.Certificate == nil || s.Key == nil {
+		return ErrZATCAStampRequired
+	}
+	if err := s.apply(inv, out); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	counter, previous := s.Counter+1, s.previousHash()
+	s.mu.Unlock()
+	out.AdditionalDocumentReference = append(out.AdditionalDocumentReference,
+		Reference{
+			ID:   IDType{Value: zatcaRefCounter},
+			UUID: strconv.FormatInt(counter, 10),
+		},
+		zatcaAttachment(zatcaRefPreviousHash, previous),
+		zatcaAttachment(zatcaRefQR, ""),
+	)
+	method := zatcaSignatureMethod
+	out.Signature = []Signature{{ID: zatcaSignatureID, SignatureMethod: &method}}
+	out.UBLExtensions = &Extensions{
+		Extension: []Extension{{
+			ExtensionURI:     &method,
+			ExtensionContent: new(ExtensionContent),
+		}},
+	}
+
+	// The hash does not include the elements that are completed next.
+	data, err := Bytes(out)
+	if err != nil {
+		return err
+	}
+	hash, err := ZATCAInvoiceHash(data)
+	if err != nil {
+		return err
+	}
+	sig, err := s.sign(hash)
+	if err != nil {
+		return err
+	}
+	content, err := s.signatures(hash, sig, formatZATCATimestamp(out))
+	if err != nil {
+		return err
+	}
+	out.UBLExtensions.Extension[0].ExtensionContent.Value = string(content)
+
+	qr, err := s.qrCode(inv, out, hash, sig)
+	if err != nil {
+		return err
+	}
+	zatcaReference(out, zatcaRefQR).Attachment.EmbeddedDocumentBinaryObject.Value = qr
+	return nil
+}
+
+// Commit records the document as the last one issued by the device, so
+// that the next document converted with the stamp follows it. It should
+// only be called once the document has been successfully issued, and
+// returns ErrZATCAOutOfSequence if the document does not directly follow
+// the last one committed, in which case it should be converted again.
+func (s *ZATCAStamp) Commit(doc *Invoice) error {
+	icv := zatcaReference(doc, zatcaRefCounter)
+	pih := zatcaReference(doc, zatcaRefPreviousHash)
+	if icv == nil || pih == nil || pih.Attachment == nil || pih.Attachment.EmbeddedDocumentBinaryObject == nil {
+		return ErrZATCAOutOfSequence
+	}
+	data, err := Bytes(doc)
+	if err != nil {
+		return err
+	}
+	hash, err := ZATCAInvoiceHash(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.Counter + 1
+	if icv.UUID != strconv.FormatInt(counter, 10) || pih.Attachment.EmbeddedDocumentBinaryObject.Value != s.previousHash() {
+		return ErrZATCAOutOfSequence
+	}
+	s.Counter = counter
+	s.PreviousHash = hash
+	return nil
+}
+
+// previousHash provides the hash of the last committed document, or the
+// initial hash for the first document. The lock must be held.
+func (s *ZATCAStamp) previousHash() string {
+	if s.PreviousHash == "" {
+		return ZATCAInitialHash
+	}
+	return s.PreviousHash
+}
+
+// ZATCAInvoiceHash calculates the hash of a ZATCA document: the base64
+// encoded SHA-256 digest of its canonical form without the UBL extensions,
+// signature and QR code. Elements are expected to use the usual UBL
+// prefixes.
+func ZATCAInvoiceHash(data []byte) (string, error) {
+	c, err := canonicalize(data, func(n *xmlNode) bool {
+		return n.is("ext", "UBLExtensions") ||
+			n.is("cac", "Signature") ||
+			(n.is("cac", "AdditionalDocumentReference") && n.childText("cbc", "ID") == zatcaRefQR)
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(c)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// sign provides the base64 encoded signature of the invoice hash.
+func (s *ZATCAStamp) sign(hash string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(raw)
+	sig, err := s.Key.Sign(s.Rand, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("zatca: signing: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// signatures builds the XAdES signature to include in the document's UBL
+// extension content.
+func (s *ZATCAStamp) signatures(hash, sig, signingTime string) ([]byte, error) {
+	cert := base64.StdEncoding.EncodeToString(s.Certificate.Raw)
+	props := xadesSignedProperties{
+		ID: "xadesSignedProperties",
+		SignedSignatureProperties: xadesSignedSignatureProperties{
+			SigningTime: signingTime,
+			SigningCertificate: xadesSigningCertificate{
+				Cert: xadesCert{
+					CertDigest: xadesCertDigest{
+						DigestMethod: dsAlgorithm{Algorithm: algorithmSHA256},
+						DigestValue:  hexDigest([]byte(cert)),
+					},
+					IssuerSerial: xadesIssuerSerial{
+						X509IssuerName:   s.Certificate.Issuer.String(),
+						X509SerialNumber: s.Certificate.SerialNumber.String(),
+					},
+				},
+			},
+		},
+	}
+	raw, err := xml.Marshal(props)
+	if err != nil {
+		return nil, err
+	}
+
+	var transforms []dsTransform
+	for _, p := range zatcaExcludedPaths {
+		transforms = append(transforms, dsTransform{Algorithm: algorithmXPath, XPath: p})
+	}
+	transforms = append(transforms, dsTransform{Algorithm: algorithmC14N11})
+
+	doc := zatcaSignatures{
+		SIGNamespace: namespaceSIG,
+		SACNamespace: namespaceSAC,
+		SBCNamespace: namespaceSBC,
+		SignatureInformation: zatcaSignatureInformation{
+			ID:                    zatcaSignatureInformationID,
+			ReferencedSignatureID: zatcaSignatureID,
+			Signature: dsSignature{
+				DSNamespace: namespaceDS,
+				ID:          "signature",
+				SignedInfo: dsSignedInfo{
+					CanonicalizationMethod: dsAlgorithm{Algorithm: algorithmC14N11},
+					SignatureMethod:        dsAlgorithm{Algorithm: algorithmECDSASHA256},
+					Reference: []dsReference{
+						{
+							ID:           "invoiceSignedData",
+							URI:          "",
+							Transforms:   &dsTransforms{Transform: transforms},
+							DigestMethod: dsAlgorithm{Algorithm: algorithmSHA256},
+							DigestValue:  hash,
+						},
+						{
+							Type:         "http://www.w3.org/2000/09/xmldsig#SignatureProperties",
+							URI:          "#xadesSignedProperties",
+							DigestMethod: dsAlgorithm{Algorithm: algorithmSHA256},
+							DigestValue:  hexDigest(raw),
+						},
+					},
+				},
+				SignatureValue: sig,
+				KeyInfo: dsKeyInfo{
+					X509Data: dsX509Data{X509Certificate: cert},
+				},
+				Object: dsObject{
+					QualifyingProperties: xadesQualifyingProperties{
+						XAdESNamespace:   namespaceXAdES,
+						Target:           "signature",
+						SignedProperties: props,
+					},
+				},
+			},
+		},
+	}
+	return xml.Marshal(doc)
+}
+
+// qrCode builds the base64 encoded TLV (tag, length, value) QR code
+// payload.
+func (s *ZATCAStamp) qrCode(inv *bill.Invoice, out *Invoice, hash, sig string) (string, error) {
+	pub, err := x509.MarshalPKIXPublicKey(s.Key.Public())
+	if err != nil {
+		return "", fmt.Errorf("zatca: public key: %w", err)
+	}
+	var name, vat, taxAmount string
+	if inv.Supplier != nil {
+		name = inv.Supplier.Name
+		if inv.Supplier.TaxID != nil {
+			vat = inv.Supplier.TaxID.Code.String()
+		}
+	}
+	if len(out.TaxTotal) > 0 {
+		taxAmount = out.TaxTotal[0].TaxAmount.Value
+	}
+	values := [][]byte{
+		[]byte(name),
+		[]byte(vat),
+		[]byte(formatZATCATimestamp(out)),
+		[]byte(out.LegalMonetaryTotal.TaxInclusiveAmount.Value),
+		[]byte(taxAmount),
+		[]byte(hash),
+		[]byte(sig),
+		pub,
+	}
+	if inv.HasTags(tax.TagSimplified) {
+		// Simplified invoices also include the certificate's signature.
+		values = append(values, s.Certificate.Signature)
+	}
+
+	buf := new(bytes.Buffer)
+	for i, v := range values {
+		if len(v) > 255 {
+			return "", fmt.Errorf("zatca: QR code tag %d is too long", i+1)
+		}
+		buf.WriteByte(byte(i + 1))
+		buf.WriteByte(byte(len(v)))
+		buf.Write(v)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// hexDigest provides the base64 encoded hexadecimal SHA-256 digest of
+// the data, as used by ZATCA for the certificate and signed properties.
+func hexDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(sum[:])))
+}
+
+type zatcaSignatures struct {
+	XMLName              xml.Name                  `xml:"sig:UBLDocumentSignatures"`
+	SIGNamespace         string                    `xml:"xmlns:sig,attr"`
+	SACNamespace         string                    `xml:"xmlns:sac,attr"`
+	SBCNamespace         string                    `xml:"xmlns:sbc,attr"`
+	SignatureInformation zatcaSignatureInformation `xml:"sac:SignatureInformation"`
+}
+
+type zatcaSignatureInformation struct {
+	ID                    string      `xml:"cbc:ID"`
+	ReferencedSignatureID string      `xml:"sbc:ReferencedSignatureID"`
+	Signature             dsSignature `xml:"ds:Signature"`
+}
+
+type dsSignature struct {
+	DSNamespace    string       `xml:"xmlns:ds,attr"`
+	ID             string       `xml:"Id,attr"`
+	SignedInfo     dsSignedInfo `xml:"ds:SignedInfo"`
+	SignatureValue string       `xml:"ds:SignatureValue"`
+	KeyInfo        dsKeyInfo    `xml:"ds:KeyInfo"`
+	Object         dsObject     `xml:"ds:Object"`
+}
+
+type dsSignedInfo struct {
+	CanonicalizationMethod dsAlgorithm   `xml:"ds:CanonicalizationMethod"`
+	SignatureMethod        dsAlgorithm   `xml:"ds:SignatureMethod"`
+	Reference              []dsReference `xml:"ds:Reference"`
+}
+
+type dsAlgorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+type dsReference struct {
+	ID           string        `xml:"Id,attr,omitempty"`
+	Type         string        `xml:"Type,attr,omitempty"`
+	URI          string        `xml:"URI,attr"`
+	Transforms   *dsTransforms `xml:"ds:Transforms,omitempty"`
+	DigestMethod dsAlgorithm   `xml:"ds:DigestMethod"`
+	DigestValue  string        `xml:"ds:DigestValue"`
+}
+
+type dsTransforms struct {
+	Transform []dsTransform `xml:"ds:Transform"`
+}
+
+type dsTransform struct {
+	Algorithm string `xml:"Algorithm,attr"`
+	XPath     string `xml:"ds:XPath,omitempty"`
+}
+
+type dsKeyInfo struct {
+	X509Data dsX509Data `xml:"ds:X509Data"`
+}
+
+type dsX509Data struct {
+	X509Certificate string `xml:"ds:X509Certificate"`
+}
+
+type dsObject struct {
+	QualifyingProperties xadesQualifyingProperties `xml:"xades:QualifyingProperties"`
+}
+
+type xadesQualifyingProperties struct {
+	XAdESNamespace   string                `xml:"xmlns:xades,attr"`
+	Target           string                `xml:"Target,attr"`
+	SignedProperties xadesSignedProperties `xml:"xades:SignedProperties"`
+}
+
+type xadesSignedProperties struct {
+	XMLName                   xml.Name                       `xml:"xades:SignedProperties"`
+	ID                        string                         `xml:"Id,attr"`
+	SignedSignatureProperties xadesSignedSignatureProperties `xml:"xades:SignedSignatureProperties"`
+}
+
+type xadesSignedSignatureProperties struct {
+	SigningTime        string                  `xml:"xades:SigningTime"`
+	SigningCertificate xadesSigningCertificate `xml:"xades:SigningCertificate"`
+}
+
+type xadesSigningCertificate struct {
+	Cert xadesCert `xml:"xades:Cert"`
+}
+
+type xadesCert struct {
+	CertDigest   xadesCertDigest   `xml:"xades:CertDigest"`
+	IssuerSerial xadesIssuerSerial `xml:"xades:IssuerSerial"`
+}
+
+type xadesCertDigest struct {
+	DigestMethod dsAlgorithm `xml:"ds:DigestMethod"`
+	DigestValue  string      `xml:"ds:DigestValue"`
+}
+
+type xadesIssuerSerial struct {
+	X509IssuerName   string `xml:"ds:X509IssuerName"`
+	X509SerialNumber string `xml:"ds:X509SerialNumber"`
+}
@@ -0,0 +1,201 @@
+package ubl_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ubl "github.com/invopop/gobl.ubl"
+	"github.com/invopop/gobl/bill"
+	"github.com/invopop/gobl/cal"
+	"github.com/invopop/gobl/l10n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testZATCAStamp(t *testing.T) *ubl.ZATCAStamp {
+	t.Helper()
+	cert, err := os.ReadFile(filepath.Join(getDataPath(), "zatca", "cert.pem"))
+	require.NoError(t, err)
+	key, err := os.ReadFile(filepath.Join(getDataPath(), "zatca", "key.pem"))
+	require.NoError(t, err)
+	s, err := ubl.LoadZATCAStamp(cert, key)
+	require.NoError(t, err)
+	return s
+}
+
+func testZATCAInvoice(t *testing.T, name string, s *ubl.ZATCAStamp) *ubl.Invoice {
+	t.Helper()
+	ctx := ubl.ContextZATCA
+	ctx.Hooks = s
+	doc, err := testInvoiceFromContext(filepath.Join("zatca", name), ctx)
+	require.NoError(t, err)
+	return doc
+}
+
+// testZATCAQRCode decodes the QR code TLV values by tag.
+func testZATCAQRCode(t *testing.T, doc *ubl.Invoice) map[byte][]byte {
+	t.Helper()
+	var qr string
+	for _, ref := range doc.AdditionalDocumentReference {
+		if ref.ID.Value == "QR" {
+			qr = ref.Attachment.EmbeddedDocumentBinaryObject.Value
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(qr)
+	require.NoError(t, err)
+	tags := make(map[byte][]byte)
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 2)
+		n := int(data[1])
+		require.GreaterOrEqual(t, len(data), n+2)
+		tags[data[0]] = data[2 : n+2]
+		data = data[n+2:]
+	}
+	return tags
+}
+
+func TestContextZATCA(t *testing.T) {
+	examples, err := filepath.Glob(filepath.Join(getConvertPath(), "zatca", jsonPattern))
+	require.NoError(t, err)
+	require.NotEmpty(t, examples)
+
+	for _, example := range examples {
+		inName := filepath.Base(example)
+		outName := strings.Replace(inName, ".json", ".xml", 1)
+
+		t.Run(inName, func(t *testing.T) {
+			doc := testZATCAInvoice(t, inName, testZATCAStamp(t))
+			data, err := ubl.Bytes(doc)
+			require.NoError(t, err)
+
+			assert.NoError(t, ubl.ValidateSchema(data), "Output should be valid against the UBL schema")
+
+			outPath := filepath.Join(getConvertPath(), "zatca", "out", outName)
+			if *updateOut {
+				require.NoError(t, os.WriteFile(outPath, data, 0644))
+			}
+			output, err := os.ReadFile(outPath)
+			assert.NoError(t, err)
+			assert.Equal(t, string(output), string(data), "Output should match the expected XML. Update with --update flag.")
+		})
+	}
+
+	t.Run("hash and signature", func(t *testing.T) {
+		s := testZATCAStamp(t)
+		doc := testZATCAInvoice(t, "invoice-sa-standard.json", s)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+
+		hash, err := ubl.ZATCAInvoiceHash(data)
+		require.NoError(t, err)
+		require.NoError(t, s.Commit(doc))
+		assert.Equal(t, hash, s.PreviousHash)
+		assert.Contains(t, string(data), "<ds:DigestValue>"+hash+"</ds:DigestValue>")
+
+		tags := testZATCAQRCode(t, doc)
+		assert.Equal(t, "Maximum Speed Tech Supply LTD", string(tags[1]))
+		assert.Equal(t, "399999999900003", string(tags[2]))
+		assert.Equal(t, "2024-09-12T10:15:30", string(tags[3]))
+		assert.Equal(t, "231.15", string(tags[4]))
+		assert.Equal(t, "30.15", string(tags[5]))
+		assert.Equal(t, hash, string(tags[6]))
+		assert.NotContains(t, tags, byte(9))
+
+		raw, err := base64.StdEncoding.DecodeString(hash)
+		require.NoError(t, err)
+		digest := sha256.Sum256(raw)
+		sig, err := base64.StdEncoding.DecodeString(string(tags[7]))
+		require.NoError(t, err)
+		pub, ok := s.Certificate.PublicKey.(*ecdsa.PublicKey)
+		require.True(t, ok)
+		assert.True(t, ecdsa.VerifyASN1(pub, digest[:], sig))
+	})
+
+	t.Run("simplified invoice", func(t *testing.T) {
+		s := testZATCAStamp(t)
+		doc := testZATCAInvoice(t, "invoice-sa-simplified.json", s)
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `<cbc:InvoiceTypeCode name="0200000">388</cbc:InvoiceTypeCode>`)
+
+		tags := testZATCAQRCode(t, doc)
+		assert.Equal(t, s.Certificate.Signature, tags[9])
+	})
+
+	t.Run("chaining", func(t *testing.T) {
+		s := testZATCAStamp(t)
+		first := testZATCAInvoice(t, "invoice-sa-standard.json", s)
+		require.NoError(t, s.Commit(first))
+		hash := s.PreviousHash
+		second := testZATCAInvoice(t, "invoice-sa-simplified.json", s)
+		require.NoError(t, s.Commit(second))
+		assert.Equal(t, int64(2), s.Counter)
+
+		refs := make(map[string]ubl.Reference)
+		for _, ref := range second.AdditionalDocumentReference {
+			refs[ref.ID.Value] = ref
+		}
+		assert.Equal(t, "2", refs["ICV"].UUID)
+		assert.Equal(t, hash, refs["PIH"].Attachment.EmbeddedDocumentBinaryObject.Value)
+
+		for _, ref := range first.AdditionalDocumentReference {
+			if ref.ID.Value == "PIH" {
+				assert.Equal(t, ubl.ZATCAInitialHash, ref.Attachment.EmbeddedDocumentBinaryObject.Value)
+			}
+		}
+	})
+
+	t.Run("uncommitted documents", func(t *testing.T) {
+		s := testZATCAStamp(t)
+		first := testZATCAInvoice(t, "invoice-sa-standard.json", s)
+		assert.Equal(t, int64(0), s.Counter)
+		assert.Equal(t, ubl.ZATCAInitialHash, s.PreviousHash)
+
+		// A document that was not issued is replaced by the next one.
+		second := testZATCAInvoice(t, "invoice-sa-simplified.json", s)
+		for _, ref := range second.AdditionalDocumentReference {
+			if ref.ID.Value == "ICV" {
+				assert.Equal(t, "1", ref.UUID)
+			}
+		}
+		require.NoError(t, s.Commit(second))
+		assert.Equal(t, int64(1), s.Counter)
+
+		assert.ErrorIs(t, s.Commit(first), ubl.ErrZATCAOutOfSequence)
+		assert.ErrorIs(t, s.Commit(second), ubl.ErrZATCAOutOfSequence)
+		assert.Equal(t, int64(1), s.Counter)
+	})
+
+	t.Run("stamp required", func(t *testing.T) {
+		_, err := testInvoiceFromContext(filepath.Join("zatca", "invoice-sa-standard.json"), ubl.ContextZATCA)
+		assert.ErrorIs(t, err, ubl.ErrZATCAStampRequired)
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		doc := testZATCAInvoice(t, "invoice-sa-standard.json", testZATCAStamp(t))
+		data, err := ubl.Bytes(doc)
+		require.NoError(t, err)
+
+		in, err := ubl.Parse(data)
+		require.NoError(t, err)
+		env, err := in.(*ubl.Invoice).Convert()
+		require.NoError(t, err)
+		inv, ok := env.Extract().(*bill.Invoice)
+		require.True(t, ok)
+		assert.Empty(t, inv.GetRegime())
+		assert.Equal(t, l10n.TaxCountryCode("SA"), inv.Supplier.TaxID.Country)
+		assert.Equal(t, bill.InvoiceTypeStandard, inv.Type)
+		assert.Equal(t, cal.NewTime(10, 15, 30), inv.IssueTime)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		ctx := ubl.ContextByName("zatca")
+		require.NotNil(t, ctx)
+		assert.True(t, ctx.Is(ubl.ContextZATCA))
+	})
+}